@@ -11,20 +11,26 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/sirupsen/logrus"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/apikey"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/approval"
 )
 
 // AppServer 应用服务器结构体，封装所有服务和处理器
 type AppServer struct {
 	xiaohongshuService *XiaohongshuService
 	mcpServer          *mcp.Server
+	auth               *apikey.Authenticator // 为空或未配置 Key 时不启用认证
 	router             *gin.Engine
 	httpServer         *http.Server
+	approvals          *approval.Queue // 客户端不支持 elicitation 时的人工审批队列
 }
 
 // NewAppServer 创建新的应用服务器实例
-func NewAppServer(xiaohongshuService *XiaohongshuService) *AppServer {
+func NewAppServer(xiaohongshuService *XiaohongshuService, auth *apikey.Authenticator) *AppServer {
 	appServer := &AppServer{
 		xiaohongshuService: xiaohongshuService,
+		auth:               auth,
+		approvals:          approval.New(approval.DefaultTTL),
 	}
 
 	// 初始化 MCP Server（需要在创建 appServer 之后，因为工具注册需要访问 appServer）
@@ -69,3 +75,34 @@ func (s *AppServer) Start(port string) error {
 
 	return nil
 }
+
+// ServeStdio 通过标准输入输出提供 MCP 服务，客户端关闭 stdin 或收到中断信号时退出。
+// stdout 只用于 MCP 协议消息，日志输出到 stderr 或日志文件
+func (s *AppServer) ServeStdio() error {
+	// stdio 模式没有 HTTP 接口，无法审批，需要确认的工具只能通过 elicitation 确认
+	s.approvals = nil
+
+	session, err := s.mcpServer.Connect(context.Background(), &mcp.StdioTransport{}, nil)
+	if err != nil {
+		return err
+	}
+
+	// 传输已经持有真正的 stdout，之后其他代码（如浏览器下载器的日志）写入 stdout 的内容
+	// 转到 stderr，避免破坏协议消息
+	os.Stdout = os.Stderr
+
+	logrus.Info("MCP 服务已通过 stdio 启动")
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-quit
+		logrus.Infof("正在关闭 MCP 会话...")
+		session.Close()
+	}()
+
+	if err := session.Wait(); err != nil {
+		logrus.Infof("MCP 会话结束: %v", err)
+	}
+	return nil
+}
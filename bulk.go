@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xpzouying/xiaohongshu-mcp/configs"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/bulkpublish"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/downloader"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/publishlog"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/ratelimit"
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
+)
+
+// BulkPublishRequest 批量发布请求。清单内容由调用方上传，服务端不读取本地清单文件
+type BulkPublishRequest struct {
+	Manifest string `json:"manifest"`         // 清单内容
+	Format   string `json:"format,omitempty"` // csv / jsonl，为空时根据内容判断
+
+	Spacing     string `json:"spacing,omitempty"` // 两次发布之间的间隔，如 10m，为空时使用 -bulk-spacing
+	Account     string `json:"account,omitempty"` // 当前登录的账号，清单中填写了其他账号的行不发布
+	DryRun      bool   `json:"dry_run,omitempty"`
+	StopOnError bool   `json:"stop_on_error,omitempty"`
+}
+
+// BulkPublish 解析清单并在后台逐条发布，返回任务的初始报告
+func (s *XiaohongshuService) BulkPublish(req *BulkPublishRequest) (*bulkpublish.Job, error) {
+	data := []byte(req.Manifest)
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, errors.New("清单为空")
+	}
+
+	format := bulkpublish.Format(req.Format)
+	if format == "" {
+		format = bulkpublish.DetectFormat("", data)
+	}
+
+	spacing := configs.GetBulkSpacing()
+	if req.Spacing != "" {
+		var err error
+		if spacing, err = time.ParseDuration(req.Spacing); err != nil {
+			return nil, fmt.Errorf("发布间隔格式错误: %w", err)
+		}
+	}
+
+	rows, err := bulkpublish.Parse(bytes.NewReader(data), format)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.bulk.Start(rows, bulkpublish.Options{
+		Spacing:     spacing,
+		Account:     req.Account,
+		DryRun:      req.DryRun,
+		StopOnError: req.StopOnError,
+
+		Validate: s.validateBulkRow,
+		Publish: func(ctx context.Context, row bulkpublish.Row) (bulkpublish.Outcome, error) {
+			return s.publishBulkRow(ctx, row, req.DryRun)
+		},
+		OnUpdate: func(id string) {
+			s.cache.Notify(jobURI(id))
+		},
+	})
+}
+
+// GetBulkJob 获取批量发布任务报告
+func (s *XiaohongshuService) GetBulkJob(id string) (*bulkpublish.Job, error) {
+	return s.bulk.Get(id)
+}
+
+// ListBulkJobs 列出批量发布任务
+func (s *XiaohongshuService) ListBulkJobs() []*bulkpublish.Job {
+	return s.bulk.List()
+}
+
+// CancelBulkJob 取消批量发布任务
+func (s *XiaohongshuService) CancelBulkJob(id string) (*bulkpublish.Job, error) {
+	return s.bulk.Cancel(id)
+}
+
+// validateBulkRow 在开始发布前检查一行，避免等到计划时间才发现问题。
+// 网络图片在发布时才下载校验，本地图片在这里就检查。
+func (s *XiaohongshuService) validateBulkRow(row bulkpublish.Row) error {
+	if row.Title == "" {
+		return errors.New("标题不能为空")
+	}
+	if row.Content == "" {
+		return errors.New("正文不能为空")
+	}
+	if len(row.Images) == 0 {
+		return errors.New("至少需要一张图片")
+	}
+	if len(row.Images) > xiaohongshu.MaxImages {
+		return fmt.Errorf("图片数量不能超过 %d 张", xiaohongshu.MaxImages)
+	}
+
+	content, tags := row.Content, row.Tags
+	if row.Markdown {
+		content, tags, _ = formatMarkdown(content, tags)
+	}
+	if _, err := s.lintBeforePublish(row.Title, content, tags); err != nil {
+		return err
+	}
+
+	for i, image := range row.Images {
+		if downloader.IsImageURL(image) || downloader.IsDataURI(image) || downloader.IsBase64Image(image) {
+			continue
+		}
+		if err := downloader.ValidateImageFile(image, downloader.DefaultMaxImageBytes); err != nil {
+			return fmt.Errorf("第 %d 张图片 %s: %w", i+1, image, err)
+		}
+	}
+	return nil
+}
+
+// publishBulkRow 通过 PublishContent 发布一行，保留发布前检查、幂等和重复内容检测。
+// 超过频率限制时等待到可以重试，而不是让该行失败
+func (s *XiaohongshuService) publishBulkRow(ctx context.Context, row bulkpublish.Row, dryRun bool) (bulkpublish.Outcome, error) {
+	req := PublishRequest{
+		Title:   row.Title,
+		Content: row.Content,
+		Images:  row.Images,
+		Tags:    row.Tags,
+
+		DryRun:         dryRun,
+		IdempotencyKey: row.IdempotencyKey,
+		Markdown:       row.Markdown,
+	}
+
+	for {
+		attempt := req
+		resp, err := s.PublishContent(ctx, &attempt)
+
+		var rlErr *ratelimit.Error
+		if errors.As(err, &rlErr) {
+			logrus.Infof("批量发布第 %d 行超过频率限制，%s 后重试", row.Line, rlErr.RetryAfter.Round(time.Second))
+			select {
+			case <-ctx.Done():
+				return bulkpublish.Outcome{}, err
+			case <-time.After(rlErr.RetryAfter):
+				continue
+			}
+		}
+
+		if err != nil {
+			var dupErr *publishlog.DuplicateError
+			if errors.As(err, &dupErr) {
+				return bulkpublish.Outcome{Status: bulkpublish.RowDuplicate}, err
+			}
+			return bulkpublish.Outcome{}, err
+		}
+
+		var warnings []string
+		for _, w := range resp.Warnings {
+			warnings = append(warnings, w.Message)
+		}
+		switch {
+		case dryRun:
+			return bulkpublish.Outcome{Status: bulkpublish.RowDryRun, Warnings: warnings}, nil
+		case resp.Replayed:
+			return bulkpublish.Outcome{Status: bulkpublish.RowReplayed, PostID: resp.PostID, Warnings: warnings}, nil
+		}
+		return bulkpublish.Outcome{PostID: resp.PostID, Warnings: warnings}, nil
+	}
+}
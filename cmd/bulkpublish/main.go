@@ -0,0 +1,202 @@
+// bulkpublish 将 CSV / JSONL 清单提交给运行中的服务批量发布，等待完成后输出逐行报告。
+//
+// 清单中的图片路径是服务所在机器上的路径（或图片链接）。
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/bulkpublish"
+)
+
+type apiResponse struct {
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data"`
+	Message string          `json:"message"`
+	Error   string          `json:"error"`
+	Code    string          `json:"code"`
+	Details any             `json:"details"`
+}
+
+type client struct {
+	server string
+	apiKey string
+	http   *http.Client
+}
+
+func main() {
+	var (
+		server       string
+		apiKey       string
+		manifestPath string
+		format       string
+		spacing      string
+		account      string
+		dryRun       bool
+		stopOnError  bool
+		reportPath   string
+		pollInterval time.Duration
+	)
+	flag.StringVar(&server, "server", "http://localhost:18060", "xiaohongshu-mcp 服务地址")
+	flag.StringVar(&apiKey, "api-key", os.Getenv("XHS_API_KEY"), "服务启用认证时使用的 API Key（需要 publish 权限），默认读取环境变量 XHS_API_KEY")
+	flag.StringVar(&manifestPath, "manifest", "", "清单文件（.csv / .jsonl）")
+	flag.StringVar(&format, "format", "", "清单格式 csv / jsonl，为空时根据扩展名判断")
+	flag.StringVar(&spacing, "spacing", "", "两次发布之间的间隔，如 15m，为空时使用服务端的 -bulk-spacing")
+	flag.StringVar(&account, "account", "", "当前登录的账号，清单中填写了账号的行必须与之一致才会发布")
+	flag.BoolVar(&dryRun, "dry-run", false, "只填写发布表单不发布，忽略计划时间和间隔")
+	flag.BoolVar(&stopOnError, "stop-on-error", false, "发布失败后取消剩余的行")
+	flag.StringVar(&reportPath, "report", "", "报告输出文件（JSON），为空时输出到标准输出")
+	flag.DurationVar(&pollInterval, "poll", 10*time.Second, "查询任务进度的间隔")
+	flag.Parse()
+
+	if manifestPath == "" {
+		logrus.Fatal("请通过 -manifest 指定清单文件")
+	}
+
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		logrus.Fatalf("读取清单失败: %v", err)
+	}
+	if format == "" {
+		format = string(bulkpublish.DetectFormat(filepath.Base(manifestPath), data))
+	}
+
+	c := &client{server: strings.TrimRight(server, "/"), apiKey: apiKey, http: &http.Client{Timeout: 30 * time.Second}}
+
+	var job bulkpublish.Job
+	err = c.do(http.MethodPost, "/api/v1/publish/bulk", map[string]any{
+		"manifest":      string(data),
+		"format":        format,
+		"spacing":       spacing,
+		"account":       account,
+		"dry_run":       dryRun,
+		"stop_on_error": stopOnError,
+	}, &job)
+	if err != nil {
+		logrus.Fatalf("提交批量发布失败: %v", err)
+	}
+	logrus.Infof("批量发布任务 %s 已开始，共 %d 行，间隔 %s", job.ID, job.Total, job.Spacing)
+	logSummary(&job)
+
+	// Ctrl+C 时取消服务端任务，剩余的行不再发布
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for job.Status == bulkpublish.JobRunning {
+		select {
+		case <-interrupt:
+			logrus.Warn("正在取消批量发布任务...")
+			if err := c.do(http.MethodDelete, "/api/v1/publish/bulk/"+job.ID, nil, &job); err != nil {
+				logrus.Errorf("取消任务失败: %v", err)
+			}
+		case <-ticker.C:
+			var latest bulkpublish.Job
+			if err := c.do(http.MethodGet, "/api/v1/publish/bulk/"+job.ID, nil, &latest); err != nil {
+				logrus.Warnf("查询任务进度失败: %v", err)
+				continue
+			}
+			if !maps.Equal(job.Summary, latest.Summary) {
+				logSummary(&latest)
+			}
+			job = latest
+		}
+	}
+
+	if err := writeReport(&job, reportPath); err != nil {
+		logrus.Fatalf("写入报告失败: %v", err)
+	}
+
+	logSummary(&job)
+	for _, row := range job.Rows {
+		switch row.Status {
+		case bulkpublish.RowInvalid, bulkpublish.RowFailed, bulkpublish.RowDuplicate:
+			logrus.Warnf("第 %d 行 %s [%s]: %s", row.Line, row.Title, row.Status, row.Error)
+		}
+	}
+
+	if job.Summary[bulkpublish.RowInvalid]+job.Summary[bulkpublish.RowFailed] > 0 {
+		os.Exit(1)
+	}
+}
+
+// do 调用服务接口并解析 data 字段
+func (c *client) do(method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.server+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("解析响应失败 (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s [%s]: %v", result.Error, result.Code, result.Details)
+	}
+	return json.Unmarshal(result.Data, out)
+}
+
+func writeReport(job *bulkpublish.Job, path string) error {
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		_, err = fmt.Println(string(data))
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func logSummary(job *bulkpublish.Job) {
+	var parts []string
+	for _, status := range []bulkpublish.RowStatus{
+		bulkpublish.RowPending, bulkpublish.RowRunning, bulkpublish.RowPublished, bulkpublish.RowReplayed,
+		bulkpublish.RowDryRun, bulkpublish.RowDuplicate, bulkpublish.RowFailed, bulkpublish.RowInvalid,
+		bulkpublish.RowSkipped, bulkpublish.RowCancelled,
+	} {
+		if n := job.Summary[status]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", status, n))
+		}
+	}
+
+	next := ""
+	if job.NextAt != nil {
+		next = "，下一条 " + job.NextAt.Format(time.DateTime)
+	}
+	logrus.Infof("任务 %s [%s] %s%s", job.ID, job.Status, strings.Join(parts, " "), next)
+}
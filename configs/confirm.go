@@ -0,0 +1,16 @@
+package configs
+
+var confirmTools = map[string]bool{}
+
+// SetConfirmTools 设置执行前需要人工确认的 MCP 工具
+func SetConfirmTools(tools []string) {
+	confirmTools = make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		confirmTools[tool] = true
+	}
+}
+
+// ConfirmRequired 工具执行前是否需要人工确认
+func ConfirmRequired(tool string) bool {
+	return confirmTools[tool]
+}
@@ -0,0 +1,13 @@
+package configs
+
+// corsAllowedOrigins 允许跨域访问 HTTP 接口的来源，默认允许任意来源
+var corsAllowedOrigins = []string{"*"}
+
+// SetCORSAllowedOrigins 设置允许跨域访问的来源，* 表示任意来源
+func SetCORSAllowedOrigins(origins []string) {
+	corsAllowedOrigins = origins
+}
+
+func GetCORSAllowedOrigins() []string {
+	return corsAllowedOrigins
+}
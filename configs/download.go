@@ -0,0 +1,34 @@
+package configs
+
+var (
+	downloadProxy          = ""
+	downloadAllowedDomains []string
+	downloadAllowPrivate   = false
+)
+
+// SetDownloadProxy 设置下载图片/视频使用的代理
+func SetDownloadProxy(proxy string) {
+	downloadProxy = proxy
+}
+
+func GetDownloadProxy() string {
+	return downloadProxy
+}
+
+// SetDownloadAllowedDomains 设置允许下载的域名白名单，为空表示不限制
+func SetDownloadAllowedDomains(domains []string) {
+	downloadAllowedDomains = domains
+}
+
+func GetDownloadAllowedDomains() []string {
+	return downloadAllowedDomains
+}
+
+// SetDownloadAllowPrivate 设置是否允许下载内网地址
+func SetDownloadAllowPrivate(allow bool) {
+	downloadAllowPrivate = allow
+}
+
+func DownloadAllowPrivate() bool {
+	return downloadAllowPrivate
+}
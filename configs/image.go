@@ -12,3 +12,24 @@ const (
 func GetImagesPath() string {
 	return filepath.Join(os.TempDir(), ImagesDir)
 }
+
+const (
+	CardsDir = "xiaohongshu_cards"
+)
+
+// GetCardsPath 文字卡片的输出目录
+func GetCardsPath() string {
+	return filepath.Join(os.TempDir(), CardsDir)
+}
+
+var cardAssetsDir string
+
+// SetCardAssetsDir 设置文字卡片模板可以引用的背景图片、字体文件所在目录
+func SetCardAssetsDir(dir string) {
+	cardAssetsDir = dir
+}
+
+// GetCardAssetsDir 文字卡片素材目录，为空时模板不能指定背景图片和字体文件
+func GetCardAssetsDir() string {
+	return cardAssetsDir
+}
@@ -0,0 +1,12 @@
+package configs
+
+var sensitiveWords []string
+
+// SetSensitiveWords 设置发布前检查使用的敏感词列表
+func SetSensitiveWords(words []string) {
+	sensitiveWords = words
+}
+
+func GetSensitiveWords() []string {
+	return sensitiveWords
+}
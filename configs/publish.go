@@ -0,0 +1,50 @@
+package configs
+
+import (
+	"os"
+	"time"
+)
+
+// DefaultDedupWindow 默认的重复内容检测窗口
+const DefaultDedupWindow = 24 * time.Hour
+
+var dedupWindow = DefaultDedupWindow
+
+// SetDedupWindow 设置重复内容检测窗口，0 表示不检测
+func SetDedupWindow(d time.Duration) {
+	dedupWindow = d
+}
+
+func GetDedupWindow() time.Duration {
+	return dedupWindow
+}
+
+// GetPublishRecordsPath 获取发布记录文件路径，可以通过环境变量 PUBLISH_RECORDS_PATH 指定
+func GetPublishRecordsPath() string {
+	if path := os.Getenv("PUBLISH_RECORDS_PATH"); path != "" {
+		return path
+	}
+	return "publish_records.json"
+}
+
+// GetRateLimitStatePath 获取频率限制状态文件路径，可以通过环境变量 RATE_LIMIT_STATE_PATH 指定
+func GetRateLimitStatePath() string {
+	if path := os.Getenv("RATE_LIMIT_STATE_PATH"); path != "" {
+		return path
+	}
+	return "rate_limit_state.json"
+}
+
+// DefaultBulkSpacing 批量发布时两次发布之间的默认间隔
+const DefaultBulkSpacing = 10 * time.Minute
+
+var bulkSpacing = DefaultBulkSpacing
+
+// SetBulkSpacing 设置批量发布的默认间隔
+func SetBulkSpacing(d time.Duration) {
+	bulkSpacing = d
+}
+
+func GetBulkSpacing() time.Duration {
+	return bulkSpacing
+}
@@ -0,0 +1,11 @@
+package configs
+
+import "os"
+
+// GetTemplatesPath 获取笔记模板目录，可以通过环境变量 POST_TEMPLATES_DIR 指定
+func GetTemplatesPath() string {
+	if dir := os.Getenv("POST_TEMPLATES_DIR"); dir != "" {
+		return dir
+	}
+	return "post_templates"
+}
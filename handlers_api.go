@@ -1,9 +1,18 @@
 package main
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/xpzouying/xiaohongshu-mcp/cookies"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/approval"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/bulkpublish"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/contentlint"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/downloader"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/posttemplate"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/publishlog"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/ratelimit"
 	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
 
 	"github.com/gin-gonic/gin"
@@ -92,11 +101,14 @@ func (s *AppServer) publishHandler(c *gin.Context) {
 	// 执行发布
 	result, err := s.xiaohongshuService.PublishContent(c.Request.Context(), &req)
 	if err != nil {
-		respondError(c, http.StatusInternalServerError, "PUBLISH_FAILED",
-			"发布失败", err.Error())
+		respondPublishError(c, err, "PUBLISH_FAILED", "发布失败")
 		return
 	}
 
+	if req.DryRun {
+		respondSuccess(c, result, "表单已填写，未发布")
+		return
+	}
 	respondSuccess(c, result, "发布成功")
 }
 
@@ -112,11 +124,14 @@ func (s *AppServer) publishVideoHandler(c *gin.Context) {
 	// 执行视频发布
 	result, err := s.xiaohongshuService.PublishVideo(c.Request.Context(), &req)
 	if err != nil {
-		respondError(c, http.StatusInternalServerError, "PUBLISH_VIDEO_FAILED",
-			"视频发布失败", err.Error())
+		respondPublishError(c, err, "PUBLISH_VIDEO_FAILED", "视频发布失败")
 		return
 	}
 
+	if req.DryRun {
+		respondSuccess(c, result, "视频表单已填写，未发布")
+		return
+	}
 	respondSuccess(c, result, "视频发布成功")
 }
 
@@ -226,8 +241,7 @@ func (s *AppServer) postCommentHandler(c *gin.Context) {
 	// 发表评论
 	result, err := s.xiaohongshuService.PostCommentToFeed(c.Request.Context(), req.FeedID, req.XsecToken, req.Content)
 	if err != nil {
-		respondError(c, http.StatusInternalServerError, "POST_COMMENT_FAILED",
-			"发表评论失败", err.Error())
+		respondActionError(c, err, "POST_COMMENT_FAILED", "发表评论失败")
 		return
 	}
 
@@ -258,3 +272,306 @@ func (s *AppServer) myProfileHandler(c *gin.Context) {
 	c.Set("account", "ai-report")
 	respondSuccess(c, map[string]any{"data": result}, "获取我的主页成功")
 }
+
+// queueStatusHandler 当前账号执行中和排队中的操作
+func (s *AppServer) queueStatusHandler(c *gin.Context) {
+	respondSuccess(c, s.xiaohongshuService.QueueStatus(), "获取操作队列成功")
+}
+
+// respondPublishError 将发布错误映射为对应的状态码和错误码，其余错误返回 500
+func respondPublishError(c *gin.Context, err error, code, message string) {
+	var (
+		lintErr  *contentlint.LintError
+		imageErr *downloader.ImageValidationError
+		dupErr   *publishlog.DuplicateError
+	)
+
+	switch {
+	case errors.As(err, &lintErr):
+		respondError(c, http.StatusBadRequest, "CONTENT_LINT_FAILED",
+			"内容检查未通过", lintErr.Result)
+	case errors.As(err, &imageErr):
+		respondError(c, http.StatusBadRequest, "INVALID_IMAGES",
+			"图片校验失败", imageErr.Errors)
+	case errors.As(err, &dupErr):
+		respondError(c, http.StatusConflict, "DUPLICATE_CONTENT",
+			dupErr.Error(), dupErr.Record)
+	case errors.Is(err, publishlog.ErrInProgress):
+		respondError(c, http.StatusConflict, "PUBLISH_IN_PROGRESS",
+			"发布正在进行中", err.Error())
+	case errors.Is(err, publishlog.ErrKeyConflict):
+		respondError(c, http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_CONFLICT",
+			"幂等键冲突", err.Error())
+	default:
+		respondActionError(c, err, code, message)
+	}
+}
+
+// respondActionError 返回账号操作的错误，超过频率限制时返回 429 和 Retry-After
+func respondActionError(c *gin.Context, err error, code, message string) {
+	var rlErr *ratelimit.Error
+	if errors.As(err, &rlErr) {
+		c.Header("Retry-After", strconv.Itoa(rlErr.RetryAfterSeconds()))
+		respondError(c, http.StatusTooManyRequests, "RATE_LIMITED", rlErr.Error(), map[string]any{
+			"action":              rlErr.Action,
+			"reason":              rlErr.Reason,
+			"retry_after_seconds": rlErr.RetryAfterSeconds(),
+		})
+		return
+	}
+	respondError(c, http.StatusInternalServerError, code, message, err.Error())
+}
+
+// lintContentHandler 发布前内容检查
+func (s *AppServer) lintContentHandler(c *gin.Context) {
+	var req contentlint.Input
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST",
+			"请求参数错误", err.Error())
+		return
+	}
+
+	result := s.xiaohongshuService.LintContent(req)
+	respondSuccess(c, result, "内容检查完成")
+}
+
+// formatContentHandler 将 Markdown 转换为小红书正文
+func (s *AppServer) formatContentHandler(c *gin.Context) {
+	var req FormatContentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST",
+			"请求参数错误", err.Error())
+		return
+	}
+
+	result := s.xiaohongshuService.FormatContent(req.Content, req.MaxLength)
+	respondSuccess(c, result, "转换完成")
+}
+
+// renderTextCardsHandler 渲染文字卡片
+func (s *AppServer) renderTextCardsHandler(c *gin.Context) {
+	var req RenderTextCardsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST",
+			"请求参数错误", err.Error())
+		return
+	}
+
+	result, err := s.xiaohongshuService.RenderTextCards(&req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "RENDER_CARDS_FAILED",
+			"渲染文字卡片失败", err.Error())
+		return
+	}
+
+	respondSuccess(c, result, "渲染文字卡片成功")
+}
+
+// listTemplatesHandler 列出笔记模板
+func (s *AppServer) listTemplatesHandler(c *gin.Context) {
+	templates, err := s.xiaohongshuService.ListTemplates()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "LIST_TEMPLATES_FAILED",
+			"获取模板列表失败", err.Error())
+		return
+	}
+
+	respondSuccess(c, map[string]any{"templates": templates, "count": len(templates)}, "获取模板列表成功")
+}
+
+// getTemplateHandler 获取笔记模板
+func (s *AppServer) getTemplateHandler(c *gin.Context) {
+	tpl, err := s.xiaohongshuService.GetTemplate(c.Param("name"))
+	if err != nil {
+		respondTemplateError(c, err, "GET_TEMPLATE_FAILED", "获取模板失败")
+		return
+	}
+
+	respondSuccess(c, tpl, "获取模板成功")
+}
+
+// saveTemplateHandler 创建或更新笔记模板，模板名称以路径为准
+func (s *AppServer) saveTemplateHandler(c *gin.Context) {
+	var tpl posttemplate.Template
+	if err := c.ShouldBindJSON(&tpl); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST",
+			"请求参数错误", err.Error())
+		return
+	}
+	tpl.Name = c.Param("name")
+
+	saved, err := s.xiaohongshuService.SaveTemplate(&tpl)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "SAVE_TEMPLATE_FAILED",
+			"保存模板失败", err.Error())
+		return
+	}
+
+	respondSuccess(c, saved, "保存模板成功")
+}
+
+// deleteTemplateHandler 删除笔记模板
+func (s *AppServer) deleteTemplateHandler(c *gin.Context) {
+	name := c.Param("name")
+	if err := s.xiaohongshuService.DeleteTemplate(name); err != nil {
+		respondTemplateError(c, err, "DELETE_TEMPLATE_FAILED", "删除模板失败")
+		return
+	}
+
+	respondSuccess(c, map[string]any{"name": name}, "删除模板成功")
+}
+
+// publishFromTemplateHandler 使用模板发布
+func (s *AppServer) publishFromTemplateHandler(c *gin.Context) {
+	var req PublishFromTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST",
+			"请求参数错误", err.Error())
+		return
+	}
+	req.Name = c.Param("name")
+
+	result, err := s.xiaohongshuService.PublishFromTemplate(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, posttemplate.ErrNotFound) {
+			respondTemplateError(c, err, "PUBLISH_FAILED", "发布失败")
+			return
+		}
+		respondPublishError(c, err, "PUBLISH_FAILED", "发布失败")
+		return
+	}
+
+	if req.DryRun {
+		respondSuccess(c, result, "表单已填写，未发布")
+		return
+	}
+	respondSuccess(c, result, "发布成功")
+}
+
+// respondTemplateError 模板不存在返回 404，其余返回 500
+func respondTemplateError(c *gin.Context, err error, code, message string) {
+	if errors.Is(err, posttemplate.ErrNotFound) {
+		respondError(c, http.StatusNotFound, "TEMPLATE_NOT_FOUND",
+			"模板不存在", c.Param("name"))
+		return
+	}
+	respondError(c, http.StatusInternalServerError, code, message, err.Error())
+}
+
+// bulkPublishHandler 批量发布，任务在后台执行，返回任务报告
+func (s *AppServer) bulkPublishHandler(c *gin.Context) {
+	var req BulkPublishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST",
+			"请求参数错误", err.Error())
+		return
+	}
+
+	job, err := s.xiaohongshuService.BulkPublish(&req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_MANIFEST",
+			"清单解析失败", err.Error())
+		return
+	}
+
+	c.Set("account", "ai-report")
+	respondSuccess(c, job, "批量发布任务已开始")
+}
+
+// listBulkJobsHandler 列出批量发布任务
+func (s *AppServer) listBulkJobsHandler(c *gin.Context) {
+	jobs := s.xiaohongshuService.ListBulkJobs()
+	respondSuccess(c, map[string]any{"jobs": jobs, "count": len(jobs)}, "获取批量发布任务成功")
+}
+
+// getBulkJobHandler 获取批量发布任务报告
+func (s *AppServer) getBulkJobHandler(c *gin.Context) {
+	job, err := s.xiaohongshuService.GetBulkJob(c.Param("id"))
+	if err != nil {
+		respondBulkJobError(c, err)
+		return
+	}
+
+	respondSuccess(c, job, "获取批量发布任务成功")
+}
+
+// cancelBulkJobHandler 取消批量发布任务
+func (s *AppServer) cancelBulkJobHandler(c *gin.Context) {
+	job, err := s.xiaohongshuService.CancelBulkJob(c.Param("id"))
+	if err != nil {
+		respondBulkJobError(c, err)
+		return
+	}
+
+	respondSuccess(c, job, "批量发布任务已取消")
+}
+
+func respondBulkJobError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, bulkpublish.ErrJobNotFound):
+		respondError(c, http.StatusNotFound, "JOB_NOT_FOUND", "批量发布任务不存在", c.Param("id"))
+	case errors.Is(err, bulkpublish.ErrJobFinished):
+		respondError(c, http.StatusConflict, "JOB_FINISHED", "批量发布任务已结束", c.Param("id"))
+	default:
+		respondError(c, http.StatusInternalServerError, "BULK_JOB_FAILED", "操作批量发布任务失败", err.Error())
+	}
+}
+
+// listApprovalsHandler 列出等待审批和已处理的操作
+func (s *AppServer) listApprovalsHandler(c *gin.Context) {
+	approvals := s.approvals.List()
+	respondSuccess(c, map[string]any{"approvals": approvals, "count": len(approvals)}, "获取审批列表成功")
+}
+
+// getApprovalHandler 获取审批记录，批准后可查看执行结果
+func (s *AppServer) getApprovalHandler(c *gin.Context) {
+	req, err := s.approvals.Get(c.Param("id"))
+	if err != nil {
+		respondApprovalError(c, err)
+		return
+	}
+
+	respondSuccess(c, req, "获取审批记录成功")
+}
+
+// approveHandler 批准操作，操作在后台执行
+func (s *AppServer) approveHandler(c *gin.Context) {
+	req, err := s.approvals.Approve(c.Param("id"))
+	if err != nil {
+		respondApprovalError(c, err)
+		return
+	}
+
+	respondSuccess(c, req, "已批准，操作执行中")
+}
+
+// rejectHandler 拒绝操作，操作不会执行
+func (s *AppServer) rejectHandler(c *gin.Context) {
+	var body RejectApprovalRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			respondError(c, http.StatusBadRequest, "INVALID_REQUEST",
+				"请求参数错误", err.Error())
+			return
+		}
+	}
+
+	req, err := s.approvals.Reject(c.Param("id"), body.Reason)
+	if err != nil {
+		respondApprovalError(c, err)
+		return
+	}
+
+	respondSuccess(c, req, "已拒绝，操作不会执行")
+}
+
+func respondApprovalError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, approval.ErrNotFound):
+		respondError(c, http.StatusNotFound, "APPROVAL_NOT_FOUND", "审批请求不存在", c.Param("id"))
+	case errors.Is(err, approval.ErrDecided):
+		respondError(c, http.StatusConflict, "APPROVAL_DECIDED", "审批请求已处理或已过期", c.Param("id"))
+	default:
+		respondError(c, http.StatusInternalServerError, "APPROVAL_FAILED", "处理审批请求失败", err.Error())
+	}
+}
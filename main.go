@@ -1,11 +1,22 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"log"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/xpzouying/xiaohongshu-mcp/configs"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/apikey"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/contentlint"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/downloader"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/opsched"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/ratelimit"
 )
 
 func main() {
@@ -13,25 +24,236 @@ func main() {
 		headless bool
 		binPath  string // 浏览器二进制文件路径
 		port     string
+
+		transport string // MCP 传输方式：http 或 stdio
+		logFile   string // 日志文件，为空时输出到 stderr
+
+		downloadProxy          string // 下载图片/视频使用的代理
+		downloadAllowedDomains string // 允许下载的域名白名单，逗号分隔
+		downloadAllowPrivate   bool   // 是否允许下载内网地址
+
+		sensitiveWordsFile string        // 敏感词文件，每行一个
+		dedupWindow        time.Duration // 重复内容检测窗口
+		bulkSpacing        time.Duration // 批量发布的默认间隔
+		cardAssetsDir      string        // 文字卡片模板可以引用的素材目录
+
+		apiKeysFile string // API Key 配置文件
+		corsOrigins string // 允许跨域访问的来源，逗号分隔
+
+		rateLimitsFile   string // 频率限制配置文件
+		disableRateLimit bool   // 关闭频率限制
+
+		maxConcurrentReads int // 同一账号最多同时执行的读操作数
+
+		confirmTools string // 执行前需要人工确认的工具，逗号分隔
 	)
 	flag.BoolVar(&headless, "headless", true, "是否无头模式")
 	flag.StringVar(&binPath, "bin", "", "浏览器二进制文件路径")
 	flag.StringVar(&port, "port", ":18060", "端口")
+	flag.StringVar(&transport, "transport", "http", "MCP 传输方式：http 启动 HTTP 服务（MCP + REST API）；stdio 通过标准输入输出提供 MCP 服务，供桌面客户端直接启动")
+	flag.StringVar(&logFile, "log-file", "", "日志文件路径，为空时输出到 stderr")
+	flag.StringVar(&downloadProxy, "download-proxy", "", "下载图片/视频使用的代理，如 http://127.0.0.1:7890")
+	flag.StringVar(&downloadAllowedDomains, "download-allowed-domains", "", "允许下载的域名白名单，逗号分隔，为空表示不限制")
+	flag.BoolVar(&downloadAllowPrivate, "download-allow-private", false, "是否允许下载内网/本机地址（有 SSRF 风险）")
+	flag.StringVar(&sensitiveWordsFile, "sensitive-words", "", "发布前检查使用的敏感词文件，每行一个，# 开头为注释")
+	flag.DurationVar(&dedupWindow, "dedup-window", configs.DefaultDedupWindow, "相同标题+正文+图片在该时间内不允许重复发布（可用 force 强制），0 表示不检测")
+	flag.StringVar(&cardAssetsDir, "card-assets-dir", "", "文字卡片模板可以引用的背景图片、字体文件所在目录，为空时模板不能指定 background_image 和 font_file")
+	flag.DurationVar(&bulkSpacing, "bulk-spacing", configs.DefaultBulkSpacing, "批量发布时两次发布之间的默认间隔，请求中可单独指定")
+	flag.StringVar(&apiKeysFile, "api-keys", "", "API Key 配置文件（JSON），配置后所有接口都需要认证")
+	flag.StringVar(&corsOrigins, "cors-origins", "", "允许跨域访问的来源，逗号分隔，如 https://example.com；为空时允许任意来源（*），启用 API Key 认证时不允许任意来源")
+	flag.StringVar(&rateLimitsFile, "rate-limits", "", "频率限制配置文件（JSON），覆盖默认的发布/评论/点赞/收藏限制")
+	flag.BoolVar(&disableRateLimit, "disable-rate-limit", false, "关闭频率限制（容易触发平台风控，不推荐）")
+	flag.IntVar(&maxConcurrentReads, "max-concurrent-reads", opsched.DefaultMaxReads, "同一账号最多同时执行的浏览、搜索等读操作数，发布、评论等写操作始终逐个执行")
+	flag.StringVar(&confirmTools, "confirm-tools", "", "执行前需要人工确认的工具，逗号分隔，all 表示全部（"+strings.Join(confirmableTools, ",")+"），为空表示不需要确认")
 	flag.Parse()
 
+	if len(logFile) == 0 {
+		logFile = os.Getenv("XHS_LOG_FILE")
+	}
+	if logFile != "" {
+		if err := setupLogFile(logFile); err != nil {
+			logrus.Fatalf("failed to open log file: %v", err)
+		}
+	}
+
+	if transport != "http" && transport != "stdio" {
+		logrus.Fatalf("invalid transport: %s (http|stdio)", transport)
+	}
+
 	if len(binPath) == 0 {
 		binPath = os.Getenv("ROD_BROWSER_BIN")
 	}
+	if len(downloadProxy) == 0 {
+		downloadProxy = os.Getenv("XHS_DOWNLOAD_PROXY")
+	}
+	if len(downloadAllowedDomains) == 0 {
+		downloadAllowedDomains = os.Getenv("XHS_DOWNLOAD_ALLOWED_DOMAINS")
+	}
+	if len(sensitiveWordsFile) == 0 {
+		sensitiveWordsFile = os.Getenv("XHS_SENSITIVE_WORDS_FILE")
+	}
+	if len(apiKeysFile) == 0 {
+		apiKeysFile = os.Getenv("XHS_API_KEYS_FILE")
+	}
+	if len(rateLimitsFile) == 0 {
+		rateLimitsFile = os.Getenv("XHS_RATE_LIMITS_FILE")
+	}
+	if len(confirmTools) == 0 {
+		confirmTools = os.Getenv("XHS_CONFIRM_TOOLS")
+	}
+	if len(cardAssetsDir) == 0 {
+		cardAssetsDir = os.Getenv("XHS_CARD_ASSETS_DIR")
+	}
+	if len(corsOrigins) == 0 {
+		corsOrigins = os.Getenv("XHS_CORS_ORIGINS")
+	}
+
+	if downloadProxy != "" {
+		if _, err := downloader.ParseProxy(downloadProxy); err != nil {
+			logrus.Fatalf("invalid download proxy: %v", err)
+		}
+	}
 
 	configs.InitHeadless(headless)
 	configs.SetBinPath(binPath)
+	configs.SetDownloadProxy(downloadProxy)
+	configs.SetDownloadAllowedDomains(splitAndTrim(downloadAllowedDomains))
+	configs.SetDownloadAllowPrivate(downloadAllowPrivate)
+	configs.SetDedupWindow(dedupWindow)
+	configs.SetBulkSpacing(bulkSpacing)
+	configs.SetCardAssetsDir(cardAssetsDir)
+	if origins := splitAndTrim(corsOrigins); len(origins) > 0 {
+		configs.SetCORSAllowedOrigins(origins)
+	}
+
+	tools, err := parseConfirmTools(confirmTools)
+	if err != nil {
+		logrus.Fatalf("invalid confirm tools: %v", err)
+	}
+	configs.SetConfirmTools(tools)
+	if len(tools) > 0 {
+		logrus.Infof("以下工具执行前需要人工确认: %s", strings.Join(tools, ", "))
+	}
+
+	if sensitiveWordsFile != "" {
+		words, err := contentlint.LoadWordList(sensitiveWordsFile)
+		if err != nil {
+			logrus.Fatalf("failed to load sensitive words: %v", err)
+		}
+		configs.SetSensitiveWords(words)
+		logrus.Infof("loaded %d sensitive words", len(words))
+	}
+
+	// 定期清理下载的图片和视频缓存
+	downloader.StartCacheJanitor(context.Background(), configs.GetImagesPath(), downloader.DefaultCachePolicy, time.Hour)
+	downloader.StartCacheJanitor(context.Background(), configs.GetVideosPath(), downloader.DefaultVideoCachePolicy, time.Hour)
+
+	budgets := ratelimit.DefaultBudgets
+	if rateLimitsFile != "" {
+		var err error
+		if budgets, err = ratelimit.LoadBudgets(rateLimitsFile); err != nil {
+			logrus.Fatalf("failed to load rate limits: %v", err)
+		}
+	}
+	limiter, err := ratelimit.Open(configs.GetRateLimitStatePath(), budgets)
+	if err != nil {
+		// 状态文件损坏时不影响使用，只是重启前的额度不再计入
+		logrus.Errorf("恢复频率限制状态失败，从空状态开始: %v", err)
+		limiter, _ = ratelimit.Open("", budgets)
+	}
+	if disableRateLimit {
+		logrus.Warn("已关闭频率限制")
+		limiter = nil
+	}
 
 	// 初始化服务
-	xiaohongshuService := NewXiaohongshuService()
+	xiaohongshuService := NewXiaohongshuService(
+		WithRateLimiter(limiter),
+		WithMaxConcurrentReads(maxConcurrentReads),
+	)
+
+	// stdio 模式由客户端启动本程序，只有该客户端能访问，不需要 API Key 认证
+	if transport == "stdio" {
+		appServer := NewAppServer(xiaohongshuService, nil)
+		if err := appServer.ServeStdio(); err != nil {
+			logrus.Fatalf("failed to serve stdio: %v", err)
+		}
+		return
+	}
+
+	authenticator, err := loadAuthenticator(apiKeysFile, os.Getenv("XHS_API_KEYS"))
+	if err != nil {
+		logrus.Fatalf("failed to load api keys: %v", err)
+	}
 
 	// 创建并启动应用服务器
-	appServer := NewAppServer(xiaohongshuService)
+	appServer := NewAppServer(xiaohongshuService, authenticator)
 	if err := appServer.Start(port); err != nil {
 		logrus.Fatalf("failed to run server: %v", err)
 	}
 }
+
+// setupLogFile 将 logrus 和标准库 log 的输出追加写入日志文件
+func setupLogFile(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	logrus.SetOutput(f)
+	log.SetOutput(f)
+	return nil
+}
+
+// loadAuthenticator 合并配置文件和环境变量 XHS_API_KEYS 中的 API Key
+func loadAuthenticator(path, env string) (*apikey.Authenticator, error) {
+	var keys []apikey.Key
+	if path != "" {
+		fileKeys, err := apikey.LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, fileKeys...)
+	}
+	envKeys, err := apikey.ParseKeys(env)
+	if err != nil {
+		return nil, err
+	}
+	keys = append(keys, envKeys...)
+
+	authenticator, err := apikey.New(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	if authenticator.Enabled() {
+		logrus.Infof("loaded %d api keys, authentication enabled", len(keys))
+	} else {
+		logrus.Warn("未配置 API Key，所有接口无需认证即可访问，请勿将端口暴露在共享网络中")
+	}
+	return authenticator, nil
+}
+
+// parseConfirmTools 解析需要人工确认的工具列表，all 表示全部支持确认的工具
+func parseConfirmTools(s string) ([]string, error) {
+	tools := splitAndTrim(s)
+	if len(tools) == 1 && tools[0] == "all" {
+		return confirmableTools, nil
+	}
+	for _, tool := range tools {
+		if !slices.Contains(confirmableTools, tool) {
+			return nil, fmt.Errorf("不支持确认的工具 %s，可选: %s", tool, strings.Join(confirmableTools, ", "))
+		}
+	}
+	return tools, nil
+}
+
+// splitAndTrim 按逗号拆分并去除空白项
+func splitAndTrim(s string) []string {
+	var result []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
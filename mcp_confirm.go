@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/xpzouying/xiaohongshu-mcp/configs"
+	"github.com/xpzouying/xiaohongshu-mcp/cookies"
+)
+
+// confirmableTools 支持执行前人工确认的工具
+var confirmableTools = []string{
+	"publish_content",
+	"publish_with_video",
+	"publish_from_template",
+	"post_comment_to_feed",
+	"delete_cookies",
+}
+
+// confirmSchema 确认表单，只有一个必填的布尔字段
+var confirmSchema = &jsonschema.Schema{
+	Type: "object",
+	Properties: map[string]*jsonschema.Schema{
+		"confirm": {
+			Type:        "boolean",
+			Title:       "确认执行",
+			Description: "确认以上内容无误并执行",
+		},
+	},
+	Required: []string{"confirm"},
+}
+
+// withConfirmation 工具配置为需要确认时（-confirm-tools），执行前向用户展示最终内容并等待确认：
+// 客户端支持 elicitation 时直接请求确认；否则提交到审批队列，审批人通过 HTTP 接口批准后由服务端执行。
+// describe 返回展示给用户的内容，needed 为 false 时（如 dry run）不需要确认；
+// 展示的内容需要先计算（如渲染模板）时，describe 把结果写回 args，确认后执行的正是展示的内容。
+// describe 返回错误时无法确认，操作不执行；发布类工具的 describe 会先执行图片数量、内容检查等不需要浏览器的校验，
+// 校验不通过时直接返回错误，不会让用户确认或提交审批一个注定失败的操作。
+// 确认只作用于 MCP 工具调用，REST 发布、删除接口不经过确认，由 API Key 权限控制
+func withConfirmation[T any](
+	appServer *AppServer,
+	describe func(args *T) (summary string, needed bool, err error),
+	handler func(context.Context, *mcp.CallToolRequest, T) (*mcp.CallToolResult, any, error),
+) func(context.Context, *mcp.CallToolRequest, T) (*mcp.CallToolResult, any, error) {
+
+	return func(ctx context.Context, req *mcp.CallToolRequest, args T) (*mcp.CallToolResult, any, error) {
+		tool := req.Params.Name
+		if !configs.ConfirmRequired(tool) {
+			return handler(ctx, req, args)
+		}
+		summary, needed, err := describe(&args)
+		if err != nil {
+			return textResult(fmt.Sprintf("生成确认内容失败，未执行 %s: %v", tool, err)), nil, nil
+		}
+		if !needed {
+			return handler(ctx, req, args)
+		}
+
+		if supportsElicitation(req.Session) {
+			confirmed, err := elicitConfirmation(ctx, req.Session, summary)
+			if err != nil {
+				return textResult(fmt.Sprintf("请求用户确认失败，未执行 %s: %v", tool, err)), nil, nil
+			}
+			if !confirmed {
+				logrus.Infof("MCP: 用户拒绝执行 %s", tool)
+				return textResult(fmt.Sprintf("用户拒绝执行 %s，操作未执行。请根据用户意见修改后再调用。", tool)), nil, nil
+			}
+			return handler(ctx, req, args)
+		}
+
+		// stdio 模式没有 HTTP 接口，无法审批
+		if appServer.approvals == nil {
+			return textResult(fmt.Sprintf("%s 需要用户确认，但客户端不支持 elicitation，操作未执行", tool)), nil, nil
+		}
+
+		// 审批通过后在后台执行，不再关联原请求的 context 和进度通知
+		pending := appServer.approvals.Submit(tool, summary, func(ctx context.Context) (any, error) {
+			result, structured, err := handler(ctx, &mcp.CallToolRequest{Params: req.Params, Extra: req.Extra}, args)
+			if err != nil {
+				return nil, err
+			}
+			if result.IsError {
+				return nil, errors.New(resultText(result))
+			}
+			if structured != nil {
+				return structured, nil
+			}
+			return resultText(result), nil
+		})
+
+		return textResult(fmt.Sprintf(
+			"%s 需要用户确认，当前客户端不支持 elicitation，已提交人工审批，操作尚未执行。\n"+
+				"审批 ID: %s（%s 前有效）\n"+
+				"审批人确认内容后调用 POST /api/v1/approvals/%s/approve 批准，批准后由服务端执行；"+
+				"执行结果可通过 GET /api/v1/approvals/%s 查看。请不要重复调用本工具。",
+			tool, pending.ID, pending.ExpiresAt.Format("2006-01-02 15:04"), pending.ID, pending.ID,
+		)), nil, nil
+	}
+}
+
+// supportsElicitation 客户端是否声明了 elicitation 能力
+func supportsElicitation(session *mcp.ServerSession) bool {
+	if session == nil {
+		return false
+	}
+	params := session.InitializeParams()
+	return params != nil && params.Capabilities != nil && params.Capabilities.Elicitation != nil
+}
+
+// elicitConfirmation 向用户展示最终内容，用户接受并勾选确认时返回 true
+func elicitConfirmation(ctx context.Context, session *mcp.ServerSession, summary string) (bool, error) {
+	result, err := session.Elicit(ctx, &mcp.ElicitParams{
+		Message:         summary,
+		RequestedSchema: confirmSchema,
+	})
+	if err != nil {
+		return false, err
+	}
+	if result.Action != "accept" {
+		return false, nil
+	}
+	confirmed, _ := result.Content["confirm"].(bool)
+	return confirmed, nil
+}
+
+// describePublishContent 检查并展示最终发布的图文内容，dry run 不需要确认。
+// 检查不通过时直接返回错误，不会请求用户确认或提交审批
+func describePublishContent(appServer *AppServer) func(*PublishContentArgs) (string, bool, error) {
+	return func(args *PublishContentArgs) (string, bool, error) {
+		if args.DryRun {
+			return "", false, nil
+		}
+		if err := checkImageCount(len(args.Images)); err != nil {
+			return "", false, err
+		}
+		text, err := appServer.xiaohongshuService.preparePublishText(args.Title, args.Content, args.Tags, args.Markdown, args.TruncateTags)
+		if err != nil {
+			return "", false, err
+		}
+		return describePost("发布图文笔记", args.Title, text, args.Mentions,
+			fmt.Sprintf("图片：%d 张", len(args.Images))), true, nil
+	}
+}
+
+// describePublishVideo 检查并展示最终发布的视频内容，dry run 不需要确认
+func describePublishVideo(appServer *AppServer) func(*PublishVideoArgs) (string, bool, error) {
+	return func(args *PublishVideoArgs) (string, bool, error) {
+		if args.DryRun {
+			return "", false, nil
+		}
+		if args.Video == "" {
+			return "", false, errVideoRequired
+		}
+		text, err := appServer.xiaohongshuService.preparePublishText(args.Title, args.Content, args.Tags, args.Markdown, args.TruncateTags)
+		if err != nil {
+			return "", false, err
+		}
+		return describePost("发布视频笔记", args.Title, text, args.Mentions,
+			"视频："+args.Video), true, nil
+	}
+}
+
+// describePublishFromTemplate 渲染模板并检查后展示最终发布的内容。
+// 渲染结果保存到 args，确认或审批后按这份结果发布，期间修改模板不影响已确认的内容
+func describePublishFromTemplate(appServer *AppServer) func(*PublishFromTemplateArgs) (string, bool, error) {
+	return func(args *PublishFromTemplateArgs) (string, bool, error) {
+		if args.DryRun {
+			return "", false, nil
+		}
+		tpl, err := appServer.xiaohongshuService.GetTemplate(args.Name)
+		if err != nil {
+			return "", false, err
+		}
+		rendered, err := tpl.Render(args.Variables)
+		if err != nil {
+			return "", false, err
+		}
+		args.rendered = rendered
+
+		images := len(rendered.Images) + len(args.Images)
+		if err := checkImageCount(images); err != nil {
+			return "", false, err
+		}
+		text, err := appServer.xiaohongshuService.preparePublishText(rendered.Title, rendered.Content, rendered.Tags, rendered.Markdown, false)
+		if err != nil {
+			return "", false, err
+		}
+		return describePost("使用模板 "+args.Name+" 发布图文笔记", rendered.Title, text, nil,
+			fmt.Sprintf("图片：%d 张", images)), true, nil
+	}
+}
+
+// describePostComment 展示评论内容
+func describePostComment(args *PostCommentArgs) (string, bool, error) {
+	return fmt.Sprintf("发表评论\n\n笔记 ID：%s\n评论内容：\n%s", args.FeedID, args.Content), true, nil
+}
+
+// describeDeleteCookies 展示要删除的 cookies 文件
+func describeDeleteCookies(*any) (string, bool, error) {
+	return fmt.Sprintf("删除 cookies，删除后需要重新扫码登录\n\n文件：%s", cookies.GetCookiesFilePath()), true, nil
+}
+
+// describePost 笔记的确认内容，附带发布前检查的提示（如正文、标签已截断）
+func describePost(action, title string, text *publishText, mentions []string, media string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n标题：%s\n正文：\n%s\n", action, title, text.Content)
+	if len(text.Tags) > 0 {
+		fmt.Fprintf(&b, "标签：#%s\n", strings.Join(text.Tags, " #"))
+	}
+	if len(mentions) > 0 {
+		fmt.Fprintf(&b, "@用户：%s\n", strings.Join(mentions, "、"))
+	}
+	b.WriteString(media)
+	for _, f := range text.Warnings {
+		fmt.Fprintf(&b, "\n提示：%s", f.Message)
+	}
+	return b.String()
+}
+
+// textResult 只包含一段文字的错误结果，表示操作未执行
+func textResult(text string) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: text}},
+		IsError: true,
+	}
+}
+
+// resultText 拼接结果中的文字内容
+func resultText(result *mcp.CallToolResult) string {
+	var texts []string
+	for _, c := range result.Content {
+		if t, ok := c.(*mcp.TextContent); ok {
+			texts = append(texts, t.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
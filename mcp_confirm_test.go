@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xpzouying/xiaohongshu-mcp/configs"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/posttemplate"
+)
+
+func TestConfirmPublishFromTemplate(t *testing.T) {
+	configs.SetConfirmTools([]string{"publish_from_template"})
+	t.Cleanup(func() { configs.SetConfirmTools(nil) })
+
+	tests := []struct {
+		name         string
+		args         map[string]any
+		wantText     string
+		wantApproval bool
+	}{
+		{
+			name:     "模板不存在",
+			args:     map[string]any{"name": "missing"},
+			wantText: "生成确认内容失败",
+		},
+		{
+			name:     "缺少必填变量",
+			args:     map[string]any{"name": "weekly"},
+			wantText: "生成确认内容失败",
+		},
+		{
+			name:     "没有图片",
+			args:     map[string]any{"name": "weekly", "variables": map[string]any{"week": 12}},
+			wantText: "图片不能为空",
+		},
+		{
+			name:     "内容检查不通过",
+			args:     map[string]any{"name": "weekly", "variables": map[string]any{"week": strings.Repeat("很长的周数", 5)}, "images": []string{"/tmp/1.jpg"}},
+			wantText: "内容检查未通过: 标题长度",
+		},
+		{
+			name:         "提交审批",
+			args:         map[string]any{"name": "weekly", "variables": map[string]any{"week": 12}, "images": []string{"/tmp/1.jpg"}},
+			wantText:     "已提交人工审批",
+			wantApproval: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestAppServer(t)
+			_, err := s.xiaohongshuService.SaveTemplate(&posttemplate.Template{
+				Name:      "weekly",
+				Title:     "第 {{.week}} 周好物",
+				Content:   "本周推荐",
+				Variables: []posttemplate.Variable{{Name: "week", Required: true}},
+			})
+			require.NoError(t, err)
+
+			result, err := connectSession(t, s).CallTool(context.Background(), &mcp.CallToolParams{Name: "publish_from_template", Arguments: tt.args})
+			require.NoError(t, err)
+			assert.True(t, result.IsError)
+			assert.Contains(t, resultText(result), tt.wantText)
+
+			approvals := s.approvals.List()
+			if !tt.wantApproval {
+				assert.Empty(t, approvals)
+				return
+			}
+			require.Len(t, approvals, 1)
+			assert.Contains(t, approvals[0].Summary, "第 12 周好物")
+		})
+	}
+}
+
+func TestDescribePublishFromTemplateSnapshot(t *testing.T) {
+	s := newTestAppServer(t)
+	tpl := &posttemplate.Template{Name: "weekly", Title: "第 {{.week}} 周好物", Content: "本周推荐"}
+	_, err := s.xiaohongshuService.SaveTemplate(tpl)
+	require.NoError(t, err)
+
+	args := PublishFromTemplateArgs{Name: "weekly", Variables: map[string]any{"week": 12}, Images: []string{"/tmp/1.jpg"}}
+	summary, needed, err := describePublishFromTemplate(s)(&args)
+	require.NoError(t, err)
+	require.True(t, needed)
+	assert.Contains(t, summary, "第 12 周好物")
+
+	// 确认后修改模板，执行的仍是展示给用户的内容
+	tpl.Title = "已修改"
+	_, err = s.xiaohongshuService.SaveTemplate(tpl)
+	require.NoError(t, err)
+
+	require.NotNil(t, args.rendered)
+	assert.Equal(t, "第 12 周好物", args.rendered.Title)
+
+	// 使用确认的渲染结果时不再读取模板，模板没有图片时按渲染结果报错
+	require.NoError(t, s.xiaohongshuService.DeleteTemplate("weekly"))
+	_, err = s.xiaohongshuService.PublishFromTemplate(context.Background(), &PublishFromTemplateRequest{Name: "weekly", Rendered: args.rendered})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "没有图片")
+}
@@ -2,13 +2,22 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"github.com/xpzouying/xiaohongshu-mcp/cookies"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/contentlint"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/imageproc"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/posttemplate"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/ratelimit"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/textcard"
 	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
+	"os"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // MCP 工具处理函数
@@ -35,12 +44,13 @@ func (s *AppServer) handleCheckLoginStatus(ctx context.Context) *MCPToolResult {
 	} else {
 		resultText = fmt.Sprintf("❌ 未登录\n\n请使用 get_login_qrcode 工具获取二维码进行登录。")
 	}
-	
+
 	return &MCPToolResult{
 		Content: []MCPContent{{
 			Type: "text",
 			Text: resultText,
 		}},
+		Structured: status,
 	}
 }
 
@@ -59,7 +69,8 @@ func (s *AppServer) handleGetLoginQrcode(ctx context.Context) *MCPToolResult {
 
 	if result.IsLoggedIn {
 		return &MCPToolResult{
-			Content: []MCPContent{{Type: "text", Text: "你当前已处于登录状态"}},
+			Content:    []MCPContent{{Type: "text", Text: "你当前已处于登录状态"}},
+			Structured: &LoginQrcodeResult{IsLoggedIn: true, Timeout: result.Timeout},
 		}
 	}
 
@@ -81,7 +92,10 @@ func (s *AppServer) handleGetLoginQrcode(ctx context.Context) *MCPToolResult {
 			Data:     strings.TrimPrefix(result.Img, "data:image/png;base64,"),
 		},
 	}
-	return &MCPToolResult{Content: contents}
+	return &MCPToolResult{
+		Content:    contents,
+		Structured: &LoginQrcodeResult{Timeout: result.Timeout, Deadline: deadline},
+	}
 }
 
 // handleDeleteCookies 处理删除 cookies 请求，用于登录重置
@@ -103,6 +117,7 @@ func (s *AppServer) handleDeleteCookies(ctx context.Context) *MCPToolResult {
 			Type: "text",
 			Text: resultText,
 		}},
+		Structured: &DeleteCookiesResult{Deleted: true, CookiePath: cookiePath},
 	}
 }
 
@@ -115,6 +130,14 @@ func (s *AppServer) handlePublishContent(ctx context.Context, args map[string]in
 	content, _ := args["content"].(string)
 	imagePathsInterface, _ := args["images"].([]interface{})
 	tagsInterface, _ := args["tags"].([]interface{})
+	mentionsInterface, _ := args["mentions"].([]interface{})
+	imageOptions, _ := args["image_options"].(*imageproc.Options)
+	imageLabels, _ := args["image_labels"].([]ImageLabelOption)
+	dryRun, _ := args["dry_run"].(bool)
+	idempotencyKey, _ := args["idempotency_key"].(string)
+	force, _ := args["force"].(bool)
+	markdown, _ := args["markdown"].(bool)
+	truncateTags, _ := args["truncate_tags"].(bool)
 
 	var imagePaths []string
 	for _, path := range imagePathsInterface {
@@ -130,45 +153,62 @@ func (s *AppServer) handlePublishContent(ctx context.Context, args map[string]in
 		}
 	}
 
-	logrus.Infof("MCP: 发布内容 - 标题: %s, 图片数量: %d, 标签数量: %d", title, len(imagePaths), len(tags))
+	var mentions []string
+	for _, mention := range mentionsInterface {
+		if mentionStr, ok := mention.(string); ok {
+			mentions = append(mentions, mentionStr)
+		}
+	}
+
+	logrus.Infof("MCP: 发布内容 - 标题: %s, 图片数量: %d, 标签数量: %d, @用户数量: %d", title, len(imagePaths), len(tags), len(mentions))
 
 	// 构建发布请求
 	req := &PublishRequest{
-		Title:   title,
-		Content: content,
-		Images:  imagePaths,
-		Tags:    tags,
+		Title:    title,
+		Content:  content,
+		Images:   imagePaths,
+		Tags:     tags,
+		Mentions: mentions,
+
+		ImageOptions: imageOptions,
+		ImageLabels:  imageLabels,
+		DryRun:       dryRun,
+
+		IdempotencyKey: idempotencyKey,
+		Force:          force,
+		Markdown:       markdown,
+		TruncateTags:   truncateTags,
 	}
 
 	// 执行发布
 	result, err := s.xiaohongshuService.PublishContent(ctx, req)
 	if err != nil {
-		return &MCPToolResult{
-			Content: []MCPContent{{
-				Type: "text",
-				Text: "发布失败: " + err.Error(),
-			}},
-			IsError: true,
-		}
+		return actionErrorResult("发布失败", err)
 	}
 
-	resultText := fmt.Sprintf("内容发布成功: %+v", result)
-	return &MCPToolResult{
-		Content: []MCPContent{{
-			Type: "text",
-			Text: resultText,
-		}},
+	if result.Preview != nil {
+		return dryRunResult("图文", result.Preview, result.Warnings, withoutScreenshot(result))
 	}
+
+	return structuredResult(publishSummary("内容", result.Title, result.Status, result.PostID, result.Replayed, result.Warnings), result)
 }
 
-// handlePublishVideo 处理发布视频内容（仅本地单个视频文件）
+// handlePublishVideo 处理发布视频内容（单个视频，本地文件或URL）
 func (s *AppServer) handlePublishVideo(ctx context.Context, args map[string]interface{}) *MCPToolResult {
-	logrus.Info("MCP: 发布视频内容（本地）")
+	logrus.Info("MCP: 发布视频内容")
 
 	title, _ := args["title"].(string)
 	content, _ := args["content"].(string)
 	videoPath, _ := args["video"].(string)
 	tagsInterface, _ := args["tags"].([]interface{})
+	mentionsInterface, _ := args["mentions"].([]interface{})
+	coverImage, _ := args["cover_image"].(string)
+	coverTimestamp, _ := args["cover_timestamp"].(*float64)
+	dryRun, _ := args["dry_run"].(bool)
+	idempotencyKey, _ := args["idempotency_key"].(string)
+	force, _ := args["force"].(bool)
+	markdown, _ := args["markdown"].(bool)
+	truncateTags, _ := args["truncate_tags"].(bool)
 
 	var tags []string
 	for _, tag := range tagsInterface {
@@ -177,11 +217,18 @@ func (s *AppServer) handlePublishVideo(ctx context.Context, args map[string]inte
 		}
 	}
 
+	var mentions []string
+	for _, mention := range mentionsInterface {
+		if mentionStr, ok := mention.(string); ok {
+			mentions = append(mentions, mentionStr)
+		}
+	}
+
 	if videoPath == "" {
 		return &MCPToolResult{
 			Content: []MCPContent{{
 				Type: "text",
-				Text: "发布失败: 缺少本地视频文件路径",
+				Text: "发布失败: 缺少视频文件路径",
 			}},
 			IsError: true,
 		}
@@ -191,31 +238,38 @@ func (s *AppServer) handlePublishVideo(ctx context.Context, args map[string]inte
 
 	// 构建发布请求
 	req := &PublishVideoRequest{
-		Title:   title,
-		Content: content,
-		Video:   videoPath,
-		Tags:    tags,
+		Title:    title,
+		Content:  content,
+		Video:    videoPath,
+		Tags:     tags,
+		Mentions: mentions,
+		DryRun:   dryRun,
+
+		IdempotencyKey: idempotencyKey,
+		Force:          force,
+		Markdown:       markdown,
+		TruncateTags:   truncateTags,
+	}
+	if coverImage != "" || coverTimestamp != nil {
+		req.Cover = &VideoCoverOption{
+			Image:     coverImage,
+			Timestamp: coverTimestamp,
+		}
 	}
 
 	// 执行发布
 	result, err := s.xiaohongshuService.PublishVideo(ctx, req)
 	if err != nil {
-		return &MCPToolResult{
-			Content: []MCPContent{{
-				Type: "text",
-				Text: "发布失败: " + err.Error(),
-			}},
-			IsError: true,
-		}
+		return actionErrorResult("发布失败", err)
 	}
 
-	resultText := fmt.Sprintf("视频发布成功: %+v", result)
-	return &MCPToolResult{
-		Content: []MCPContent{{
-			Type: "text",
-			Text: resultText,
-		}},
+	if result.Preview != nil {
+		preview := *result
+		preview.Preview = &PublishPreview{Filled: result.Preview.Filled}
+		return dryRunResult("视频", result.Preview, result.Warnings, &preview)
 	}
+
+	return structuredResult(publishSummary("视频", result.Title, result.Status, result.PostID, result.Replayed, result.Warnings), result)
 }
 
 // handleListFeeds 处理获取Feeds列表
@@ -233,24 +287,7 @@ func (s *AppServer) handleListFeeds(ctx context.Context) *MCPToolResult {
 		}
 	}
 
-	// 格式化输出，转换为JSON字符串
-	jsonData, err := json.MarshalIndent(result, "", "  ")
-	if err != nil {
-		return &MCPToolResult{
-			Content: []MCPContent{{
-				Type: "text",
-				Text: fmt.Sprintf("获取Feeds列表成功，但序列化失败: %v", err),
-			}},
-			IsError: true,
-		}
-	}
-
-	return &MCPToolResult{
-		Content: []MCPContent{{
-			Type: "text",
-			Text: string(jsonData),
-		}},
-	}
+	return structuredResult(fmt.Sprintf("获取到 %d 条首页 Feeds", result.Count), result)
 }
 
 // handleSearchFeeds 处理搜索Feeds
@@ -289,24 +326,7 @@ func (s *AppServer) handleSearchFeeds(ctx context.Context, args SearchFeedsArgs)
 		}
 	}
 
-	// 格式化输出，转换为JSON字符串
-	jsonData, err := json.MarshalIndent(result, "", "  ")
-	if err != nil {
-		return &MCPToolResult{
-			Content: []MCPContent{{
-				Type: "text",
-				Text: fmt.Sprintf("搜索Feeds成功，但序列化失败: %v", err),
-			}},
-			IsError: true,
-		}
-	}
-
-	return &MCPToolResult{
-		Content: []MCPContent{{
-			Type: "text",
-			Text: string(jsonData),
-		}},
-	}
+	return structuredResult(fmt.Sprintf("搜索「%s」获取到 %d 条结果", args.Keyword, result.Count), result)
 }
 
 // handleGetFeedDetail 处理获取Feed详情
@@ -349,24 +369,7 @@ func (s *AppServer) handleGetFeedDetail(ctx context.Context, args map[string]any
 		}
 	}
 
-	// 格式化输出，转换为JSON字符串
-	jsonData, err := json.MarshalIndent(result, "", "  ")
-	if err != nil {
-		return &MCPToolResult{
-			Content: []MCPContent{{
-				Type: "text",
-				Text: fmt.Sprintf("获取Feed详情成功，但序列化失败: %v", err),
-			}},
-			IsError: true,
-		}
-	}
-
-	return &MCPToolResult{
-		Content: []MCPContent{{
-			Type: "text",
-			Text: string(jsonData),
-		}},
-	}
+	return structuredResult(fmt.Sprintf("获取笔记「%s」详情成功，%d 条评论", result.Data.Note.Title, len(result.Data.Comments.List)), result)
 }
 
 // handleUserProfile 获取用户主页
@@ -409,24 +412,7 @@ func (s *AppServer) handleUserProfile(ctx context.Context, args map[string]any)
 		}
 	}
 
-	// 格式化输出，转换为JSON字符串
-	jsonData, err := json.MarshalIndent(result, "", "  ")
-	if err != nil {
-		return &MCPToolResult{
-			Content: []MCPContent{{
-				Type: "text",
-				Text: fmt.Sprintf("获取用户主页，但序列化失败: %v", err),
-			}},
-			IsError: true,
-		}
-	}
-
-	return &MCPToolResult{
-		Content: []MCPContent{{
-			Type: "text",
-			Text: string(jsonData),
-		}},
-	}
+	return structuredResult(fmt.Sprintf("获取用户「%s」主页成功，%d 篇笔记", result.UserBasicInfo.Nickname, len(result.Feeds)), result)
 }
 
 // handleLikeFeed 处理点赞/取消点赞
@@ -455,14 +441,14 @@ func (s *AppServer) handleLikeFeed(ctx context.Context, args map[string]interfac
 		if unlike {
 			action = "取消点赞"
 		}
-		return &MCPToolResult{Content: []MCPContent{{Type: "text", Text: action + "失败: " + err.Error()}}, IsError: true}
+		return actionErrorResult(action+"失败", err)
 	}
 
 	action := "点赞"
 	if unlike {
 		action = "取消点赞"
 	}
-	return &MCPToolResult{Content: []MCPContent{{Type: "text", Text: fmt.Sprintf("%s成功 - Feed ID: %s", action, res.FeedID)}}}
+	return structuredResult(fmt.Sprintf("%s成功 - Feed ID: %s", action, res.FeedID), res)
 }
 
 // handleFavoriteFeed 处理收藏/取消收藏
@@ -491,14 +477,14 @@ func (s *AppServer) handleFavoriteFeed(ctx context.Context, args map[string]inte
 		if unfavorite {
 			action = "取消收藏"
 		}
-		return &MCPToolResult{Content: []MCPContent{{Type: "text", Text: action + "失败: " + err.Error()}}, IsError: true}
+		return actionErrorResult(action+"失败", err)
 	}
 
 	action := "收藏"
 	if unfavorite {
 		action = "取消收藏"
 	}
-	return &MCPToolResult{Content: []MCPContent{{Type: "text", Text: fmt.Sprintf("%s成功 - Feed ID: %s", action, res.FeedID)}}}
+	return structuredResult(fmt.Sprintf("%s成功 - Feed ID: %s", action, res.FeedID), res)
 }
 
 // handlePostComment 处理发表评论到Feed
@@ -543,22 +529,273 @@ func (s *AppServer) handlePostComment(ctx context.Context, args map[string]inter
 
 	// 发表评论
 	result, err := s.xiaohongshuService.PostCommentToFeed(ctx, feedID, xsecToken, content)
+	if err != nil {
+		return actionErrorResult("发表评论失败", err)
+	}
+
+	return structuredResult(fmt.Sprintf("评论发表成功 - Feed ID: %s", result.FeedID), result)
+}
+
+// handleLintContent 处理发布前内容检查
+func (s *AppServer) handleLintContent(_ context.Context, args map[string]interface{}) *MCPToolResult {
+	logrus.Info("MCP: 内容检查")
+
+	title, _ := args["title"].(string)
+	content, _ := args["content"].(string)
+	tagsInterface, _ := args["tags"].([]interface{})
+
+	var tags []string
+	for _, tag := range tagsInterface {
+		if tagStr, ok := tag.(string); ok {
+			tags = append(tags, tagStr)
+		}
+	}
+
+	result := s.xiaohongshuService.LintContent(contentlint.Input{Title: title, Content: content, Tags: tags})
+
+	summary := fmt.Sprintf("内容检查通过，%d 个警告", result.Warnings)
+	if !result.OK() {
+		summary = fmt.Sprintf("内容检查未通过，%d 个错误、%d 个警告", result.Errors, result.Warnings)
+	}
+	return structuredResult(summary, result)
+}
+
+// handleFormatContent 处理 Markdown 转换
+func (s *AppServer) handleFormatContent(_ context.Context, args map[string]interface{}) *MCPToolResult {
+	logrus.Info("MCP: Markdown 转换")
+
+	content, _ := args["content"].(string)
+	maxLength, _ := args["max_length"].(int)
+
+	result := s.xiaohongshuService.FormatContent(content, maxLength)
+
+	summary := fmt.Sprintf("转换完成，正文 %d 字，%d 个标签", utf8.RuneCountInString(result.Content), len(result.Tags))
+	if result.Truncated {
+		summary += "，正文超长已截断"
+	}
+	return structuredResult(summary, result)
+}
+
+// handleRenderTextCards 处理文字卡片渲染
+func (s *AppServer) handleRenderTextCards(_ context.Context, args map[string]interface{}) *MCPToolResult {
+	logrus.Info("MCP: 渲染文字卡片")
+
+	title, _ := args["title"].(string)
+	paragraphsInterface, _ := args["paragraphs"].([]interface{})
+	template, _ := args["template"].(*textcard.Template)
+
+	var paragraphs []string
+	for _, p := range paragraphsInterface {
+		if pStr, ok := p.(string); ok {
+			paragraphs = append(paragraphs, pStr)
+		}
+	}
+
+	req := &RenderTextCardsRequest{Title: title, Paragraphs: paragraphs}
+	if template != nil {
+		req.Template = *template
+	}
+
+	result, err := s.xiaohongshuService.RenderTextCards(req)
 	if err != nil {
 		return &MCPToolResult{
 			Content: []MCPContent{{
 				Type: "text",
-				Text: "发表评论失败: " + err.Error(),
+				Text: "渲染文字卡片失败: " + err.Error(),
+			}},
+			IsError: true,
+		}
+	}
+
+	toolResult := structuredResult(fmt.Sprintf("已生成 %d 张文字卡片，可将 paths 作为发布的 images", result.Pages), result)
+	if toolResult.IsError {
+		return toolResult
+	}
+	for _, path := range result.Paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			logrus.Warnf("读取文字卡片失败: %s %v", path, err)
+			continue
+		}
+		toolResult.Content = append(toolResult.Content, MCPContent{
+			Type:     "image",
+			MimeType: "image/png",
+			Data:     base64.StdEncoding.EncodeToString(data),
+		})
+	}
+
+	return toolResult
+}
+
+// handleListTemplates 处理列出笔记模板
+func (s *AppServer) handleListTemplates(_ context.Context) *MCPToolResult {
+	logrus.Info("MCP: 列出笔记模板")
+
+	templates, err := s.xiaohongshuService.ListTemplates()
+	if err != nil {
+		return &MCPToolResult{
+			Content: []MCPContent{{
+				Type: "text",
+				Text: "获取模板列表失败: " + err.Error(),
+			}},
+			IsError: true,
+		}
+	}
+
+	return structuredResult(fmt.Sprintf("共 %d 个笔记模板", len(templates)), &ListTemplatesResult{Templates: templates, Count: len(templates)})
+}
+
+// handlePublishFromTemplate 处理使用模板发布
+func (s *AppServer) handlePublishFromTemplate(ctx context.Context, args map[string]interface{}) *MCPToolResult {
+	name, _ := args["name"].(string)
+	variables, _ := args["variables"].(map[string]any)
+	imagePathsInterface, _ := args["images"].([]interface{})
+	dryRun, _ := args["dry_run"].(bool)
+	idempotencyKey, _ := args["idempotency_key"].(string)
+	force, _ := args["force"].(bool)
+	rendered, _ := args["rendered"].(*posttemplate.Rendered)
+
+	logrus.Infof("MCP: 使用模板发布 - 模板: %s, 变量数量: %d", name, len(variables))
+
+	var imagePaths []string
+	for _, path := range imagePathsInterface {
+		if pathStr, ok := path.(string); ok {
+			imagePaths = append(imagePaths, pathStr)
+		}
+	}
+
+	req := &PublishFromTemplateRequest{
+		Name:      name,
+		Variables: variables,
+		Images:    imagePaths,
+
+		DryRun:         dryRun,
+		IdempotencyKey: idempotencyKey,
+		Force:          force,
+
+		Rendered: rendered,
+	}
+
+	result, err := s.xiaohongshuService.PublishFromTemplate(ctx, req)
+	if err != nil {
+		return actionErrorResult("使用模板发布失败", err)
+	}
+
+	if result.Preview != nil {
+		return dryRunResult("图文", result.Preview, result.Warnings, withoutScreenshot(result))
+	}
+
+	return structuredResult(publishSummary("模板内容", result.Title, result.Status, result.PostID, result.Replayed, result.Warnings), result)
+}
+
+// actionErrorResult 账号操作失败的结果，超过频率限制时返回 RATE_LIMITED 和重试等待秒数
+func actionErrorResult(message string, err error) *MCPToolResult {
+	var rlErr *ratelimit.Error
+	if errors.As(err, &rlErr) {
+		jsonData, _ := json.Marshal(map[string]any{
+			"code":                "RATE_LIMITED",
+			"action":              rlErr.Action,
+			"reason":              rlErr.Reason,
+			"retry_after_seconds": rlErr.RetryAfterSeconds(),
+		})
+		return &MCPToolResult{
+			Content: []MCPContent{{
+				Type: "text",
+				Text: fmt.Sprintf("RATE_LIMITED: %s\n%s", rlErr.Error(), jsonData),
 			}},
 			IsError: true,
 		}
 	}
 
-	// 返回成功结果，只包含feed_id
-	resultText := fmt.Sprintf("评论发表成功 - Feed ID: %s", result.FeedID)
 	return &MCPToolResult{
 		Content: []MCPContent{{
 			Type: "text",
-			Text: resultText,
+			Text: message + ": " + err.Error(),
 		}},
+		IsError: true,
+	}
+}
+
+// dryRunResult 将 dry run 预览转换为 MCP 结果：填写内容摘要 + 整页截图，
+// 结构化结果中不包含截图
+func dryRunResult(kind string, preview *PublishPreview, warnings []contentlint.Finding, structured any) *MCPToolResult {
+	jsonData, err := json.MarshalIndent(preview.Filled, "", "  ")
+	if err != nil {
+		return &MCPToolResult{
+			Content: []MCPContent{{
+				Type: "text",
+				Text: fmt.Sprintf("%s表单已填写（未发布），但序列化失败: %v", kind, err),
+			}},
+			IsError: true,
+		}
+	}
+
+	return &MCPToolResult{
+		Content: []MCPContent{
+			{
+				Type: "text",
+				Text: fmt.Sprintf("%s表单已填写，未点击发布，请确认截图后再正式发布。实际填写内容:\n%s", kind, jsonData) + warningsText(warnings),
+			},
+			{
+				Type:     "image",
+				MimeType: "image/png",
+				Data:     preview.Screenshot,
+			},
+		},
+		Structured: structured,
+	}
+}
+
+// withoutScreenshot 复制发布结果并去掉预览截图，截图已经作为图片内容返回
+func withoutScreenshot(result *PublishResponse) *PublishResponse {
+	copied := *result
+	copied.Preview = &PublishPreview{Filled: result.Preview.Filled}
+	return &copied
+}
+
+// publishSummary 发布结果的文字摘要
+func publishSummary(kind, title, status, postID string, replayed bool, warnings []contentlint.Finding) string {
+	summary := fmt.Sprintf("%s发布成功：%s（%s）", kind, title, status)
+	if postID != "" {
+		summary += "，笔记ID: " + postID
+	}
+	if replayed {
+		summary += "。幂等键已发布过，返回的是之前的结果"
+	}
+	return summary + warningsText(warnings)
+}
+
+// warningsText 发布前检查提示的文字说明
+func warningsText(warnings []contentlint.Finding) string {
+	if len(warnings) == 0 {
+		return ""
+	}
+	messages := make([]string, 0, len(warnings))
+	for _, w := range warnings {
+		messages = append(messages, w.Message)
+	}
+	return "\n发布前检查提示: " + strings.Join(messages, "；")
+}
+
+// structuredResult 返回文字摘要和结构化结果。结构化结果同时序列化为 JSON 文本，
+// 兼容不读取 structuredContent 的客户端
+func structuredResult(summary string, data any) *MCPToolResult {
+	jsonData, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return &MCPToolResult{
+			Content: []MCPContent{{
+				Type: "text",
+				Text: fmt.Sprintf("%s，但序列化失败: %v", summary, err),
+			}},
+			IsError: true,
+		}
+	}
+
+	return &MCPToolResult{
+		Content: []MCPContent{
+			{Type: "text", Text: summary},
+			{Type: "text", Text: string(jsonData)},
+		},
+		Structured: data,
 	}
 }
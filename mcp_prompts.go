@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/sirupsen/logrus"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/contentlint"
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
+)
+
+// registerPrompts 注册常用工作流的 MCP 提示词，供客户端在界面中直接选择
+func registerPrompts(server *mcp.Server) {
+	prompts := 0
+	addPrompt := func(p *mcp.Prompt, h mcp.PromptHandler) {
+		server.AddPrompt(p, h)
+		prompts++
+	}
+
+	// 提示词 1: 根据产品资料写笔记
+	addPrompt(&mcp.Prompt{
+		Name:        "write_post_from_brief",
+		Title:       "根据产品资料写一篇小红书笔记",
+		Description: "根据产品资料撰写符合平台限制的图文笔记，先检查、预览，确认后再发布",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "brief", Title: "产品资料", Description: "产品介绍、卖点、价格、活动信息等", Required: true},
+			{Name: "audience", Title: "目标人群", Description: "如：大学生、职场新人、宝妈，可选"},
+			{Name: "tone", Title: "语气风格", Description: "如：真诚分享、干货清单、种草安利，可选，默认真诚分享"},
+			{Name: "images", Title: "图片", Description: "图片链接或本地绝对路径，多张用逗号或换行分隔，可选，不提供时用文字卡片生成配图"},
+		},
+	}, promptWritePostFromBrief)
+
+	// 提示词 2: 总结笔记评论
+	addPrompt(&mcp.Prompt{
+		Name:        "summarize_comments",
+		Title:       "总结笔记评论",
+		Description: "读取笔记的评论，归纳用户关注点、正负面反馈和高频问题",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "feed_id", Title: "笔记 ID", Description: "小红书笔记 ID", Required: true},
+			{Name: "xsec_token", Title: "访问令牌", Description: "笔记的 xsec_token，通过 list_feeds / search_feeds 获取过的笔记可以省略"},
+			{Name: "focus", Title: "关注点", Description: "希望重点分析的方面，如：价格、质量、售后，可选"},
+		},
+	}, promptSummarizeComments)
+
+	// 提示词 3: 关键词竞品分析
+	addPrompt(&mcp.Prompt{
+		Name:        "competitor_analysis",
+		Title:       "关键词竞品分析",
+		Description: "搜索关键词下的热门笔记，分析标题、封面、内容结构和互动数据，给出选题建议",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "keyword", Title: "关键词", Description: "要分析的搜索关键词", Required: true},
+			{Name: "sort_by", Title: "排序依据", Description: "综合|最新|最多点赞|最多评论|最多收藏，可选，默认最多点赞"},
+			{Name: "note_type", Title: "笔记类型", Description: "不限|视频|图文，可选"},
+			{Name: "publish_time", Title: "发布时间", Description: "不限|一天内|一周内|半年内，可选"},
+			{Name: "search_scope", Title: "搜索范围", Description: "不限|已看过|未看过|已关注，可选"},
+			{Name: "location", Title: "位置距离", Description: "不限|同城|附近，可选"},
+			{Name: "count", Title: "分析篇数", Description: "深入分析的笔记数量，可选，默认5"},
+		},
+	}, promptCompetitorAnalysis)
+
+	logrus.Infof("Registered %d MCP prompts", prompts)
+}
+
+// promptWritePostFromBrief 根据产品资料写笔记
+func promptWritePostFromBrief(_ context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	args := req.Params.Arguments
+	brief, err := requiredPromptArg(args, "brief")
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	b.WriteString("请根据下面的产品资料写一篇小红书图文笔记。\n\n")
+	fmt.Fprintf(&b, "## 产品资料\n%s\n\n", brief)
+	if audience := promptArg(args, "audience", ""); audience != "" {
+		fmt.Fprintf(&b, "目标人群：%s\n", audience)
+	}
+	fmt.Fprintf(&b, "语气风格：%s\n\n", promptArg(args, "tone", "真诚分享"))
+
+	b.WriteString(platformGuidance())
+
+	b.WriteString("\n## 步骤\n")
+	b.WriteString("1. 写出标题、正文和标签，正文不要包含 # 标签，标签放在 tags 中\n")
+	b.WriteString("2. 调用 lint_content 检查，有 error 级别的问题时修改后重新检查\n")
+	if images := splitPromptList(promptArg(args, "images", "")); len(images) > 0 {
+		fmt.Fprintf(&b, "3. 使用以下图片：%s\n", strings.Join(images, "、"))
+	} else {
+		b.WriteString("3. 没有提供图片，调用 render_text_cards 把要点渲染成文字卡片作为配图\n")
+	}
+	b.WriteString("4. 调用 publish_content 并设置 dry_run=true 预览，把填写结果展示给我\n")
+	b.WriteString("5. 等我确认后，再去掉 dry_run 并设置 idempotency_key 正式发布\n")
+
+	return userPrompt("根据产品资料写小红书笔记", b.String()), nil
+}
+
+// promptSummarizeComments 总结笔记评论
+func promptSummarizeComments(_ context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	args := req.Params.Arguments
+	feedID, err := requiredPromptArg(args, "feed_id")
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "请总结小红书笔记 %s 的评论。\n\n", feedID)
+	b.WriteString("## 步骤\n")
+	if xsecToken := promptArg(args, "xsec_token", ""); xsecToken != "" {
+		fmt.Fprintf(&b, "1. 调用 get_feed_detail（feed_id=%s, xsec_token=%s）获取笔记内容和评论\n", feedID, xsecToken)
+	} else {
+		fmt.Fprintf(&b, "1. 读取资源 %s 获取笔记内容和评论；提示缺少 xsec_token 时，先用 search_feeds 搜索笔记标题拿到令牌\n", noteURI(feedID))
+	}
+	b.WriteString("2. 结合笔记内容阅读全部评论和子评论，忽略无意义的表情和灌水\n\n")
+
+	b.WriteString("## 输出\n")
+	b.WriteString("- 评论总体情绪（正面/中性/负面的大致比例）\n")
+	b.WriteString("- 用户最关注的 3-5 个话题，每个附 1-2 条代表性评论原文\n")
+	b.WriteString("- 高频问题及建议的回复口径\n")
+	b.WriteString("- 点赞最多的评论\n")
+	if focus := promptArg(args, "focus", ""); focus != "" {
+		fmt.Fprintf(&b, "- 重点分析与「%s」相关的评论\n", focus)
+	}
+	b.WriteString("\n只做分析，不要调用 post_comment_to_feed 回复评论。\n")
+
+	return userPrompt("总结笔记评论", b.String()), nil
+}
+
+// promptCompetitorAnalysis 关键词竞品分析
+func promptCompetitorAnalysis(_ context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	args := req.Params.Arguments
+	keyword, err := requiredPromptArg(args, "keyword")
+	if err != nil {
+		return nil, err
+	}
+	count := promptArg(args, "count", "5")
+	filters := []string{"sort_by=" + promptArg(args, "sort_by", "最多点赞")}
+	for _, name := range []string{"note_type", "publish_time", "search_scope", "location"} {
+		if v := promptArg(args, name, ""); v != "" {
+			filters = append(filters, name+"="+v)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "请对小红书关键词「%s」做竞品分析。\n\n", keyword)
+	b.WriteString("## 步骤\n")
+	fmt.Fprintf(&b, "1. 调用 search_feeds（keyword=%s，filters: %s）搜索笔记\n", keyword, strings.Join(filters, ", "))
+	fmt.Fprintf(&b, "2. 选出互动数据最好的 %s 篇，逐篇调用 get_feed_detail 获取正文和评论\n", count)
+	b.WriteString("3. 如有需要，调用 user_profile 查看头部作者的主页和粉丝量\n\n")
+
+	b.WriteString("## 输出\n")
+	b.WriteString("- 每篇笔记：标题、作者、点赞/收藏/评论数、内容形式（图文/视频）、封面和标题的亮点\n")
+	b.WriteString("- 共性：标题句式、正文结构、常用标签、发布时间\n")
+	b.WriteString("- 评论区反映的用户需求和未被满足的痛点\n")
+	b.WriteString("- 3 个可以做的选题，每个给出示例标题和标签\n\n")
+
+	b.WriteString("示例标题和标签需要符合以下平台限制：\n")
+	b.WriteString(platformGuidance())
+	b.WriteString("\n只做分析，不要点赞、收藏、评论或发布。\n")
+
+	return userPrompt("关键词竞品分析", b.String()), nil
+}
+
+// completePromptArgument 补全提示词中的搜索筛选参数，可选值与 search_feeds 的 filters 一致。
+// MCP 补全只支持提示词和资源模板的参数，search_feeds 工具参数的可选值通过输入 schema 的 enum 声明
+func completePromptArgument(_ context.Context, req *mcp.CompleteRequest) (*mcp.CompleteResult, error) {
+	values := []string{}
+	if req.Params.Ref != nil && req.Params.Ref.Type == "ref/prompt" {
+		for _, v := range xiaohongshu.FilterValues()[req.Params.Argument.Name] {
+			if strings.HasPrefix(v, req.Params.Argument.Value) {
+				values = append(values, v)
+			}
+		}
+	}
+	return &mcp.CompleteResult{
+		Completion: mcp.CompletionResultDetails{Values: values, Total: len(values)},
+	}, nil
+}
+
+// platformGuidance 平台限制说明，数值与 lint_content、发布校验保持一致
+func platformGuidance() string {
+	limits := contentlint.DefaultLimits
+
+	var b strings.Builder
+	b.WriteString("## 平台限制\n")
+	fmt.Fprintf(&b, "- 标题宽度不超过 %d（中文/日文/韩文占 2，英文/数字占 1，即最多约 %d 个汉字）\n", limits.MaxTitleWidth, limits.MaxTitleWidth/2)
+	fmt.Fprintf(&b, "- 正文不超过 %d 字，分段清晰，可以适当使用表情符号\n", limits.MaxContentRunes)
+	fmt.Fprintf(&b, "- 标签不超过 %d 个，不需要带 #\n", limits.MaxTags)
+	fmt.Fprintf(&b, "- 图片 1-%d 张，推荐 3:4 竖图，其次 1:1 或 4:3，可以通过 image_options.aspect_ratio 自动裁剪\n", xiaohongshu.MaxImages)
+	b.WriteString("- 避免绝对化用语（如最好、第一）、站外引流（微信号、链接）和医疗功效宣称\n")
+	return b.String()
+}
+
+// userPrompt 构造只包含一条用户消息的提示词结果
+func userPrompt(description, text string) *mcp.GetPromptResult {
+	return &mcp.GetPromptResult{
+		Description: description,
+		Messages: []*mcp.PromptMessage{{
+			Role:    "user",
+			Content: &mcp.TextContent{Text: text},
+		}},
+	}
+}
+
+// promptArg 读取提示词参数，为空时返回默认值
+func promptArg(args map[string]string, name, defaultValue string) string {
+	if v := strings.TrimSpace(args[name]); v != "" {
+		return v
+	}
+	return defaultValue
+}
+
+// requiredPromptArg 读取必填的提示词参数
+func requiredPromptArg(args map[string]string, name string) (string, error) {
+	v := promptArg(args, name, "")
+	if v == "" {
+		return "", fmt.Errorf("缺少参数 %s", name)
+	}
+	return v, nil
+}
+
+// splitPromptList 按逗号或换行拆分列表参数
+func splitPromptList(s string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '，' || r == '\n'
+	}) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/bulkpublish"
+)
+
+// registerResources 注册 MCP 资源和资源模板
+func registerResources(server *mcp.Server, appServer *AppServer) {
+	resources, templates := 0, 0
+	addResource := func(r *mcp.Resource, h mcp.ResourceHandler) {
+		server.AddResource(r, h)
+		resources++
+	}
+	addResourceTemplate := func(t *mcp.ResourceTemplate, h mcp.ResourceHandler) {
+		server.AddResourceTemplate(t, h)
+		templates++
+	}
+
+	// 资源 1: 当前账号主页
+	addResource(&mcp.Resource{
+		URI:         resourceMe,
+		Name:        "me",
+		Title:       "我的主页",
+		Description: "当前登录账号的基本信息、互动数据和笔记列表，缓存 10 分钟",
+		MIMEType:    "application/json",
+	}, appServer.readMyProfileResource)
+
+	// 资源 2: dry run 草稿
+	addResource(&mcp.Resource{
+		URI:         resourceDrafts,
+		Name:        "drafts",
+		Title:       "草稿",
+		Description: "最近 dry run 时填写但未发布的图文/视频表单内容，从新到旧排序",
+		MIMEType:    "application/json",
+	}, appServer.readDraftsResource)
+
+	// 资源 3: 批量发布任务列表
+	addResource(&mcp.Resource{
+		URI:         resourceJobs,
+		Name:        "jobs",
+		Title:       "批量发布任务",
+		Description: "最近的批量发布任务报告",
+		MIMEType:    "application/json",
+	}, appServer.readJobsResource)
+
+	// 资源模板 1: 笔记详情
+	addResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: "xhs://note/{id}{?xsec_token}",
+		Name:        "note",
+		Title:       "笔记详情",
+		Description: "笔记内容、互动数据和评论，缓存 10 分钟。通过 list_feeds / search_feeds 获取过的笔记可以省略 xsec_token",
+		MIMEType:    "application/json",
+	}, appServer.readNoteResource)
+
+	// 资源模板 2: 用户主页
+	addResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: "xhs://user/{id}{?xsec_token}",
+		Name:        "user",
+		Title:       "用户主页",
+		Description: "用户基本信息、互动数据和笔记列表，缓存 10 分钟。通过 list_feeds / search_feeds 获取过的作者可以省略 xsec_token",
+		MIMEType:    "application/json",
+	}, appServer.readUserResource)
+
+	// 资源模板 3: 批量发布任务报告
+	addResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: "xhs://jobs/{id}",
+		Name:        "job",
+		Title:       "批量发布任务报告",
+		Description: "批量发布任务每一行的状态，任务进行中时可订阅更新",
+		MIMEType:    "application/json",
+	}, appServer.readJobResource)
+
+	// 数据更新时通知订阅了对应 URI 的客户端
+	appServer.xiaohongshuService.OnResourceUpdate(func(uri string) {
+		if err := server.ResourceUpdated(context.Background(), &mcp.ResourceUpdatedNotificationParams{URI: uri}); err != nil {
+			logrus.Warnf("发送资源更新通知失败: %s %v", uri, err)
+		}
+	})
+
+	logrus.Infof("Registered %d MCP resources and %d resource templates", resources, templates)
+}
+
+// subscribeResource 只允许订阅本服务的资源。订阅使用不带查询参数的 URI，如 xhs://note/{id}
+func subscribeResource(_ context.Context, req *mcp.SubscribeRequest) error {
+	if !strings.HasPrefix(req.Params.URI, "xhs://") {
+		return mcp.ResourceNotFoundError(req.Params.URI)
+	}
+	logrus.Infof("MCP: 订阅资源 %s", req.Params.URI)
+	return nil
+}
+
+func unsubscribeResource(_ context.Context, req *mcp.UnsubscribeRequest) error {
+	logrus.Infof("MCP: 取消订阅资源 %s", req.Params.URI)
+	return nil
+}
+
+// readMyProfileResource 读取当前账号主页
+func (s *AppServer) readMyProfileResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	result, err := s.xiaohongshuService.MyProfileResource(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取我的主页失败")
+	}
+	return jsonResource(req.Params.URI, result)
+}
+
+// readDraftsResource 读取 dry run 草稿
+func (s *AppServer) readDraftsResource(_ context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	drafts := s.xiaohongshuService.Drafts()
+	return jsonResource(req.Params.URI, map[string]any{"drafts": drafts, "count": len(drafts)})
+}
+
+// readJobsResource 读取批量发布任务列表
+func (s *AppServer) readJobsResource(_ context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	jobs := s.xiaohongshuService.ListBulkJobs()
+	return jsonResource(req.Params.URI, map[string]any{"jobs": jobs, "count": len(jobs)})
+}
+
+// readNoteResource 读取笔记详情
+func (s *AppServer) readNoteResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	id, xsecToken, err := parseResourceURI(req.Params.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.xiaohongshuService.NoteResource(ctx, id, xsecToken)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取笔记详情失败")
+	}
+	return jsonResource(req.Params.URI, result)
+}
+
+// readUserResource 读取用户主页
+func (s *AppServer) readUserResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	id, xsecToken, err := parseResourceURI(req.Params.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.xiaohongshuService.UserResource(ctx, id, xsecToken)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取用户主页失败")
+	}
+	return jsonResource(req.Params.URI, result)
+}
+
+// readJobResource 读取批量发布任务报告
+func (s *AppServer) readJobResource(_ context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	id, _, err := parseResourceURI(req.Params.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := s.xiaohongshuService.GetBulkJob(id)
+	if errors.Is(err, bulkpublish.ErrJobNotFound) {
+		return nil, mcp.ResourceNotFoundError(req.Params.URI)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return jsonResource(req.Params.URI, job)
+}
+
+// parseResourceURI 解析 xhs://{kind}/{id}?xsec_token=... 形式的 URI
+func parseResourceURI(uri string) (id, xsecToken string, err error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", "", fmt.Errorf("资源 URI 不合法: %s", uri)
+	}
+
+	id = strings.Trim(u.Path, "/")
+	if id == "" || strings.Contains(id, "/") {
+		return "", "", mcp.ResourceNotFoundError(uri)
+	}
+	return id, u.Query().Get("xsec_token"), nil
+}
+
+// jsonResource 将数据序列化为 JSON 资源内容
+func jsonResource(uri string, data any) (*mcp.ReadResourceResult, error) {
+	jsonData, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "序列化资源失败")
+	}
+
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{{
+			URI:      uri,
+			MIMEType: "application/json",
+			Text:     string(jsonData),
+		}},
+	}, nil
+}
@@ -4,28 +4,63 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"reflect"
 	"runtime/debug"
+	"slices"
 
+	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/sirupsen/logrus"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/apikey"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/contentlint"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/imageproc"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/mdformat"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/posttemplate"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/progress"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/textcard"
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
 )
 
 // MCP 工具参数结构体定义
 
 // PublishContentArgs 发布内容的参数
 type PublishContentArgs struct {
-	Title   string   `json:"title" jsonschema:"内容标题（小红书限制：最多20个中文字或英文单词）"`
-	Content string   `json:"content" jsonschema:"正文内容，不包含以#开头的标签内容，所有话题标签都用tags参数来生成和提供即可"`
-	Images  []string `json:"images" jsonschema:"图片路径列表（至少需要1张图片）。支持两种方式：1. HTTP/HTTPS图片链接（自动下载）；2. 本地图片绝对路径（推荐，如:/Users/user/image.jpg）"`
-	Tags    []string `json:"tags,omitempty" jsonschema:"话题标签列表（可选参数），如 [美食, 旅行, 生活]"`
+	Title    string   `json:"title" jsonschema:"内容标题（小红书限制：最多20个中文字或英文单词）"`
+	Content  string   `json:"content" jsonschema:"正文内容，不包含以#开头的标签内容，所有话题标签都用tags参数来生成和提供即可"`
+	Images   []string `json:"images" jsonschema:"图片列表（1-18张图片），按顺序发布，上传后会校验顺序。支持三种方式：1. HTTP/HTTPS图片链接（自动下载）；2. 本地图片绝对路径（推荐，如:/Users/user/image.jpg）；3. base64 图片数据或 data URI（如:data:image/png;base64,...）。任意一张图片无效都会导致发布失败"`
+	Tags     []string `json:"tags,omitempty" jsonschema:"话题标签列表（可选参数），如 [美食, 旅行, 生活]"`
+	Mentions []string `json:"mentions,omitempty" jsonschema:"需要@的用户列表（可选参数），填写与联想结果完全一致的用户昵称或小红书号，如 [小红薯, 123456789]，找不到完全一致的用户时发布失败"`
+
+	ImageOptions *imageproc.Options `json:"image_options,omitempty" jsonschema:"图片预处理选项（可选参数），如转换为JPEG、按3:4裁剪、限制文件大小"`
+	ImageLabels  []ImageLabelOption `json:"image_labels,omitempty" jsonschema:"单张图片的标记（可选参数），如 [{index: 0, labels: [上海]}]，index 为图片在 images 中的序号（从0开始）"`
+	DryRun       bool               `json:"dry_run,omitempty" jsonschema:"是否只预览（可选参数），为 true 时完整填写发布表单但不点击发布，返回整页截图和实际填写内容供人工确认"`
+
+	IdempotencyKey string `json:"idempotency_key,omitempty" jsonschema:"幂等键（可选参数），超时重试时使用相同的键，已发布成功则直接返回之前的结果，不会重复发布"`
+	Force          bool   `json:"force,omitempty" jsonschema:"是否强制发布（可选参数），默认相同标题+正文+图片在一定时间内不允许重复发布"`
+	Markdown       bool   `json:"markdown,omitempty" jsonschema:"正文是否为Markdown（可选参数），为 true 时转换为小红书纯文本（标题/列表转为表情符号、去除链接等语法），并将正文中的#标签提取到tags"`
+	TruncateTags   bool   `json:"truncate_tags,omitempty" jsonschema:"标签超过10个时是否只保留前10个继续发布（可选参数），默认超过上限时不发布并返回检查错误"`
 }
 
-// PublishVideoArgs 发布视频的参数（仅支持本地单个视频文件）
+// PublishVideoArgs 发布视频的参数（单个视频文件）
 type PublishVideoArgs struct {
-	Title   string   `json:"title" jsonschema:"内容标题（小红书限制：最多20个中文字或英文单词）"`
-	Content string   `json:"content" jsonschema:"正文内容，不包含以#开头的标签内容，所有话题标签都用tags参数来生成和提供即可"`
-	Video   string   `json:"video" jsonschema:"本地视频绝对路径（仅支持单个视频文件，如:/Users/user/video.mp4）"`
-	Tags    []string `json:"tags,omitempty" jsonschema:"话题标签列表（可选参数），如 [美食, 旅行, 生活]"`
+	Title    string          `json:"title" jsonschema:"内容标题（小红书限制：最多20个中文字或英文单词）"`
+	Content  string          `json:"content" jsonschema:"正文内容，不包含以#开头的标签内容，所有话题标签都用tags参数来生成和提供即可"`
+	Video    string          `json:"video" jsonschema:"视频文件（仅支持单个 MP4/MOV 视频）。支持两种方式：1. HTTP/HTTPS视频链接（自动下载）；2. 本地视频绝对路径（如:/Users/user/video.mp4）。上传前会校验时长、分辨率、编码和大小"`
+	Tags     []string        `json:"tags,omitempty" jsonschema:"话题标签列表（可选参数），如 [美食, 旅行, 生活]"`
+	Mentions []string        `json:"mentions,omitempty" jsonschema:"需要@的用户列表（可选参数），填写与联想结果完全一致的用户昵称或小红书号，如 [小红薯, 123456789]，找不到完全一致的用户时发布失败"`
+	Cover    *VideoCoverArgs `json:"cover,omitempty" jsonschema:"视频封面（可选参数），不提供时使用平台自动选取的封面"`
+	DryRun   bool            `json:"dry_run,omitempty" jsonschema:"是否只预览（可选参数），为 true 时完整填写发布表单但不点击发布，返回整页截图和实际填写内容供人工确认"`
+
+	IdempotencyKey string `json:"idempotency_key,omitempty" jsonschema:"幂等键（可选参数），超时重试时使用相同的键，已发布成功则直接返回之前的结果，不会重复发布"`
+	Force          bool   `json:"force,omitempty" jsonschema:"是否强制发布（可选参数），默认相同标题+正文+视频在一定时间内不允许重复发布"`
+	Markdown       bool   `json:"markdown,omitempty" jsonschema:"正文是否为Markdown（可选参数），为 true 时转换为小红书纯文本（标题/列表转为表情符号、去除链接等语法），并将正文中的#标签提取到tags"`
+	TruncateTags   bool   `json:"truncate_tags,omitempty" jsonschema:"标签超过10个时是否只保留前10个继续发布（可选参数），默认超过上限时不发布并返回检查错误"`
+}
+
+// VideoCoverArgs 视频封面参数，image 与 timestamp 二选一
+type VideoCoverArgs struct {
+	Image     string   `json:"image,omitempty" jsonschema:"自定义封面图片，支持HTTP/HTTPS链接或本地图片绝对路径"`
+	Timestamp *float64 `json:"timestamp,omitempty" jsonschema:"截取视频中指定时间点（秒）的画面作为封面，如 3.5，0 表示第一帧"`
 }
 
 // SearchFeedsArgs 搜索内容的参数
@@ -76,6 +111,39 @@ type FavoriteFeedArgs struct {
 	Unfavorite bool   `json:"unfavorite,omitempty" jsonschema:"是否取消收藏，true为取消收藏，false或未设置则为收藏"`
 }
 
+// LintContentArgs 内容检查参数
+type LintContentArgs struct {
+	Title   string   `json:"title" jsonschema:"内容标题"`
+	Content string   `json:"content" jsonschema:"正文内容"`
+	Tags    []string `json:"tags,omitempty" jsonschema:"话题标签列表（可选参数）"`
+}
+
+// FormatContentArgs Markdown 转换参数
+type FormatContentArgs struct {
+	Content   string `json:"content" jsonschema:"Markdown 格式的正文"`
+	MaxLength int    `json:"max_length,omitempty" jsonschema:"正文最大字数（可选参数），超过时按段落截断，默认1000"`
+}
+
+// RenderTextCardsArgs 文字卡片渲染参数
+type RenderTextCardsArgs struct {
+	Title      string             `json:"title,omitempty" jsonschema:"卡片标题，只出现在第一页"`
+	Paragraphs []string           `json:"paragraphs" jsonschema:"正文段落列表，内容超过一页时自动分页（最多18页）"`
+	Template   *textcard.Template `json:"template,omitempty" jsonschema:"卡片模板（可选参数）：背景颜色/图片、字体文件、字号、留白等，默认1080x1440白底"`
+}
+
+// PublishFromTemplateArgs 使用模板发布参数
+type PublishFromTemplateArgs struct {
+	Name      string         `json:"name" jsonschema:"模板名称，可通过 list_templates 获取"`
+	Variables map[string]any `json:"variables,omitempty" jsonschema:"模板变量，如 {week: 12, products: [保温杯, 帆布包]}，必填变量见模板的 variables"`
+	Images    []string       `json:"images,omitempty" jsonschema:"额外的图片（可选参数），追加在模板图片之后，支持链接、本地路径和base64"`
+
+	DryRun         bool   `json:"dry_run,omitempty" jsonschema:"是否只预览（可选参数），为 true 时完整填写发布表单但不点击发布"`
+	IdempotencyKey string `json:"idempotency_key,omitempty" jsonschema:"幂等键（可选参数），重试时使用相同的键不会重复发布"`
+	Force          bool   `json:"force,omitempty" jsonschema:"是否强制发布（可选参数），忽略重复内容检测"`
+
+	rendered *posttemplate.Rendered // 需要确认时展示给用户的渲染结果
+}
+
 // InitMCPServer 初始化 MCP Server
 func InitMCPServer(appServer *AppServer) *mcp.Server {
 	// 创建 MCP Server
@@ -84,12 +152,20 @@ func InitMCPServer(appServer *AppServer) *mcp.Server {
 			Name:    "xiaohongshu-mcp",
 			Version: "2.0.0",
 		},
-		nil,
+		&mcp.ServerOptions{
+			SubscribeHandler:   subscribeResource,
+			UnsubscribeHandler: unsubscribeResource,
+			CompletionHandler:  completePromptArgument,
+		},
 	)
 
 	// 注册所有工具
 	registerTools(server, appServer)
 
+	// 注册资源和提示词
+	registerResources(server, appServer)
+	registerPrompts(server)
+
 	logrus.Info("MCP Server initialized with official SDK")
 
 	return server
@@ -127,193 +203,478 @@ func withPanicRecovery[T any](
 	}
 }
 
+// withProgress 客户端请求携带 progressToken 时，将服务层上报的进度转换为 notifications/progress。
+// 客户端取消请求时 ctx 随之取消，正在执行的浏览器操作也会中止
+func withProgress[T any](
+	handler func(context.Context, *mcp.CallToolRequest, T) (*mcp.CallToolResult, any, error),
+) func(context.Context, *mcp.CallToolRequest, T) (*mcp.CallToolResult, any, error) {
+
+	return func(ctx context.Context, req *mcp.CallToolRequest, args T) (*mcp.CallToolResult, any, error) {
+		token := req.Params.GetProgressToken()
+		if token == nil || req.Session == nil {
+			return handler(ctx, req, args)
+		}
+
+		ctx = progress.WithFunc(ctx, func(current, total float64, message string) {
+			err := req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+				ProgressToken: token,
+				Progress:      current,
+				Total:         total,
+				Message:       message,
+			})
+			if err != nil {
+				logrus.Warnf("发送进度通知失败: tool=%s %v", req.Params.Name, err)
+			}
+		})
+		return handler(ctx, req, args)
+	}
+}
+
+// withScope 要求调用方的 API Key 拥有 scope 权限。
+// 未启用认证时请求不携带 TokenInfo，不做限制
+func withScope[T any](
+	scope apikey.Scope,
+	handler func(context.Context, *mcp.CallToolRequest, T) (*mcp.CallToolResult, any, error),
+) func(context.Context, *mcp.CallToolRequest, T) (*mcp.CallToolResult, any, error) {
+
+	return func(ctx context.Context, req *mcp.CallToolRequest, args T) (*mcp.CallToolResult, any, error) {
+		if req.Extra != nil && req.Extra.TokenInfo != nil && !slices.Contains(req.Extra.TokenInfo.Scopes, string(scope)) {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{
+						Text: fmt.Sprintf("API Key 权限不足：工具 %s 需要 %s 权限", req.Params.Name, scope),
+					},
+				},
+				IsError: true,
+			}, nil, nil
+		}
+
+		return handler(ctx, req, args)
+	}
+}
+
 // registerTools 注册所有 MCP 工具
 func registerTools(server *mcp.Server, appServer *AppServer) {
+	tools := 0
+
 	// 工具 1: 检查登录状态
-	mcp.AddTool(server,
+	addTool(server, &tools,
 		&mcp.Tool{
-			Name:        "check_login_status",
-			Description: "检查小红书登录状态",
+			Name:         "check_login_status",
+			Title:        "检查登录状态",
+			Description:  "检查小红书登录状态",
+			Annotations:  &mcp.ToolAnnotations{ReadOnlyHint: true},
+			OutputSchema: outputSchema[LoginStatusResponse](),
 		},
 		withPanicRecovery("check_login_status", func(ctx context.Context, req *mcp.CallToolRequest, _ any) (*mcp.CallToolResult, any, error) {
 			result := appServer.handleCheckLoginStatus(ctx)
-			return convertToMCPResult(result), nil, nil
+			return convertToMCPResult(result)
 		}),
 	)
 
 	// 工具 2: 获取登录二维码
-	mcp.AddTool(server,
+	addTool(server, &tools,
 		&mcp.Tool{
-			Name:        "get_login_qrcode",
-			Description: "获取登录二维码（返回 Base64 图片和超时时间）",
+			Name:         "get_login_qrcode",
+			Title:        "获取登录二维码",
+			Description:  "获取登录二维码（返回 Base64 图片和超时时间）",
+			Annotations:  &mcp.ToolAnnotations{DestructiveHint: boolPtr(false)},
+			OutputSchema: outputSchema[LoginQrcodeResult](),
 		},
-		withPanicRecovery("get_login_qrcode", func(ctx context.Context, req *mcp.CallToolRequest, _ any) (*mcp.CallToolResult, any, error) {
+		withScope(apikey.ScopeAdmin, withPanicRecovery("get_login_qrcode", func(ctx context.Context, req *mcp.CallToolRequest, _ any) (*mcp.CallToolResult, any, error) {
 			result := appServer.handleGetLoginQrcode(ctx)
-			return convertToMCPResult(result), nil, nil
-		}),
+			return convertToMCPResult(result)
+		})),
 	)
 
 	// 工具 3: 删除 cookies（登录重置）
-	mcp.AddTool(server,
+	addTool(server, &tools,
 		&mcp.Tool{
-			Name:        "delete_cookies",
-			Description: "删除 cookies 文件，重置登录状态。删除后需要重新登录。",
+			Name:         "delete_cookies",
+			Title:        "删除 cookies",
+			Description:  "删除 cookies 文件，重置登录状态。删除后需要重新登录。",
+			Annotations:  &mcp.ToolAnnotations{DestructiveHint: boolPtr(true), IdempotentHint: true, OpenWorldHint: boolPtr(false)},
+			OutputSchema: outputSchema[DeleteCookiesResult](),
 		},
-		withPanicRecovery("delete_cookies", func(ctx context.Context, req *mcp.CallToolRequest, _ any) (*mcp.CallToolResult, any, error) {
+		withScope(apikey.ScopeAdmin, withConfirmation(appServer, describeDeleteCookies, withPanicRecovery("delete_cookies", func(ctx context.Context, req *mcp.CallToolRequest, _ any) (*mcp.CallToolResult, any, error) {
 			result := appServer.handleDeleteCookies(ctx)
-			return convertToMCPResult(result), nil, nil
-		}),
+			return convertToMCPResult(result)
+		}))),
 	)
 
 	// 工具 4: 发布内容
-	mcp.AddTool(server,
+	addTool(server, &tools,
 		&mcp.Tool{
-			Name:        "publish_content",
-			Description: "发布小红书图文内容",
+			Name:         "publish_content",
+			Title:        "发布图文笔记",
+			Description:  "发布小红书图文内容",
+			Annotations:  &mcp.ToolAnnotations{DestructiveHint: boolPtr(false)},
+			OutputSchema: outputSchema[PublishResponse](),
 		},
-		withPanicRecovery("publish_content", func(ctx context.Context, req *mcp.CallToolRequest, args PublishContentArgs) (*mcp.CallToolResult, any, error) {
+		withScope(apikey.ScopePublish, withProgress(withConfirmation(appServer, describePublishContent(appServer), withPanicRecovery("publish_content", func(ctx context.Context, req *mcp.CallToolRequest, args PublishContentArgs) (*mcp.CallToolResult, any, error) {
 			// 转换参数格式到现有的 handler
 			argsMap := map[string]interface{}{
-				"title":   args.Title,
-				"content": args.Content,
-				"images":  convertStringsToInterfaces(args.Images),
-				"tags":    convertStringsToInterfaces(args.Tags),
+				"title":    args.Title,
+				"content":  args.Content,
+				"images":   convertStringsToInterfaces(args.Images),
+				"tags":     convertStringsToInterfaces(args.Tags),
+				"mentions": convertStringsToInterfaces(args.Mentions),
+				"dry_run":  args.DryRun,
+
+				"idempotency_key": args.IdempotencyKey,
+				"force":           args.Force,
+				"markdown":        args.Markdown,
+				"truncate_tags":   args.TruncateTags,
+			}
+			if args.ImageOptions != nil {
+				argsMap["image_options"] = args.ImageOptions
+			}
+			if len(args.ImageLabels) > 0 {
+				argsMap["image_labels"] = args.ImageLabels
 			}
 			result := appServer.handlePublishContent(ctx, argsMap)
-			return convertToMCPResult(result), nil, nil
-		}),
+			return convertToMCPResult(result)
+		})))),
 	)
 
 	// 工具 5: 获取Feed列表
-	mcp.AddTool(server,
+	addTool(server, &tools,
 		&mcp.Tool{
-			Name:        "list_feeds",
-			Description: "获取首页 Feeds 列表",
+			Name:         "list_feeds",
+			Title:        "首页推荐",
+			Description:  "获取首页 Feeds 列表",
+			Annotations:  &mcp.ToolAnnotations{ReadOnlyHint: true},
+			OutputSchema: outputSchema[FeedsListResponse](),
 		},
-		withPanicRecovery("list_feeds", func(ctx context.Context, req *mcp.CallToolRequest, _ any) (*mcp.CallToolResult, any, error) {
+		withProgress(withPanicRecovery("list_feeds", func(ctx context.Context, req *mcp.CallToolRequest, _ any) (*mcp.CallToolResult, any, error) {
 			result := appServer.handleListFeeds(ctx)
-			return convertToMCPResult(result), nil, nil
-		}),
+			return convertToMCPResult(result)
+		})),
 	)
 
 	// 工具 6: 搜索内容
-	mcp.AddTool(server,
+	addTool(server, &tools,
 		&mcp.Tool{
-			Name:        "search_feeds",
-			Description: "搜索小红书内容（需要已登录）",
+			Name:         "search_feeds",
+			Title:        "搜索笔记",
+			Description:  "搜索小红书内容（需要已登录）",
+			Annotations:  &mcp.ToolAnnotations{ReadOnlyHint: true},
+			InputSchema:  searchFeedsInputSchema(),
+			OutputSchema: outputSchema[FeedsListResponse](),
 		},
-		withPanicRecovery("search_feeds", func(ctx context.Context, req *mcp.CallToolRequest, args SearchFeedsArgs) (*mcp.CallToolResult, any, error) {
+		withProgress(withPanicRecovery("search_feeds", func(ctx context.Context, req *mcp.CallToolRequest, args SearchFeedsArgs) (*mcp.CallToolResult, any, error) {
 			result := appServer.handleSearchFeeds(ctx, args)
-			return convertToMCPResult(result), nil, nil
-		}),
+			return convertToMCPResult(result)
+		})),
 	)
 
 	// 工具 7: 获取Feed详情
-	mcp.AddTool(server,
+	addTool(server, &tools,
 		&mcp.Tool{
-			Name:        "get_feed_detail",
-			Description: "获取小红书笔记详情，返回笔记内容、图片、作者信息、互动数据（点赞/收藏/分享数）及评论列表",
+			Name:         "get_feed_detail",
+			Title:        "笔记详情",
+			Description:  "获取小红书笔记详情，返回笔记内容、图片、作者信息、互动数据（点赞/收藏/分享数）及评论列表",
+			Annotations:  &mcp.ToolAnnotations{ReadOnlyHint: true},
+			OutputSchema: outputSchema[FeedDetailResponse](),
 		},
-		withPanicRecovery("get_feed_detail", func(ctx context.Context, req *mcp.CallToolRequest, args FeedDetailArgs) (*mcp.CallToolResult, any, error) {
+		withProgress(withPanicRecovery("get_feed_detail", func(ctx context.Context, req *mcp.CallToolRequest, args FeedDetailArgs) (*mcp.CallToolResult, any, error) {
 			argsMap := map[string]interface{}{
 				"feed_id":    args.FeedID,
 				"xsec_token": args.XsecToken,
 			}
 			result := appServer.handleGetFeedDetail(ctx, argsMap)
-			return convertToMCPResult(result), nil, nil
-		}),
+			return convertToMCPResult(result)
+		})),
 	)
 
 	// 工具 8: 获取用户主页
-	mcp.AddTool(server,
+	addTool(server, &tools,
 		&mcp.Tool{
-			Name:        "user_profile",
-			Description: "获取指定的小红书用户主页，返回用户基本信息，关注、粉丝、获赞量及其笔记内容",
+			Name:         "user_profile",
+			Title:        "用户主页",
+			Description:  "获取指定的小红书用户主页，返回用户基本信息，关注、粉丝、获赞量及其笔记内容",
+			Annotations:  &mcp.ToolAnnotations{ReadOnlyHint: true},
+			OutputSchema: outputSchema[UserProfileResponse](),
 		},
-		withPanicRecovery("user_profile", func(ctx context.Context, req *mcp.CallToolRequest, args UserProfileArgs) (*mcp.CallToolResult, any, error) {
+		withProgress(withPanicRecovery("user_profile", func(ctx context.Context, req *mcp.CallToolRequest, args UserProfileArgs) (*mcp.CallToolResult, any, error) {
 			argsMap := map[string]interface{}{
 				"user_id":    args.UserID,
 				"xsec_token": args.XsecToken,
 			}
 			result := appServer.handleUserProfile(ctx, argsMap)
-			return convertToMCPResult(result), nil, nil
-		}),
+			return convertToMCPResult(result)
+		})),
 	)
 
 	// 工具 9: 发表评论
-	mcp.AddTool(server,
+	addTool(server, &tools,
 		&mcp.Tool{
-			Name:        "post_comment_to_feed",
-			Description: "发表评论到小红书笔记",
+			Name:         "post_comment_to_feed",
+			Title:        "发表评论",
+			Description:  "发表评论到小红书笔记",
+			Annotations:  &mcp.ToolAnnotations{DestructiveHint: boolPtr(false)},
+			OutputSchema: outputSchema[PostCommentResponse](),
 		},
-		withPanicRecovery("post_comment_to_feed", func(ctx context.Context, req *mcp.CallToolRequest, args PostCommentArgs) (*mcp.CallToolResult, any, error) {
+		withScope(apikey.ScopePublish, withConfirmation(appServer, describePostComment, withPanicRecovery("post_comment_to_feed", func(ctx context.Context, req *mcp.CallToolRequest, args PostCommentArgs) (*mcp.CallToolResult, any, error) {
 			argsMap := map[string]interface{}{
 				"feed_id":    args.FeedID,
 				"xsec_token": args.XsecToken,
 				"content":    args.Content,
 			}
 			result := appServer.handlePostComment(ctx, argsMap)
-			return convertToMCPResult(result), nil, nil
-		}),
+			return convertToMCPResult(result)
+		}))),
 	)
 
-	// 工具 10: 发布视频（仅本地文件）
-	mcp.AddTool(server,
+	// 工具 10: 发布视频
+	addTool(server, &tools,
 		&mcp.Tool{
-			Name:        "publish_with_video",
-			Description: "发布小红书视频内容（仅支持本地单个视频文件）",
+			Name:         "publish_with_video",
+			Title:        "发布视频笔记",
+			Description:  "发布小红书视频内容（单个视频，支持本地文件或 HTTP/HTTPS 链接）",
+			Annotations:  &mcp.ToolAnnotations{DestructiveHint: boolPtr(false)},
+			OutputSchema: outputSchema[PublishVideoResponse](),
 		},
-		withPanicRecovery("publish_with_video", func(ctx context.Context, req *mcp.CallToolRequest, args PublishVideoArgs) (*mcp.CallToolResult, any, error) {
+		withScope(apikey.ScopePublish, withProgress(withConfirmation(appServer, describePublishVideo(appServer), withPanicRecovery("publish_with_video", func(ctx context.Context, req *mcp.CallToolRequest, args PublishVideoArgs) (*mcp.CallToolResult, any, error) {
 			argsMap := map[string]interface{}{
-				"title":   args.Title,
-				"content": args.Content,
-				"video":   args.Video,
-				"tags":    convertStringsToInterfaces(args.Tags),
+				"title":    args.Title,
+				"content":  args.Content,
+				"video":    args.Video,
+				"tags":     convertStringsToInterfaces(args.Tags),
+				"mentions": convertStringsToInterfaces(args.Mentions),
+				"dry_run":  args.DryRun,
+
+				"idempotency_key": args.IdempotencyKey,
+				"force":           args.Force,
+				"markdown":        args.Markdown,
+				"truncate_tags":   args.TruncateTags,
+			}
+			if args.Cover != nil {
+				argsMap["cover_image"] = args.Cover.Image
+				argsMap["cover_timestamp"] = args.Cover.Timestamp
 			}
 			result := appServer.handlePublishVideo(ctx, argsMap)
-			return convertToMCPResult(result), nil, nil
-		}),
+			return convertToMCPResult(result)
+		})))),
 	)
 
 	// 工具 11: 点赞笔记
-	mcp.AddTool(server,
+	addTool(server, &tools,
 		&mcp.Tool{
-			Name:        "like_feed",
-			Description: "为指定笔记点赞或取消点赞（如已点赞将跳过点赞，如未点赞将跳过取消点赞）",
+			Name:         "like_feed",
+			Title:        "点赞/取消点赞",
+			Description:  "为指定笔记点赞或取消点赞（如已点赞将跳过点赞，如未点赞将跳过取消点赞）",
+			Annotations:  &mcp.ToolAnnotations{DestructiveHint: boolPtr(false), IdempotentHint: true},
+			OutputSchema: outputSchema[ActionResult](),
 		},
-		withPanicRecovery("like_feed", func(ctx context.Context, req *mcp.CallToolRequest, args LikeFeedArgs) (*mcp.CallToolResult, any, error) {
+		withScope(apikey.ScopePublish, withPanicRecovery("like_feed", func(ctx context.Context, req *mcp.CallToolRequest, args LikeFeedArgs) (*mcp.CallToolResult, any, error) {
 			argsMap := map[string]interface{}{
 				"feed_id":    args.FeedID,
 				"xsec_token": args.XsecToken,
 				"unlike":     args.Unlike,
 			}
 			result := appServer.handleLikeFeed(ctx, argsMap)
-			return convertToMCPResult(result), nil, nil
-		}),
+			return convertToMCPResult(result)
+		})),
 	)
 
 	// 工具 12: 收藏笔记
-	mcp.AddTool(server,
+	addTool(server, &tools,
 		&mcp.Tool{
-			Name:        "favorite_feed",
-			Description: "收藏指定笔记或取消收藏（如已收藏将跳过收藏，如未收藏将跳过取消收藏）",
+			Name:         "favorite_feed",
+			Title:        "收藏/取消收藏",
+			Description:  "收藏指定笔记或取消收藏（如已收藏将跳过收藏，如未收藏将跳过取消收藏）",
+			Annotations:  &mcp.ToolAnnotations{DestructiveHint: boolPtr(false), IdempotentHint: true},
+			OutputSchema: outputSchema[ActionResult](),
 		},
-		withPanicRecovery("favorite_feed", func(ctx context.Context, req *mcp.CallToolRequest, args FavoriteFeedArgs) (*mcp.CallToolResult, any, error) {
+		withScope(apikey.ScopePublish, withPanicRecovery("favorite_feed", func(ctx context.Context, req *mcp.CallToolRequest, args FavoriteFeedArgs) (*mcp.CallToolResult, any, error) {
 			argsMap := map[string]interface{}{
 				"feed_id":    args.FeedID,
 				"xsec_token": args.XsecToken,
 				"unfavorite": args.Unfavorite,
 			}
 			result := appServer.handleFavoriteFeed(ctx, argsMap)
-			return convertToMCPResult(result), nil, nil
+			return convertToMCPResult(result)
+		})),
+	)
+
+	// 工具 13: 发布前内容检查
+	addTool(server, &tools,
+		&mcp.Tool{
+			Name:         "lint_content",
+			Title:        "检查笔记内容",
+			Description:  "发布前检查小红书内容：标题/正文长度、标签数量与重复、非法字符、外链/手机号、敏感词，返回结构化的检查结果（发布时会自动执行，error 级别问题会阻止发布）",
+			Annotations:  &mcp.ToolAnnotations{ReadOnlyHint: true, OpenWorldHint: boolPtr(false)},
+			OutputSchema: outputSchema[contentlint.Result](),
+		},
+		withPanicRecovery("lint_content", func(ctx context.Context, req *mcp.CallToolRequest, args LintContentArgs) (*mcp.CallToolResult, any, error) {
+			argsMap := map[string]interface{}{
+				"title":   args.Title,
+				"content": args.Content,
+				"tags":    convertStringsToInterfaces(args.Tags),
+			}
+			result := appServer.handleLintContent(ctx, argsMap)
+			return convertToMCPResult(result)
+		}),
+	)
+
+	// 工具 14: Markdown 转小红书正文
+	addTool(server, &tools,
+		&mcp.Tool{
+			Name:         "format_content",
+			Title:        "Markdown 转换为正文",
+			Description:  "将Markdown正文转换为适合小红书的纯文本：标题和列表转为表情符号、去除加粗/链接/代码等不支持的语法、规范换行、提取正文中的#标签，并限制正文长度。返回转换后的 content 和 tags，可直接用于发布",
+			Annotations:  &mcp.ToolAnnotations{ReadOnlyHint: true, OpenWorldHint: boolPtr(false)},
+			OutputSchema: outputSchema[mdformat.Result](),
+		},
+		withPanicRecovery("format_content", func(ctx context.Context, req *mcp.CallToolRequest, args FormatContentArgs) (*mcp.CallToolResult, any, error) {
+			argsMap := map[string]interface{}{
+				"content":    args.Content,
+				"max_length": args.MaxLength,
+			}
+			result := appServer.handleFormatContent(ctx, argsMap)
+			return convertToMCPResult(result)
+		}),
+	)
+
+	// 工具 15: 渲染文字卡片
+	addTool(server, &tools,
+		&mcp.Tool{
+			Name:         "render_text_cards",
+			Title:        "生成文字卡片",
+			Description:  "将标题和段落渲染为3:4文字卡片图片（PNG），内容较长时自动分页。返回卡片图片和本地文件路径，路径可直接作为 publish_content 的 images 参数",
+			Annotations:  &mcp.ToolAnnotations{DestructiveHint: boolPtr(false), IdempotentHint: true, OpenWorldHint: boolPtr(false)},
+			OutputSchema: outputSchema[RenderTextCardsResponse](),
+		},
+		withScope(apikey.ScopePublish, withPanicRecovery("render_text_cards", func(ctx context.Context, req *mcp.CallToolRequest, args RenderTextCardsArgs) (*mcp.CallToolResult, any, error) {
+			argsMap := map[string]interface{}{
+				"title":      args.Title,
+				"paragraphs": convertStringsToInterfaces(args.Paragraphs),
+			}
+			if args.Template != nil {
+				argsMap["template"] = args.Template
+			}
+			result := appServer.handleRenderTextCards(ctx, argsMap)
+			return convertToMCPResult(result)
+		})),
+	)
+
+	// 工具 16: 列出笔记模板
+	addTool(server, &tools,
+		&mcp.Tool{
+			Name:         "list_templates",
+			Title:        "笔记模板列表",
+			Description:  "列出已保存的笔记模板，包括模板的标题/正文/标签/图片和需要提供的变量",
+			Annotations:  &mcp.ToolAnnotations{ReadOnlyHint: true, OpenWorldHint: boolPtr(false)},
+			OutputSchema: outputSchema[ListTemplatesResult](),
+		},
+		withPanicRecovery("list_templates", func(ctx context.Context, req *mcp.CallToolRequest, _ any) (*mcp.CallToolResult, any, error) {
+			result := appServer.handleListTemplates(ctx)
+			return convertToMCPResult(result)
 		}),
 	)
 
-	logrus.Infof("Registered %d MCP tools", 12)
+	// 工具 17: 使用模板发布
+	addTool(server, &tools,
+		&mcp.Tool{
+			Name:         "publish_from_template",
+			Title:        "使用模板发布",
+			Description:  "使用已保存的笔记模板和变量渲染标题、正文、标签和图片，然后发布图文内容，用于每周固定格式的笔记",
+			Annotations:  &mcp.ToolAnnotations{DestructiveHint: boolPtr(false)},
+			OutputSchema: outputSchema[PublishResponse](),
+		},
+		withScope(apikey.ScopePublish, withProgress(withConfirmation(appServer, describePublishFromTemplate(appServer), withPanicRecovery("publish_from_template", func(ctx context.Context, req *mcp.CallToolRequest, args PublishFromTemplateArgs) (*mcp.CallToolResult, any, error) {
+			argsMap := map[string]interface{}{
+				"name":      args.Name,
+				"variables": args.Variables,
+				"images":    convertStringsToInterfaces(args.Images),
+
+				"dry_run":         args.DryRun,
+				"idempotency_key": args.IdempotencyKey,
+				"force":           args.Force,
+				"rendered":        args.rendered,
+			}
+			result := appServer.handlePublishFromTemplate(ctx, argsMap)
+			return convertToMCPResult(result)
+		})))),
+	)
+
+	logrus.Infof("Registered %d MCP tools", tools)
+}
+
+// addTool 注册工具并累加已注册的工具数量
+func addTool[In, Out any](server *mcp.Server, count *int, t *mcp.Tool, h mcp.ToolHandlerFor[In, Out]) {
+	mcp.AddTool(server, t, h)
+	*count++
+}
+
+// outputSchema 根据 T 生成工具的输出 schema。
+// 服务返回的列表和 map 可能为 null，统一允许 null，避免客户端按 schema 校验结构化结果时失败
+func outputSchema[T any]() *jsonschema.Schema {
+	schema, err := jsonschema.For[T](&jsonschema.ForOptions{TypeSchemas: outputTypeSchemas})
+	if err != nil {
+		panic(fmt.Sprintf("生成输出 schema 失败: %v", err))
+	}
+	allowNullCollections(schema)
+	return schema
 }
 
-// convertToMCPResult 将自定义的 MCPToolResult 转换为官方 SDK 的格式
-func convertToMCPResult(result *MCPToolResult) *mcp.CallToolResult {
+// searchFeedsInputSchema 搜索参数的 schema，筛选字段声明可选值，便于客户端提示和校验。
+// 可选值包含空字符串，与省略该字段相同，表示使用默认值
+func searchFeedsInputSchema() *jsonschema.Schema {
+	schema, err := jsonschema.For[SearchFeedsArgs](nil)
+	if err != nil {
+		panic(fmt.Sprintf("生成搜索参数 schema 失败: %v", err))
+	}
+	filters := schema.Properties["filters"]
+	for field, values := range xiaohongshu.FilterValues() {
+		filters.Properties[field].Enum = append(filters.Properties[field].Enum, "")
+		for _, v := range values {
+			filters.Properties[field].Enum = append(filters.Properties[field].Enum, v)
+		}
+	}
+	return schema
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+// outputTypeSchemas 无法自动生成 schema 的类型
+var outputTypeSchemas = func() map[reflect.Type]*jsonschema.Schema {
+	// 评论的 subComments 是递归类型，子评论只声明为对象
+	comment, err := jsonschema.For[xiaohongshu.Comment](&jsonschema.ForOptions{
+		TypeSchemas: map[reflect.Type]*jsonschema.Schema{
+			reflect.TypeFor[[]xiaohongshu.Comment](): {Type: "array", Items: &jsonschema.Schema{Type: "object"}},
+		},
+	})
+	if err != nil {
+		panic(fmt.Sprintf("生成评论 schema 失败: %v", err))
+	}
+	return map[reflect.Type]*jsonschema.Schema{
+		reflect.TypeFor[xiaohongshu.Comment](): comment,
+	}
+}()
+
+// allowNullCollections 数组和 map 允许为 null：Go 中未初始化的切片和 map 序列化为 null。
+// 只放宽集合类型，其他字段的类型和必填项仍按 schema 校验（见 TestToolOutputSchemas）
+func allowNullCollections(s *jsonschema.Schema) {
+	if s == nil {
+		return
+	}
+	if s.Type == "array" || (s.Type == "object" && s.Properties == nil && s.AdditionalProperties != nil) {
+		s.Types = []string{"null", s.Type}
+		s.Type = ""
+	}
+	for _, p := range s.Properties {
+		allowNullCollections(p)
+	}
+	allowNullCollections(s.Items)
+	allowNullCollections(s.AdditionalProperties)
+}
+
+// convertToMCPResult 将自定义的 MCPToolResult 转换为官方 SDK 的格式。
+// 结构化结果作为工具的输出返回，由 SDK 按工具的 OutputSchema 校验后写入 StructuredContent，
+// 与 schema 不一致时调用失败而不是返回不符合声明的结果
+func convertToMCPResult(result *MCPToolResult) (*mcp.CallToolResult, any, error) {
 	var contents []mcp.Content
 	for _, c := range result.Content {
 		switch c.Type {
@@ -337,10 +698,14 @@ func convertToMCPResult(result *MCPToolResult) *mcp.CallToolResult {
 		}
 	}
 
-	return &mcp.CallToolResult{
+	res := &mcp.CallToolResult{
 		Content: contents,
 		IsError: result.IsError,
 	}
+	if result.IsError || result.Structured == nil {
+		return res, nil, nil
+	}
+	return res, result.Structured, nil
 }
 
 // convertStringsToInterfaces 辅助函数：将 []string 转换为 []interface{}
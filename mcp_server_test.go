@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/contentlint"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/mdformat"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/posttemplate"
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
+)
+
+// connectSession 通过内存连接创建客户端会话，测试结束时关闭
+func connectSession(t *testing.T, s *AppServer) *mcp.ClientSession {
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err := s.mcpServer.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "test"}, nil).Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+// listTools 通过内存连接获取服务注册的全部工具
+func listTools(t *testing.T, s *AppServer) []*mcp.Tool {
+	result, err := connectSession(t, s).ListTools(context.Background(), nil)
+	require.NoError(t, err)
+	return result.Tools
+}
+
+// validateSchema 按 schema 校验 JSON 值，与 SDK 校验工具参数和结果的方式一致
+func validateSchema(t *testing.T, schema any, value any) error {
+	data, err := json.Marshal(schema)
+	require.NoError(t, err)
+	var s jsonschema.Schema
+	require.NoError(t, json.Unmarshal(data, &s))
+	resolved, err := s.Resolve(nil)
+	require.NoError(t, err)
+
+	data, err = json.Marshal(value)
+	require.NoError(t, err)
+	var v map[string]any
+	require.NoError(t, json.Unmarshal(data, &v))
+	return resolved.Validate(&v)
+}
+
+// validateOutput 按工具的输出 schema 校验结构化结果，与 SDK 返回结果前的校验一致
+func validateOutput(t *testing.T, tool *mcp.Tool, out any) error {
+	return validateSchema(t, tool.OutputSchema, out)
+}
+
+func TestToolOutputSchemas(t *testing.T) {
+	publish := &PublishResponse{
+		Title:    "标题",
+		Content:  "正文",
+		Images:   2,
+		Status:   "发布完成",
+		Warnings: []contentlint.Finding{truncatedWarning()},
+	}
+	dryRun := &PublishResponse{
+		Title:   "标题",
+		Status:  statusDryRun,
+		Preview: &PublishPreview{Filled: &xiaohongshu.PublishPreview{Title: "标题", Tags: []string{"美食"}}, Screenshot: "iVBORw0KGgo="},
+	}
+	video := &PublishVideoResponse{Title: "标题", Status: "发布完成"}
+
+	// 每个工具的结构化结果，包括零值（未初始化的切片序列化为 null）和成功时的典型结果
+	samples := map[string][]*MCPToolResult{
+		"check_login_status": {
+			{Structured: &LoginStatusResponse{}},
+			{Structured: &LoginStatusResponse{IsLoggedIn: true, Username: "xiaohongshu-mcp"}},
+		},
+		"get_login_qrcode": {
+			{Structured: &LoginQrcodeResult{IsLoggedIn: true, Timeout: "0s"}},
+			{Structured: &LoginQrcodeResult{Timeout: "4m0s", Deadline: "2025-01-01 12:04:00"}},
+		},
+		"delete_cookies": {
+			{Structured: &DeleteCookiesResult{Deleted: true, CookiePath: "cookies.json"}},
+		},
+		"publish_content": {
+			structuredResult("发布成功", publish),
+			dryRunResult("图文", dryRun.Preview, nil, withoutScreenshot(dryRun)),
+		},
+		"publish_from_template": {
+			structuredResult("发布成功", publish),
+		},
+		"publish_with_video": {
+			structuredResult("发布成功", video),
+		},
+		"list_feeds": {
+			structuredResult("ok", &FeedsListResponse{}),
+			structuredResult("ok", &FeedsListResponse{Feeds: []xiaohongshu.Feed{{ID: "1"}}, Count: 1}),
+		},
+		"search_feeds": {
+			structuredResult("ok", &FeedsListResponse{Feeds: []xiaohongshu.Feed{{}}, Count: 1}),
+		},
+		"get_feed_detail": {
+			structuredResult("ok", &FeedDetailResponse{FeedID: "1", Data: &xiaohongshu.FeedDetailResponse{}}),
+		},
+		"user_profile": {
+			structuredResult("ok", &UserProfileResponse{}),
+			structuredResult("ok", &UserProfileResponse{Interactions: []xiaohongshu.UserInteractions{{}}, Feeds: []xiaohongshu.Feed{{}}}),
+		},
+		"post_comment_to_feed": {
+			structuredResult("ok", &PostCommentResponse{FeedID: "1", Success: true, Message: "评论发表成功"}),
+		},
+		"like_feed": {
+			structuredResult("ok", &ActionResult{FeedID: "1", Success: true, Message: "点赞成功"}),
+		},
+		"favorite_feed": {
+			structuredResult("ok", &ActionResult{FeedID: "1", Success: true, Message: "收藏成功"}),
+		},
+		"lint_content": {
+			structuredResult("ok", contentlint.New().Lint(contentlint.Input{Title: "", Content: "正文"})),
+		},
+		"format_content": {
+			structuredResult("ok", func() *mdformat.Result { r := mdformat.Format("# 标题\n正文 #美食"); return &r }()),
+		},
+		"render_text_cards": {
+			structuredResult("ok", &RenderTextCardsResponse{Paths: []string{"/tmp/card_01.png"}, Pages: 1, Font: "goregular"}),
+		},
+		"list_templates": {
+			structuredResult("ok", &ListTemplatesResult{}),
+			structuredResult("ok", &ListTemplatesResult{Templates: []*posttemplate.Template{{Name: "weekly", Title: "周报", Content: "正文"}}, Count: 1}),
+		},
+	}
+
+	tools := listTools(t, newTestAppServer(t))
+	for _, tool := range tools {
+		if tool.OutputSchema == nil {
+			continue
+		}
+		t.Run(tool.Name, func(t *testing.T) {
+			results, ok := samples[tool.Name]
+			require.True(t, ok, "工具 %s 声明了输出 schema，需要在测试中添加结构化结果", tool.Name)
+
+			for i, result := range results {
+				res, out, err := convertToMCPResult(result)
+				require.NoError(t, err)
+				require.False(t, res.IsError)
+				require.NotNil(t, out)
+				assert.NoError(t, validateOutput(t, tool, out), "第 %d 个结果", i+1)
+			}
+
+			// schema 不允许其他类型的结果
+			assert.Error(t, validateOutput(t, tool, map[string]any{"unexpected": true}))
+		})
+	}
+}
+
+func TestSearchFeedsFilterSchema(t *testing.T) {
+	var tool *mcp.Tool
+	for _, tl := range listTools(t, newTestAppServer(t)) {
+		if tl.Name == "search_feeds" {
+			tool = tl
+		}
+	}
+	require.NotNil(t, tool)
+
+	for field, values := range xiaohongshu.FilterValues() {
+		tests := []struct {
+			name    string
+			value   string
+			wantErr bool
+		}{
+			{name: "空字符串表示默认值", value: ""},
+			{name: "有效选项", value: values[len(values)-1]},
+			{name: "无效选项", value: "随便", wantErr: true},
+		}
+
+		for _, tt := range tests {
+			t.Run(field+"/"+tt.name, func(t *testing.T) {
+				args := map[string]any{"keyword": "咖啡", "filters": map[string]any{field: tt.value}}
+				err := validateSchema(t, tool.InputSchema, args)
+				if tt.wantErr {
+					assert.Error(t, err)
+					return
+				}
+				assert.NoError(t, err)
+			})
+		}
+	}
+}
+
+func TestCompetitorAnalysisCompletion(t *testing.T) {
+	session := connectSession(t, newTestAppServer(t))
+	ctx := context.Background()
+
+	prompts, err := session.ListPrompts(ctx, nil)
+	require.NoError(t, err)
+	args := map[string]bool{}
+	for _, p := range prompts.Prompts {
+		if p.Name == "competitor_analysis" {
+			for _, arg := range p.Arguments {
+				args[arg.Name] = true
+			}
+		}
+	}
+
+	// 每个可补全的筛选项都要声明为提示词参数，否则客户端不会请求补全
+	for field, values := range xiaohongshu.FilterValues() {
+		t.Run(field, func(t *testing.T) {
+			assert.True(t, args[field], "competitor_analysis 缺少参数 %s", field)
+
+			result, err := session.Complete(ctx, &mcp.CompleteParams{
+				Ref:      &mcp.CompleteReference{Type: "ref/prompt", Name: "competitor_analysis"},
+				Argument: mcp.CompleteParamsArgument{Name: field},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, values, result.Completion.Values)
+		})
+	}
+}
@@ -1,18 +1,45 @@
 package main
 
 import (
+	"context"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/modelcontextprotocol/go-sdk/auth"
 	"github.com/sirupsen/logrus"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/apikey"
 )
 
-// corsMiddleware CORS 中间件
-func corsMiddleware() gin.HandlerFunc {
+// corsMiddleware CORS 中间件，只允许 allowedOrigins 中的来源跨域访问，包含 * 时允许任意来源。
+// 启用 API Key 认证时忽略 *，只回显列出的来源，避免任意网页拿到 Key 后跨域调用接口
+func corsMiddleware(allowedOrigins []string, authEnabled bool) gin.HandlerFunc {
+	allowAll := false
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			allowAll = true
+			continue
+		}
+		allowed[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+	}
+	if allowAll && authEnabled {
+		logrus.Warn("已启用 API Key 认证，CORS 不允许任意来源，需要跨域访问时请通过 -cors-origins 指定来源")
+		allowAll = false
+	}
+
 	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
+		origin := c.GetHeader("Origin")
+		switch {
+		case allowAll:
+			c.Header("Access-Control-Allow-Origin", "*")
+		case origin != "" && allowed[strings.ToLower(origin)]:
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Vary", "Origin")
+		}
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
@@ -32,3 +59,65 @@ func errorHandlingMiddleware() gin.HandlerFunc {
 			"服务器内部错误", recovered)
 	})
 }
+
+// authMiddleware API Key 认证中间件，要求请求携带的 Key 拥有 scope 权限。
+// 未配置任何 Key 时不做认证
+func authMiddleware(authn *apikey.Authenticator, scope apikey.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !authn.Enabled() {
+			c.Next()
+			return
+		}
+
+		key, ok := authn.Lookup(apikey.TokenFromRequest(c.Request))
+		if !ok {
+			c.Header("WWW-Authenticate", "Bearer")
+			respondError(c, http.StatusUnauthorized, "UNAUTHORIZED",
+				"缺少或无效的 API Key", "请通过 Authorization: Bearer <key> 或 X-API-Key 请求头提供 API Key")
+			c.Abort()
+			return
+		}
+		if !key.Allows(scope) {
+			respondError(c, http.StatusForbidden, "FORBIDDEN",
+				"API Key 权限不足", "需要 "+string(scope)+" 权限")
+			c.Abort()
+			return
+		}
+
+		c.Set("api_key", key.Name)
+		c.Next()
+	}
+}
+
+// mcpAuthHandler MCP 端点认证，校验通过后 Key 的权限随请求传给工具处理函数（CallToolRequest.Extra.TokenInfo）。
+// 同时支持 X-API-Key 请求头
+func mcpAuthHandler(authn *apikey.Authenticator, next http.Handler) http.Handler {
+	verifier := func(_ context.Context, token string, _ *http.Request) (*auth.TokenInfo, error) {
+		key, ok := authn.Lookup(token)
+		if !ok {
+			return nil, auth.ErrInvalidToken
+		}
+
+		scopes := make([]string, 0, len(key.Scopes))
+		for _, s := range key.EffectiveScopes() {
+			scopes = append(scopes, string(s))
+		}
+		return &auth.TokenInfo{
+			Scopes: scopes,
+			// 静态 Key 不过期，SDK 要求设置过期时间
+			Expiration: time.Now().Add(time.Hour),
+			Extra:      map[string]any{"name": key.Name},
+		}, nil
+	}
+
+	bearer := auth.RequireBearerToken(verifier, nil)(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			if key := r.Header.Get("X-API-Key"); key != "" {
+				r = r.Clone(r.Context())
+				r.Header.Set("Authorization", "Bearer "+key)
+			}
+		}
+		bearer.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,174 @@
+// Package apikey 实现基于静态 API Key / Bearer Token 的认证和按权限范围的授权。
+package apikey
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Scope 权限范围。admin 包含 publish，publish 包含 read
+type Scope string
+
+const (
+	ScopeRead    Scope = "read"    // 浏览、搜索、查看详情、内容检查等只读操作
+	ScopePublish Scope = "publish" // 发布、评论、点赞、收藏等以账号身份执行的操作
+	ScopeAdmin   Scope = "admin"   // 登录、删除 cookies 等账号管理操作
+)
+
+// MinKeyLength Key 的最小长度
+const MinKeyLength = 16
+
+// implied 每个权限范围包含的其他范围
+var implied = map[Scope][]Scope{
+	ScopeRead:    {ScopeRead},
+	ScopePublish: {ScopePublish, ScopeRead},
+	ScopeAdmin:   {ScopeAdmin, ScopePublish, ScopeRead},
+}
+
+// Key 一个 API Key
+type Key struct {
+	Name   string  `json:"name"`
+	Key    string  `json:"key"`
+	Scopes []Scope `json:"scopes"`
+}
+
+// Allows 判断 Key 是否拥有指定权限
+func (k *Key) Allows(scope Scope) bool {
+	return slices.Contains(k.EffectiveScopes(), scope)
+}
+
+// EffectiveScopes 返回展开包含关系后的全部权限
+func (k *Key) EffectiveScopes() []Scope {
+	var scopes []Scope
+	for _, s := range k.Scopes {
+		for _, i := range implied[s] {
+			if !slices.Contains(scopes, i) {
+				scopes = append(scopes, i)
+			}
+		}
+	}
+	return scopes
+}
+
+// Authenticator 校验请求携带的 Key。没有配置任何 Key 时不启用认证
+type Authenticator struct {
+	keys   []Key
+	hashes [][sha256.Size]byte
+}
+
+// New 创建认证器，Key 不能为空、过短或重复，权限范围必须合法
+func New(keys []Key) (*Authenticator, error) {
+	a := &Authenticator{}
+	for i, k := range keys {
+		if k.Name == "" {
+			k.Name = fmt.Sprintf("key-%d", i+1)
+		}
+		if len(k.Key) < MinKeyLength {
+			return nil, fmt.Errorf("API Key %s 长度不能少于 %d", k.Name, MinKeyLength)
+		}
+		if len(k.Scopes) == 0 {
+			return nil, fmt.Errorf("API Key %s 未设置权限范围", k.Name)
+		}
+		for _, s := range k.Scopes {
+			if _, ok := implied[s]; !ok {
+				return nil, fmt.Errorf("API Key %s 的权限范围 %q 不合法，可选 read / publish / admin", k.Name, s)
+			}
+		}
+
+		hash := sha256.Sum256([]byte(k.Key))
+		if slices.Contains(a.hashes, hash) {
+			return nil, fmt.Errorf("API Key %s 与其他 Key 重复", k.Name)
+		}
+		a.keys = append(a.keys, k)
+		a.hashes = append(a.hashes, hash)
+	}
+	return a, nil
+}
+
+// Enabled 是否启用认证
+func (a *Authenticator) Enabled() bool {
+	return a != nil && len(a.keys) > 0
+}
+
+// Lookup 查找 token 对应的 Key，比较时间与 token 内容无关
+func (a *Authenticator) Lookup(token string) (*Key, bool) {
+	if !a.Enabled() || token == "" {
+		return nil, false
+	}
+
+	hash := sha256.Sum256([]byte(token))
+	found := -1
+	for i, h := range a.hashes {
+		if subtle.ConstantTimeCompare(h[:], hash[:]) == 1 {
+			found = i
+		}
+	}
+	if found < 0 {
+		return nil, false
+	}
+	return &a.keys[found], true
+}
+
+// TokenFromRequest 从 Authorization: Bearer 或 X-API-Key 请求头中读取 token
+func TokenFromRequest(r *http.Request) string {
+	if fields := strings.Fields(r.Header.Get("Authorization")); len(fields) == 2 && strings.EqualFold(fields[0], "bearer") {
+		return fields[1]
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+// LoadFile 从 JSON 文件加载 Key：{"keys": [{"name": "...", "key": "...", "scopes": ["read"]}]}
+func LoadFile(path string) ([]Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "读取 API Key 配置失败")
+	}
+
+	var cfg struct {
+		Keys []Key `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, errors.Wrap(err, "解析 API Key 配置失败")
+	}
+	return cfg.Keys, nil
+}
+
+// ParseKeys 解析逗号分隔的 name:key:scopes 列表，多个权限范围用 | 分隔，
+// 如 "ops:xxxxxxxx:admin,bot:yyyyyyyy:read|publish"
+func ParseKeys(s string) ([]Key, error) {
+	var keys []Key
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("API Key 格式应为 name:key:scopes，实际为 %q", maskEntry(entry))
+		}
+
+		key := Key{Name: strings.TrimSpace(parts[0]), Key: strings.TrimSpace(parts[1])}
+		for _, scope := range strings.Split(parts[2], "|") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				key.Scopes = append(key.Scopes, Scope(scope))
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// maskEntry 错误信息中只保留名称，避免泄露 Key
+func maskEntry(entry string) string {
+	name, _, _ := strings.Cut(entry, ":")
+	return name + ":***"
+}
@@ -0,0 +1,101 @@
+package apikey
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	readKey  = "read-key-0123456789"
+	adminKey = "admin-key-0123456789"
+)
+
+func TestLookupAndScopes(t *testing.T) {
+	a, err := New([]Key{
+		{Name: "bot", Key: readKey, Scopes: []Scope{ScopeRead}},
+		{Name: "ops", Key: adminKey, Scopes: []Scope{ScopeAdmin}},
+	})
+	require.NoError(t, err)
+	assert.True(t, a.Enabled())
+
+	k, ok := a.Lookup(readKey)
+	require.True(t, ok)
+	assert.Equal(t, "bot", k.Name)
+	assert.True(t, k.Allows(ScopeRead))
+	assert.False(t, k.Allows(ScopePublish))
+
+	k, ok = a.Lookup(adminKey)
+	require.True(t, ok)
+	assert.Equal(t, []Scope{ScopeAdmin, ScopePublish, ScopeRead}, k.EffectiveScopes())
+
+	_, ok = a.Lookup("unknown-key-0123456789")
+	assert.False(t, ok)
+	_, ok = a.Lookup("")
+	assert.False(t, ok)
+}
+
+func TestNewValidation(t *testing.T) {
+	_, err := New([]Key{{Name: "short", Key: "abc", Scopes: []Scope{ScopeRead}}})
+	assert.ErrorContains(t, err, "长度")
+
+	_, err = New([]Key{{Name: "none", Key: readKey}})
+	assert.ErrorContains(t, err, "权限范围")
+
+	_, err = New([]Key{{Name: "bad", Key: readKey, Scopes: []Scope{"write"}}})
+	assert.ErrorContains(t, err, "write")
+
+	_, err = New([]Key{
+		{Name: "a", Key: readKey, Scopes: []Scope{ScopeRead}},
+		{Name: "b", Key: readKey, Scopes: []Scope{ScopeAdmin}},
+	})
+	assert.ErrorContains(t, err, "重复")
+
+	a, err := New(nil)
+	require.NoError(t, err)
+	assert.False(t, a.Enabled())
+	_, ok := a.Lookup(readKey)
+	assert.False(t, ok)
+}
+
+func TestTokenFromRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	assert.Empty(t, TokenFromRequest(r))
+
+	r.Header.Set("X-API-Key", readKey)
+	assert.Equal(t, readKey, TokenFromRequest(r))
+
+	r.Header.Set("Authorization", "bearer "+adminKey)
+	assert.Equal(t, adminKey, TokenFromRequest(r))
+
+	// 其他认证方式不当作 token
+	r.Header.Del("X-API-Key")
+	r.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	assert.Empty(t, TokenFromRequest(r))
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys(" ops:" + adminKey + ":admin, bot:" + readKey + ":read|publish ,")
+	require.NoError(t, err)
+	assert.Equal(t, []Key{
+		{Name: "ops", Key: adminKey, Scopes: []Scope{ScopeAdmin}},
+		{Name: "bot", Key: readKey, Scopes: []Scope{ScopeRead, ScopePublish}},
+	}, keys)
+
+	_, err = ParseKeys("ops:" + adminKey)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), adminKey)
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"keys": [{"name": "bot", "key": "`+readKey+`", "scopes": ["read"]}]}`), 0600))
+
+	keys, err := LoadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []Key{{Name: "bot", Key: readKey, Scopes: []Scope{ScopeRead}}}, keys)
+}
@@ -0,0 +1,242 @@
+// Package approval 保存等待人工审批的操作。MCP 客户端不支持 elicitation 时，
+// 需要确认的工具调用先进入审批队列，审批人批准后由服务端执行，拒绝或超时则不执行。
+package approval
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Status 审批状态
+type Status string
+
+const (
+	StatusPending  Status = "pending"  // 等待审批
+	StatusRunning  Status = "running"  // 已批准，执行中
+	StatusDone     Status = "done"     // 执行成功
+	StatusFailed   Status = "failed"   // 执行失败
+	StatusRejected Status = "rejected" // 已拒绝，未执行
+	StatusExpired  Status = "expired"  // 超时未审批，未执行
+)
+
+const (
+	DefaultTTL   = 24 * time.Hour // 默认审批有效期
+	DefaultLimit = 100            // 保留的已结束审批数量
+)
+
+var (
+	// ErrNotFound 审批请求不存在
+	ErrNotFound = errors.New("审批请求不存在")
+	// ErrDecided 审批请求已处理或已过期
+	ErrDecided = errors.New("审批请求已处理")
+)
+
+// RunFunc 批准后执行的操作，返回的结果保存在审批记录中
+type RunFunc func(ctx context.Context) (any, error)
+
+// Request 审批记录
+type Request struct {
+	ID         string     `json:"id"`
+	Tool       string     `json:"tool"`
+	Summary    string     `json:"summary"` // 展示给审批人的最终内容
+	Status     Status     `json:"status"`
+	Reason     string     `json:"reason,omitempty"` // 拒绝原因
+	Result     any        `json:"result,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	DecidedAt  *time.Time `json:"decided_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Queue 审批队列，只保存在内存中
+type Queue struct {
+	mu    sync.Mutex
+	items map[string]*item
+	order []string
+	ttl   time.Duration
+	limit int
+
+	now func() time.Time
+}
+
+type item struct {
+	info Request
+	run  RunFunc
+}
+
+// New 创建审批队列，ttl 小于等于 0 时使用 DefaultTTL
+func New(ttl time.Duration) *Queue {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Queue{
+		items: make(map[string]*item),
+		ttl:   ttl,
+		limit: DefaultLimit,
+		now:   time.Now,
+	}
+}
+
+// Submit 提交等待审批的操作
+func (q *Queue) Submit(tool, summary string, run RunFunc) *Request {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	it := &item{
+		info: Request{
+			ID:        newID(),
+			Tool:      tool,
+			Summary:   summary,
+			Status:    StatusPending,
+			CreatedAt: now,
+			ExpiresAt: now.Add(q.ttl),
+		},
+		run: run,
+	}
+	q.items[it.info.ID] = it
+	q.order = append(q.order, it.info.ID)
+	q.trim()
+
+	logrus.Infof("操作 %s 等待人工审批: id=%s", tool, it.info.ID)
+	info := it.info
+	return &info
+}
+
+// Get 获取审批记录
+func (q *Queue) Get(id string) (*Request, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	it, ok := q.items[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	q.expire(it)
+	info := it.info
+	return &info, nil
+}
+
+// List 按提交时间列出所有审批记录
+func (q *Queue) List() []*Request {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	list := make([]*Request, 0, len(q.order))
+	for _, id := range q.order {
+		it := q.items[id]
+		q.expire(it)
+		info := it.info
+		list = append(list, &info)
+	}
+	return list
+}
+
+// Approve 批准并在后台执行操作，返回执行中的记录
+func (q *Queue) Approve(id string) (*Request, error) {
+	it, err := q.decide(id, StatusRunning, "")
+	if err != nil {
+		return nil, err
+	}
+
+	q.mu.Lock()
+	run := it.run
+	it.run = nil
+	info := it.info
+	q.mu.Unlock()
+
+	go q.execute(it, run)
+	return &info, nil
+}
+
+// Reject 拒绝操作，操作不会执行
+func (q *Queue) Reject(id, reason string) (*Request, error) {
+	it, err := q.decide(id, StatusRejected, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	q.mu.Lock()
+	it.run = nil
+	info := it.info
+	q.mu.Unlock()
+	return &info, nil
+}
+
+// decide 将等待审批的记录改为 status
+func (q *Queue) decide(id string, status Status, reason string) (*item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	it, ok := q.items[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	q.expire(it)
+	if it.info.Status != StatusPending {
+		return nil, ErrDecided
+	}
+
+	now := q.now()
+	it.info.Status = status
+	it.info.Reason = reason
+	it.info.DecidedAt = &now
+	logrus.Infof("审批 %s（%s）: %s", id, it.info.Tool, status)
+	return it, nil
+}
+
+func (q *Queue) execute(it *item, run RunFunc) {
+	result, err := run(context.Background())
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	it.info.FinishedAt = &now
+	if err != nil {
+		it.info.Status = StatusFailed
+		it.info.Error = err.Error()
+		logrus.Errorf("审批 %s（%s）执行失败: %v", it.info.ID, it.info.Tool, err)
+		return
+	}
+	it.info.Status = StatusDone
+	it.info.Result = result
+}
+
+// expire 将超过有效期的待审批记录标记为过期，调用方需持有锁
+func (q *Queue) expire(it *item) {
+	if it.info.Status == StatusPending && q.now().After(it.info.ExpiresAt) {
+		it.info.Status = StatusExpired
+		it.run = nil
+	}
+}
+
+// trim 超过数量上限时删除最早结束的记录，调用方需持有锁
+func (q *Queue) trim() {
+	for len(q.order) > q.limit {
+		idx := slices.IndexFunc(q.order, func(id string) bool {
+			it := q.items[id]
+			q.expire(it)
+			return it.info.Status != StatusPending && it.info.Status != StatusRunning
+		})
+		if idx < 0 {
+			return
+		}
+		delete(q.items, q.order[idx])
+		q.order = slices.Delete(q.order, idx, idx+1)
+	}
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,134 @@
+package approval
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestQueue(ttl time.Duration) (*Queue, *time.Time) {
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	q := New(ttl)
+	q.now = func() time.Time { return now }
+	return q, &now
+}
+
+func waitStatus(t *testing.T, q *Queue, id string, status Status) *Request {
+	var req *Request
+	require.Eventually(t, func() bool {
+		req, _ = q.Get(id)
+		return req.Status == status
+	}, time.Second, time.Millisecond)
+	return req
+}
+
+func TestDecide(t *testing.T) {
+	tests := []struct {
+		name       string
+		runErr     error
+		advance    time.Duration
+		reject     bool
+		wantErr    error // 审批操作返回的错误
+		wantStatus Status
+		wantRan    bool
+		wantResult any
+		wantError  string
+	}{
+		{
+			name:       "批准后执行",
+			wantStatus: StatusDone,
+			wantRan:    true,
+			wantResult: map[string]string{"post_id": "abc"},
+		},
+		{
+			name:       "执行失败",
+			runErr:     errors.New("文件被占用"),
+			wantStatus: StatusFailed,
+			wantRan:    true,
+			wantError:  "文件被占用",
+		},
+		{
+			name:       "拒绝后不执行",
+			reject:     true,
+			wantStatus: StatusRejected,
+		},
+		{
+			name:       "过期后不能批准",
+			advance:    2 * time.Hour,
+			wantErr:    ErrDecided,
+			wantStatus: StatusExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, now := newTestQueue(time.Hour)
+
+			var ran atomic.Int32
+			req := q.Submit("publish_content", "标题: 新品", func(ctx context.Context) (any, error) {
+				ran.Add(1)
+				if tt.runErr != nil {
+					return nil, tt.runErr
+				}
+				return map[string]string{"post_id": "abc"}, nil
+			})
+			assert.Equal(t, StatusPending, req.Status)
+			assert.Equal(t, now.Add(time.Hour), req.ExpiresAt)
+
+			*now = now.Add(tt.advance)
+			var err error
+			if tt.reject {
+				_, err = q.Reject(req.ID, "内容不合适")
+			} else {
+				_, err = q.Approve(req.ID)
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			got := waitStatus(t, q, req.ID, tt.wantStatus)
+			assert.Equal(t, tt.wantResult, got.Result)
+			assert.Equal(t, tt.wantError, got.Error)
+			if tt.wantRan {
+				assert.NotNil(t, got.FinishedAt)
+			}
+			if tt.reject {
+				assert.Equal(t, "内容不合适", got.Reason)
+			}
+
+			// 已处理的请求不能再次批准
+			_, err = q.Approve(req.ID)
+			assert.ErrorIs(t, err, ErrDecided)
+			assert.Equal(t, tt.wantRan, ran.Load() == 1)
+		})
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	q, _ := newTestQueue(time.Hour)
+	_, err := q.Get("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = q.Reject("missing", "")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestTrimKeepsPending(t *testing.T) {
+	q, _ := newTestQueue(time.Hour)
+	q.limit = 2
+
+	noop := func(ctx context.Context) (any, error) { return nil, nil }
+	first := q.Submit("publish_content", "1", noop)
+	second := q.Submit("publish_content", "2", noop)
+	_, err := q.Reject(second.ID, "")
+	require.NoError(t, err)
+	third := q.Submit("publish_content", "3", noop)
+
+	// 超过上限时删除已结束的记录，等待审批的保留
+	list := q.List()
+	require.Len(t, list, 2)
+	assert.Equal(t, first.ID, list[0].ID)
+	assert.Equal(t, third.ID, list[1].ID)
+}
@@ -0,0 +1,420 @@
+package bulkpublish
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// JobStatus 批量发布任务状态
+type JobStatus string
+
+const (
+	JobRunning   JobStatus = "running"
+	JobCompleted JobStatus = "completed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// RowStatus 单行的发布状态
+type RowStatus string
+
+const (
+	RowPending   RowStatus = "pending"   // 等待发布
+	RowRunning   RowStatus = "running"   // 发布中
+	RowInvalid   RowStatus = "invalid"   // 校验失败，未发布
+	RowSkipped   RowStatus = "skipped"   // 不属于本次发布的账号
+	RowPublished RowStatus = "published" // 发布成功
+	RowReplayed  RowStatus = "replayed"  // 幂等键已发布过，返回历史结果
+	RowDuplicate RowStatus = "duplicate" // 与近期发布的内容重复
+	RowDryRun    RowStatus = "dry_run"   // 已填写表单，未发布
+	RowFailed    RowStatus = "failed"    // 发布失败
+	RowCancelled RowStatus = "cancelled" // 任务取消或前一条失败后未发布
+)
+
+// DefaultJobLimit 保留的已结束任务数量
+const DefaultJobLimit = 20
+
+var (
+	// ErrJobNotFound 任务不存在
+	ErrJobNotFound = errors.New("批量发布任务不存在")
+	// ErrJobFinished 任务已结束
+	ErrJobFinished = errors.New("批量发布任务已结束")
+)
+
+// RowResult 单行的发布结果
+type RowResult struct {
+	Line       int        `json:"line"`
+	Title      string     `json:"title"`
+	Account    string     `json:"account,omitempty"`
+	ScheduleAt *time.Time `json:"schedule_at,omitempty"`
+	Status     RowStatus  `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	PostID     string     `json:"post_id,omitempty"`
+	Warnings   []string   `json:"warnings,omitempty"` // 发布前检查的提示，如标签超过上限已截断
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Job 批量发布任务的报告
+type Job struct {
+	ID         string            `json:"id"`
+	Status     JobStatus         `json:"status"`
+	DryRun     bool              `json:"dry_run"`
+	Spacing    string            `json:"spacing"`
+	Account    string            `json:"account,omitempty"`
+	Total      int               `json:"total"`
+	Summary    map[RowStatus]int `json:"summary"`
+	Rows       []RowResult       `json:"rows"`
+	NextAt     *time.Time        `json:"next_at,omitempty"` // 正在等待的下一条的发布时间
+	CreatedAt  time.Time         `json:"created_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+}
+
+// Outcome 单行发布的结果
+type Outcome struct {
+	Status   RowStatus // 为空时视为 RowPublished
+	PostID   string
+	Warnings []string // 发布前检查的提示
+}
+
+// ValidateFunc 发布前校验单行，返回错误时该行标记为 invalid
+type ValidateFunc func(row Row) error
+
+// PublishFunc 发布单行。返回错误且 Outcome.Status 为空时该行标记为 failed
+type PublishFunc func(ctx context.Context, row Row) (Outcome, error)
+
+// Options 批量发布选项
+type Options struct {
+	Spacing     time.Duration // 两次发布之间的最小间隔
+	Account     string        // 当前登录的账号。填写了账号的行必须与之一致，否则跳过，避免发到其他账号；未填写账号的行总是发布
+	DryRun      bool          // 只填写表单不发布，此时忽略计划时间和发布间隔
+	StopOnError bool          // 发布失败后取消剩余的行
+
+	Validate ValidateFunc
+	Publish  PublishFunc
+	OnUpdate func(jobID string) // 任务报告变化时回调（可选）
+}
+
+// Manager 管理批量发布任务，任务只保存在内存中
+type Manager struct {
+	mu    sync.Mutex
+	jobs  map[string]*job
+	order []string
+	limit int
+
+	now   func() time.Time
+	after func(time.Duration) <-chan time.Time
+}
+
+type job struct {
+	mu     sync.Mutex
+	info   Job
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewManager 创建任务管理器
+func NewManager() *Manager {
+	return &Manager{
+		jobs:  make(map[string]*job),
+		limit: DefaultJobLimit,
+		now:   time.Now,
+		after: time.After,
+	}
+}
+
+// Start 校验所有行并在后台开始发布，返回任务的初始报告。
+// 校验失败或账号不匹配的行立即出现在报告中，其余行按计划时间排序后依次发布。
+func (m *Manager) Start(rows []Row, opts Options) (*Job, error) {
+	if opts.Publish == nil {
+		return nil, errors.New("未设置发布函数")
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("清单为空")
+	}
+	if opts.Spacing < 0 {
+		return nil, errors.New("发布间隔不能为负数")
+	}
+
+	now := m.now()
+	j := &job{
+		info: Job{
+			ID:        newJobID(),
+			Status:    JobRunning,
+			DryRun:    opts.DryRun,
+			Spacing:   opts.Spacing.String(),
+			Account:   opts.Account,
+			Total:     len(rows),
+			Rows:      make([]RowResult, len(rows)),
+			CreatedAt: now,
+		},
+		done: make(chan struct{}),
+	}
+
+	var queue []int
+	for i, row := range rows {
+		result := RowResult{
+			Line:       row.Line,
+			Title:      row.Title,
+			Account:    row.Account,
+			ScheduleAt: row.ScheduleAt,
+			Status:     RowPending,
+		}
+
+		switch {
+		case row.Err != nil:
+			result.Status, result.Error = RowInvalid, row.Err.Error()
+		case row.Account != "" && opts.Account == "":
+			result.Status, result.Error = RowSkipped, "该行指定了账号 "+row.Account+"，请求未指定当前账号，无法确认是否为同一账号"
+		case row.Account != "" && row.Account != opts.Account:
+			result.Status, result.Error = RowSkipped, "账号不是 "+opts.Account
+		case opts.Validate != nil:
+			if err := opts.Validate(row); err != nil {
+				result.Status, result.Error = RowInvalid, err.Error()
+			}
+		}
+
+		if result.Status == RowPending {
+			queue = append(queue, i)
+		}
+		j.info.Rows[i] = result
+	}
+
+	// 按计划时间排序，未设置计划时间的行最先发布，其余保持清单顺序
+	slices.SortStableFunc(queue, func(a, b int) int {
+		return scheduleTime(rows[a]).Compare(scheduleTime(rows[b]))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j.cancel = cancel
+
+	m.add(j)
+	go m.run(ctx, j, rows, queue, opts)
+
+	return j.snapshot(), nil
+}
+
+// Get 获取任务报告
+func (m *Manager) Get(id string) (*Job, error) {
+	j, err := m.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return j.snapshot(), nil
+}
+
+// List 按创建时间列出所有任务
+func (m *Manager) List() []*Job {
+	m.mu.Lock()
+	jobs := make([]*job, 0, len(m.order))
+	for _, id := range m.order {
+		jobs = append(jobs, m.jobs[id])
+	}
+	m.mu.Unlock()
+
+	list := make([]*Job, 0, len(jobs))
+	for _, j := range jobs {
+		list = append(list, j.snapshot())
+	}
+	return list
+}
+
+// Cancel 取消任务，正在发布的行会收到 context 取消，未发布的行标记为 cancelled
+func (m *Manager) Cancel(id string) (*Job, error) {
+	j, err := m.get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	j.mu.Lock()
+	running := j.info.Status == JobRunning
+	j.mu.Unlock()
+	if !running {
+		return nil, ErrJobFinished
+	}
+
+	j.cancel()
+	<-j.done
+	return j.snapshot(), nil
+}
+
+func (m *Manager) get(id string) (*job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return j, nil
+}
+
+// add 保存任务，超过数量上限时删除最早结束的任务
+func (m *Manager) add(j *job) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.jobs[j.info.ID] = j
+	m.order = append(m.order, j.info.ID)
+
+	for len(m.order) > m.limit {
+		idx := slices.IndexFunc(m.order, func(id string) bool {
+			select {
+			case <-m.jobs[id].done:
+				return true
+			default:
+				return false
+			}
+		})
+		if idx < 0 {
+			return
+		}
+		delete(m.jobs, m.order[idx])
+		m.order = slices.Delete(m.order, idx, idx+1)
+	}
+}
+
+func (m *Manager) run(ctx context.Context, j *job, rows []Row, queue []int, opts Options) {
+	defer close(j.done)
+	defer j.cancel()
+
+	notify := func() {
+		if opts.OnUpdate != nil {
+			opts.OnUpdate(j.info.ID)
+		}
+	}
+	defer notify()
+
+	var last time.Time // 上一次实际操作发布页的结束时间
+	for qi, i := range queue {
+		row := rows[i]
+
+		if !opts.DryRun {
+			at := scheduleTime(row)
+			if !last.IsZero() && last.Add(opts.Spacing).After(at) {
+				at = last.Add(opts.Spacing)
+			}
+			if wait := at.Sub(m.now()); wait > 0 {
+				logrus.Infof("批量发布 %s: 第 %d 行将于 %s 发布", j.info.ID, row.Line, at.Format(time.DateTime))
+				j.setNext(&at)
+				notify()
+				select {
+				case <-ctx.Done():
+				case <-m.after(wait):
+				}
+				j.setNext(nil)
+			}
+		}
+
+		if ctx.Err() != nil {
+			j.finish(JobCancelled, queue[qi:], "任务已取消", m.now())
+			return
+		}
+
+		started := m.now()
+		j.update(i, func(r *RowResult) {
+			r.Status = RowRunning
+			r.StartedAt = &started
+		})
+		notify()
+
+		outcome, err := opts.Publish(ctx, row)
+		finished := m.now()
+
+		status := outcome.Status
+		if status == "" {
+			status = RowPublished
+			if err != nil {
+				status = RowFailed
+			}
+		}
+		if status == RowPublished || status == RowFailed {
+			last = finished
+		}
+
+		j.update(i, func(r *RowResult) {
+			r.Status = status
+			r.PostID = outcome.PostID
+			r.Warnings = outcome.Warnings
+			r.FinishedAt = &finished
+			if err != nil {
+				r.Error = err.Error()
+			}
+		})
+		logrus.Infof("批量发布 %s: 第 %d 行 %s", j.info.ID, row.Line, status)
+		notify()
+
+		if status == RowFailed && opts.StopOnError {
+			if ctx.Err() != nil {
+				j.finish(JobCancelled, queue[qi+1:], "任务已取消", finished)
+			} else {
+				j.finish(JobCompleted, queue[qi+1:], "前一条发布失败", finished)
+			}
+			return
+		}
+	}
+
+	status := JobCompleted
+	if ctx.Err() != nil {
+		status = JobCancelled
+	}
+	j.finish(status, nil, "", m.now())
+}
+
+func (j *job) update(i int, fn func(r *RowResult)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	fn(&j.info.Rows[i])
+}
+
+func (j *job) setNext(at *time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.info.NextAt = at
+}
+
+// finish 结束任务，remaining 中的行标记为 cancelled
+func (j *job) finish(status JobStatus, remaining []int, reason string, at time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, i := range remaining {
+		j.info.Rows[i].Status = RowCancelled
+		j.info.Rows[i].Error = reason
+	}
+	j.info.Status = status
+	j.info.NextAt = nil
+	j.info.FinishedAt = &at
+}
+
+func (j *job) snapshot() *Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	info := j.info
+	info.Rows = slices.Clone(j.info.Rows)
+	info.Summary = make(map[RowStatus]int)
+	for _, r := range info.Rows {
+		info.Summary[r.Status]++
+	}
+	return &info
+}
+
+func scheduleTime(row Row) time.Time {
+	if row.ScheduleAt == nil {
+		return time.Time{}
+	}
+	return *row.ScheduleAt
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,297 @@
+package bulkpublish
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock 等待时直接推进时间并记录等待时长
+type fakeClock struct {
+	mu    sync.Mutex
+	t     time.Time
+	waits []time.Duration
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) after(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+	c.waits = append(c.waits, d)
+	ch := make(chan time.Time, 1)
+	ch <- c.t
+	return ch
+}
+
+func newTestManager() (*Manager, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)}
+	m := NewManager()
+	m.now = clock.now
+	m.after = clock.after
+	return m, clock
+}
+
+func waitJob(t *testing.T, m *Manager, id string) *Job {
+	j, err := m.get(id)
+	require.NoError(t, err)
+	select {
+	case <-j.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("任务未结束")
+	}
+	job, err := m.Get(id)
+	require.NoError(t, err)
+	return job
+}
+
+func TestRunOrderSpacingAndReport(t *testing.T) {
+	m, clock := newTestManager()
+	later := clock.t.Add(2 * time.Hour)
+
+	rows := []Row{
+		{Line: 2, Title: "计划发布", ScheduleAt: &later},
+		{Line: 3, Title: "立即发布"},
+		{Line: 4, Title: "校验失败"},
+		{Line: 5, Title: "其他账号", Account: "other"},
+		{Line: 6, Title: "解析失败", Err: errors.New("bad time")},
+		{Line: 7, Title: "发布失败"},
+		{Line: 8, Title: "重复"},
+	}
+
+	var published []string
+	job, err := m.Start(rows, Options{
+		Spacing: 10 * time.Minute,
+		Account: "main",
+		Validate: func(row Row) error {
+			if row.Title == "校验失败" {
+				return errors.New("标题不合法")
+			}
+			return nil
+		},
+		Publish: func(_ context.Context, row Row) (Outcome, error) {
+			published = append(published, row.Title)
+			switch row.Title {
+			case "发布失败":
+				return Outcome{}, errors.New("网络错误")
+			case "重复":
+				return Outcome{Status: RowDuplicate}, errors.New("重复内容")
+			}
+			return Outcome{PostID: "id-" + row.Title}, nil
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, JobRunning, job.Status)
+	assert.Equal(t, RowInvalid, job.Rows[2].Status)
+	assert.Equal(t, RowSkipped, job.Rows[3].Status)
+	assert.Equal(t, RowInvalid, job.Rows[4].Status)
+
+	job = waitJob(t, m, job.ID)
+	assert.Equal(t, JobCompleted, job.Status)
+	assert.Equal(t, []string{"立即发布", "发布失败", "重复", "计划发布"}, published)
+
+	// 立即发布 -> 间隔 10 分钟 -> 发布失败 -> 重复内容未操作页面不占间隔 -> 计划时间
+	assert.Equal(t, []time.Duration{10 * time.Minute, 10 * time.Minute, 2*time.Hour - 20*time.Minute}, clock.waits)
+
+	assert.Equal(t, RowPublished, job.Rows[0].Status)
+	assert.Equal(t, "id-计划发布", job.Rows[0].PostID)
+	assert.Equal(t, RowFailed, job.Rows[5].Status)
+	assert.Equal(t, "网络错误", job.Rows[5].Error)
+	assert.Equal(t, RowDuplicate, job.Rows[6].Status)
+	assert.Equal(t, map[RowStatus]int{RowPublished: 2, RowInvalid: 2, RowSkipped: 1, RowFailed: 1, RowDuplicate: 1}, job.Summary)
+}
+
+func TestRunStopOnError(t *testing.T) {
+	tests := []struct {
+		name        string
+		stopOnError bool
+		want        []RowStatus
+	}{
+		{name: "失败后继续", want: []RowStatus{RowPublished, RowFailed, RowPublished}},
+		{name: "失败后停止", stopOnError: true, want: []RowStatus{RowPublished, RowFailed, RowCancelled}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestManager()
+			rows := []Row{{Line: 1, Title: "a"}, {Line: 2, Title: "b"}, {Line: 3, Title: "c"}}
+
+			job, err := m.Start(rows, Options{
+				StopOnError: tt.stopOnError,
+				Publish: func(_ context.Context, row Row) (Outcome, error) {
+					if row.Title == "b" {
+						return Outcome{}, errors.New("失败")
+					}
+					return Outcome{}, nil
+				},
+			})
+			require.NoError(t, err)
+
+			job = waitJob(t, m, job.ID)
+			assert.Equal(t, JobCompleted, job.Status)
+			var got []RowStatus
+			for _, row := range job.Rows {
+				got = append(got, row.Status)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAccountMismatchSkipped(t *testing.T) {
+	tests := []struct {
+		name    string
+		account string
+		want    map[string]RowStatus
+	}{
+		{
+			name:    "只发布当前账号和未填写账号的行",
+			account: "main",
+			want:    map[string]RowStatus{"": RowPublished, "main": RowPublished, "other": RowSkipped},
+		},
+		{
+			name: "未指定当前账号时跳过所有填写了账号的行",
+			want: map[string]RowStatus{"": RowPublished, "main": RowSkipped, "other": RowSkipped},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestManager()
+			rows := []Row{{Line: 1, Title: "a"}, {Line: 2, Title: "b", Account: "main"}, {Line: 3, Title: "c", Account: "other"}}
+
+			var published []string
+			job, err := m.Start(rows, Options{
+				Account: tt.account,
+				Publish: func(_ context.Context, row Row) (Outcome, error) {
+					published = append(published, row.Account)
+					return Outcome{}, nil
+				},
+			})
+			require.NoError(t, err)
+
+			job = waitJob(t, m, job.ID)
+			for _, row := range job.Rows {
+				assert.Equal(t, tt.want[row.Account], row.Status, "账号 %q", row.Account)
+				if row.Status == RowSkipped {
+					assert.NotEmpty(t, row.Error)
+				}
+			}
+			assert.NotContains(t, published, "other")
+		})
+	}
+}
+
+func TestDryRunIgnoresSchedule(t *testing.T) {
+	tests := []struct {
+		name        string
+		dryRun      bool
+		wantWaits   []time.Duration
+		wantSummary map[RowStatus]int
+		wantUpdates int // 每行开始和结束各一次，等待一次，任务结束一次
+	}{
+		{
+			name:        "按间隔和计划时间发布",
+			wantWaits:   []time.Duration{time.Hour},
+			wantSummary: map[RowStatus]int{RowPublished: 2},
+			wantUpdates: 6,
+		},
+		{
+			name:        "预览不等待",
+			dryRun:      true,
+			wantSummary: map[RowStatus]int{RowDryRun: 2},
+			wantUpdates: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, clock := newTestManager()
+			later := clock.t.Add(time.Hour)
+			rows := []Row{{Line: 1, Title: "a", ScheduleAt: &later}, {Line: 2, Title: "b"}}
+
+			var updates []string
+			job, err := m.Start(rows, Options{
+				DryRun:  tt.dryRun,
+				Spacing: time.Hour,
+				Publish: func(context.Context, Row) (Outcome, error) {
+					if tt.dryRun {
+						return Outcome{Status: RowDryRun}, nil
+					}
+					return Outcome{}, nil
+				},
+				OnUpdate: func(id string) { updates = append(updates, id) },
+			})
+			require.NoError(t, err)
+
+			job = waitJob(t, m, job.ID)
+			assert.Equal(t, tt.wantWaits, clock.waits)
+			assert.Equal(t, tt.wantSummary, job.Summary)
+			assert.Len(t, updates, tt.wantUpdates)
+			assert.Equal(t, job.ID, updates[0])
+		})
+	}
+}
+
+func TestCancel(t *testing.T) {
+	m := NewManager()
+	started := make(chan struct{})
+	rows := []Row{{Line: 1, Title: "a"}, {Line: 2, Title: "b"}}
+
+	job, err := m.Start(rows, Options{
+		Spacing: time.Hour,
+		Publish: func(ctx context.Context, row Row) (Outcome, error) {
+			close(started)
+			return Outcome{}, nil
+		},
+	})
+	require.NoError(t, err)
+
+	// 第一条发布后等待间隔时取消
+	<-started
+	require.Eventually(t, func() bool {
+		job, _ := m.Get(job.ID)
+		return job.NextAt != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	job, err = m.Cancel(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, JobCancelled, job.Status)
+	assert.Equal(t, RowPublished, job.Rows[0].Status)
+	assert.Equal(t, RowCancelled, job.Rows[1].Status)
+	assert.Nil(t, job.NextAt)
+
+	_, err = m.Cancel(job.ID)
+	assert.ErrorIs(t, err, ErrJobFinished)
+	_, err = m.Get("missing")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func TestJobLimit(t *testing.T) {
+	m, _ := newTestManager()
+	m.limit = 2
+
+	var ids []string
+	for range 3 {
+		job, err := m.Start([]Row{{Line: 1}}, Options{
+			Publish: func(context.Context, Row) (Outcome, error) { return Outcome{}, nil },
+		})
+		require.NoError(t, err)
+		waitJob(t, m, job.ID)
+		ids = append(ids, job.ID)
+	}
+
+	_, err := m.Get(ids[0])
+	assert.ErrorIs(t, err, ErrJobNotFound)
+	assert.Len(t, m.List(), 2)
+}
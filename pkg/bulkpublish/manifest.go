@@ -0,0 +1,289 @@
+// Package bulkpublish 解析批量发布清单（CSV / JSONL），并按计划时间和发布间隔逐条发布。
+package bulkpublish
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Format 清单格式
+type Format string
+
+const (
+	FormatCSV   Format = "csv"
+	FormatJSONL Format = "jsonl"
+)
+
+// Row 清单中的一条笔记
+type Row struct {
+	Line           int        `json:"line"` // 清单中的行号，从 1 开始（CSV 表头为第 1 行）
+	Title          string     `json:"title"`
+	Content        string     `json:"content"`
+	Images         []string   `json:"images"`
+	Tags           []string   `json:"tags,omitempty"`
+	ScheduleAt     *time.Time `json:"schedule_at,omitempty"` // 计划发布时间，为空表示尽快发布
+	Account        string     `json:"account,omitempty"`
+	IdempotencyKey string     `json:"idempotency_key,omitempty"`
+	Markdown       bool       `json:"markdown,omitempty"`
+
+	// Err 该行解析失败的原因，不影响其他行
+	Err error `json:"-"`
+}
+
+// columns CSV 表头及别名
+var columns = map[string]string{
+	"title":           "title",
+	"content":         "content",
+	"images":          "images",
+	"tags":            "tags",
+	"schedule_at":     "schedule_at",
+	"schedule":        "schedule_at",
+	"schedule_time":   "schedule_at",
+	"account":         "account",
+	"idempotency_key": "idempotency_key",
+	"markdown":        "markdown",
+}
+
+var requiredColumns = []string{"title", "content", "images"}
+
+// timeLayouts 计划时间支持的格式，不带时区时按本地时间解析
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+}
+
+// DetectFormat 根据文件名扩展名判断清单格式，无法判断时根据内容首个非空字符判断
+func DetectFormat(name string, data []byte) Format {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return FormatCSV
+	case ".jsonl", ".ndjson":
+		return FormatJSONL
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return FormatJSONL
+	}
+	return FormatCSV
+}
+
+// Parse 解析清单。
+//
+// 表头错误、CSV 格式错误等整体问题返回 error；单行字段错误记录在 Row.Err 中，
+// 调用方应在报告中标记该行而不是放弃整个清单。
+func Parse(r io.Reader, format Format) ([]Row, error) {
+	switch format {
+	case FormatCSV:
+		return parseCSV(r)
+	case FormatJSONL:
+		return parseJSONL(r)
+	default:
+		return nil, fmt.Errorf("不支持的清单格式: %s", format)
+	}
+}
+
+func parseCSV(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(bufio.NewReader(r))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, errors.New("清单为空")
+		}
+		return nil, errors.Wrap(err, "读取清单表头失败")
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		col, ok := columns[name]
+		if !ok {
+			return nil, fmt.Errorf("未知的列: %q", name)
+		}
+		if _, dup := index[col]; dup {
+			return nil, fmt.Errorf("重复的列: %q", name)
+		}
+		index[col] = i
+	}
+	for _, col := range requiredColumns {
+		if _, ok := index[col]; !ok {
+			return nil, fmt.Errorf("缺少必需的列: %s", col)
+		}
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "读取清单失败")
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		field := func(col string) string {
+			if i, ok := index[col]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		row := Row{
+			Line:           line,
+			Title:          field("title"),
+			Content:        field("content"),
+			Images:         splitList(field("images")),
+			Tags:           splitList(field("tags")),
+			Account:        field("account"),
+			IdempotencyKey: field("idempotency_key"),
+		}
+		row.ScheduleAt, row.Err = parseScheduleTime(field("schedule_at"))
+		if row.Err == nil {
+			row.Markdown, row.Err = parseBool(field("markdown"))
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("清单为空")
+	}
+
+	return rows, nil
+}
+
+// jsonRow JSONL 中的一行，images / tags 可以是数组，也可以是用 | 分隔的字符串
+type jsonRow struct {
+	Title          string          `json:"title"`
+	Content        string          `json:"content"`
+	Images         stringList      `json:"images"`
+	Tags           stringList      `json:"tags"`
+	ScheduleAt     string          `json:"schedule_at"`
+	Account        string          `json:"account"`
+	IdempotencyKey string          `json:"idempotency_key"`
+	Markdown       json.RawMessage `json:"markdown"`
+}
+
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*l = splitList(s)
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return errors.New("应为字符串数组或用 | 分隔的字符串")
+	}
+	*l = list
+	return nil
+}
+
+func parseJSONL(r io.Reader) ([]Row, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+
+	var rows []Row
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		row := Row{Line: line}
+		var jr jsonRow
+		dec := json.NewDecoder(strings.NewReader(text))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&jr); err != nil {
+			row.Err = errors.Wrap(err, "解析 JSON 失败")
+			rows = append(rows, row)
+			continue
+		}
+
+		row.Title = strings.TrimSpace(jr.Title)
+		row.Content = jr.Content
+		row.Images = jr.Images
+		row.Tags = jr.Tags
+		row.Account = strings.TrimSpace(jr.Account)
+		row.IdempotencyKey = strings.TrimSpace(jr.IdempotencyKey)
+		row.ScheduleAt, row.Err = parseScheduleTime(jr.ScheduleAt)
+		if row.Err == nil && len(jr.Markdown) > 0 {
+			if err := json.Unmarshal(jr.Markdown, &row.Markdown); err != nil {
+				row.Err = errors.New("markdown 应为 true 或 false")
+			}
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "读取清单失败")
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("清单为空")
+	}
+
+	return rows, nil
+}
+
+// splitList 拆分用 | 或换行分隔的列表，忽略空项
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.FieldsFunc(s, func(r rune) bool { return r == '|' || r == '\n' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func parseScheduleTime(s string) (*time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("无法解析计划时间 %q，支持 2006-01-02 15:04 或 RFC3339 格式", s)
+}
+
+func parseBool(s string) (bool, error) {
+	if s == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(strings.ToLower(s))
+	if err != nil {
+		return false, fmt.Errorf("markdown 列应为 true 或 false，实际为 %q", s)
+	}
+	return b, nil
+}
+
+func isBlankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
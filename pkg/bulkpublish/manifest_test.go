@@ -0,0 +1,84 @@
+package bulkpublish
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCSV(t *testing.T) {
+	manifest := "Title,content,images,tags,schedule,account,markdown\n" +
+		"周一穿搭,\"第一行\n第二行\",/data/1.jpg|/data/2.jpg,穿搭|通勤,2025-03-03 09:30,main,true\n" +
+		",,,,,,\n" +
+		"周二穿搭,正文,https://example.com/a.jpg,,明天,main,\n" +
+		"周三穿搭,正文,/data/3.jpg,,,,yes\n"
+
+	rows, err := Parse(strings.NewReader(manifest), FormatCSV)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	first := rows[0]
+	assert.Equal(t, 2, first.Line)
+	assert.Equal(t, "周一穿搭", first.Title)
+	assert.Equal(t, "第一行\n第二行", first.Content)
+	assert.Equal(t, []string{"/data/1.jpg", "/data/2.jpg"}, first.Images)
+	assert.Equal(t, []string{"穿搭", "通勤"}, first.Tags)
+	assert.Equal(t, "main", first.Account)
+	assert.True(t, first.Markdown)
+	require.NotNil(t, first.ScheduleAt)
+	assert.Equal(t, time.Date(2025, 3, 3, 9, 30, 0, 0, time.Local), *first.ScheduleAt)
+	assert.NoError(t, first.Err)
+
+	// 多行正文之后的行号按文件实际行计算，空行被跳过
+	assert.Equal(t, 5, rows[1].Line)
+	assert.ErrorContains(t, rows[1].Err, "计划时间")
+
+	assert.ErrorContains(t, rows[2].Err, "markdown")
+}
+
+func TestParseCSVHeaderErrors(t *testing.T) {
+	_, err := Parse(strings.NewReader("title,content\n"), FormatCSV)
+	assert.ErrorContains(t, err, "images")
+
+	_, err = Parse(strings.NewReader("title,content,images,titel\n"), FormatCSV)
+	assert.ErrorContains(t, err, "titel")
+
+	_, err = Parse(strings.NewReader("title,content,images\n"), FormatCSV)
+	assert.ErrorContains(t, err, "清单为空")
+}
+
+func TestParseJSONL(t *testing.T) {
+	manifest := `{"title": "周一", "content": "正文", "images": ["/data/1.jpg"], "tags": "a|b", "schedule_at": "2025-03-03T09:30:00+08:00"}
+
+# 注释行
+{"title": "周二", "content": "正文", "images": "/data/2.jpg", "markdown": true}
+{"title": "周三", "imgs": []}
+not json
+`
+	rows, err := Parse(strings.NewReader(manifest), FormatJSONL)
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+
+	assert.Equal(t, 1, rows[0].Line)
+	assert.Equal(t, []string{"a", "b"}, rows[0].Tags)
+	require.NotNil(t, rows[0].ScheduleAt)
+	assert.True(t, rows[0].ScheduleAt.Equal(time.Date(2025, 3, 3, 1, 30, 0, 0, time.UTC)))
+
+	assert.Equal(t, 4, rows[1].Line)
+	assert.Equal(t, []string{"/data/2.jpg"}, rows[1].Images)
+	assert.True(t, rows[1].Markdown)
+
+	assert.ErrorContains(t, rows[2].Err, "imgs")
+	assert.Equal(t, 6, rows[3].Line)
+	assert.Error(t, rows[3].Err)
+}
+
+func TestDetectFormat(t *testing.T) {
+	assert.Equal(t, FormatCSV, DetectFormat("posts.CSV", nil))
+	assert.Equal(t, FormatJSONL, DetectFormat("posts.jsonl", nil))
+	assert.Equal(t, FormatJSONL, DetectFormat("", []byte("\n  {\"title\": \"a\"}")))
+	assert.Equal(t, FormatCSV, DetectFormat("", []byte("title,content,images")))
+}
@@ -0,0 +1,376 @@
+// Package contentlint 发布前的内容检查：标题/正文长度、标签、特殊字符、外链/手机号和敏感词。
+package contentlint
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Severity 检查结果的严重程度
+type Severity string
+
+const (
+	// SeverityError 会阻止发布
+	SeverityError Severity = "error"
+	// SeverityWarning 仅提示，不阻止发布
+	SeverityWarning Severity = "warning"
+)
+
+// 规则名称
+const (
+	RuleTitleEmpty     = "title_empty"
+	RuleTitleTooLong   = "title_too_long"
+	RuleContentEmpty   = "content_empty"
+	RuleContentTooLong = "content_too_long"
+	RuleTagCount       = "tag_count"
+	RuleTagDuplicate   = "tag_duplicate"
+	RuleTagInvalid     = "tag_invalid"
+	RuleForbiddenChar  = "forbidden_char"
+	RuleExternalLink   = "external_link"
+	RulePhoneNumber    = "phone_number"
+	RuleSensitiveWord  = "sensitive_word"
+)
+
+// highlightContextLen 高亮时保留的上下文字数
+const highlightContextLen = 10
+
+// Limits 平台限制
+type Limits struct {
+	MaxTitleWidth   int // 标题最大宽度，中文/日文/韩文占2个单位，英文/数字占1个单位
+	MaxContentRunes int // 正文最大字数
+	MaxTags         int // 最多标签数量
+}
+
+// DefaultLimits 小红书当前的限制
+var DefaultLimits = Limits{
+	MaxTitleWidth:   40,
+	MaxContentRunes: 1000,
+	MaxTags:         10,
+}
+
+// Input 待检查的内容
+type Input struct {
+	Title   string   `json:"title"`
+	Content string   `json:"content"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+// Finding 单条检查结果
+type Finding struct {
+	Rule      string   `json:"rule"`
+	Severity  Severity `json:"severity"`
+	Field     string   `json:"field"` // title / content / tags
+	Message   string   `json:"message"`
+	Match     string   `json:"match,omitempty"`     // 命中的文本
+	Offset    int      `json:"offset,omitempty"`    // 命中位置（字符序号）
+	Highlight string   `json:"highlight,omitempty"` // 命中文本及上下文，命中部分用【】标出
+}
+
+// Result 检查结果汇总
+type Result struct {
+	Findings []Finding `json:"findings"`
+	Errors   int       `json:"errors"`
+	Warnings int       `json:"warnings"`
+}
+
+// OK 没有 error 级别的问题时返回 true
+func (r *Result) OK() bool {
+	return r.Errors == 0
+}
+
+func (r *Result) add(f Finding) {
+	r.Findings = append(r.Findings, f)
+	if f.Severity == SeverityError {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+}
+
+// LintError 检查不通过时返回的错误，包含全部检查结果
+type LintError struct {
+	Result *Result
+}
+
+func (e *LintError) Error() string {
+	var msgs []string
+	for _, f := range e.Result.Findings {
+		if f.Severity != SeverityError {
+			continue
+		}
+		if f.Highlight != "" {
+			msgs = append(msgs, f.Message+"："+f.Highlight)
+		} else {
+			msgs = append(msgs, f.Message)
+		}
+	}
+	return "内容检查未通过: " + strings.Join(msgs, "; ")
+}
+
+// Linter 内容检查器
+type Linter struct {
+	limits            Limits
+	sensitiveWords    []string
+	sensitivePatterns []*regexp.Regexp
+}
+
+// Option 检查器配置选项
+type Option func(*Linter)
+
+// WithLimits 设置平台限制
+func WithLimits(limits Limits) Option {
+	return func(l *Linter) {
+		l.limits = limits
+	}
+}
+
+// WithSensitiveWords 设置敏感词列表，匹配时忽略大小写
+func WithSensitiveWords(words []string) Option {
+	return func(l *Linter) {
+		for _, w := range words {
+			if w = strings.TrimSpace(w); w != "" {
+				l.sensitiveWords = append(l.sensitiveWords, w)
+				l.sensitivePatterns = append(l.sensitivePatterns, regexp.MustCompile("(?i)"+regexp.QuoteMeta(w)))
+			}
+		}
+	}
+}
+
+// New 创建内容检查器
+func New(opts ...Option) *Linter {
+	l := &Linter{limits: DefaultLimits}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+var (
+	linkPattern  = regexp.MustCompile(`(?i)(https?://|www\.)[^\s]+|[a-z0-9-]+\.(com|cn|net|org|cc|top|xyz|io)(/[^\s]*)?\b`)
+	phonePattern = regexp.MustCompile(`(^|[^0-9])(\+?86[- ]?)?(1[3-9][0-9](?:[- ]?[0-9]{4}){2})([^0-9]|$)`)
+)
+
+// Lint 检查内容，返回全部检查结果
+func (l *Linter) Lint(in Input) *Result {
+	r := &Result{Findings: []Finding{}}
+
+	l.lintTitle(r, in.Title)
+	l.lintContent(r, in.Content)
+	l.lintTags(r, in.Tags)
+
+	for _, field := range []struct{ name, text string }{{"title", in.Title}, {"content", in.Content}} {
+		lintForbiddenChars(r, field.name, field.text)
+		lintLinks(r, field.name, field.text)
+		lintPhones(r, field.name, field.text)
+		l.lintSensitiveWords(r, field.name, field.text)
+	}
+	for _, tag := range in.Tags {
+		l.lintSensitiveWords(r, "tags", tag)
+	}
+
+	return r
+}
+
+func (l *Linter) lintTitle(r *Result, title string) {
+	if strings.TrimSpace(title) == "" {
+		r.add(Finding{Rule: RuleTitleEmpty, Severity: SeverityError, Field: "title", Message: "标题不能为空"})
+		return
+	}
+
+	if width := runewidth.StringWidth(title); width > l.limits.MaxTitleWidth {
+		r.add(Finding{
+			Rule:     RuleTitleTooLong,
+			Severity: SeverityError,
+			Field:    "title",
+			Message:  fmt.Sprintf("标题长度 %d 超过限制 %d（中文占2个单位，英文/数字占1个单位）", width, l.limits.MaxTitleWidth),
+		})
+	}
+}
+
+func (l *Linter) lintContent(r *Result, content string) {
+	if strings.TrimSpace(content) == "" {
+		r.add(Finding{Rule: RuleContentEmpty, Severity: SeverityError, Field: "content", Message: "正文不能为空"})
+		return
+	}
+
+	if n := len([]rune(content)); n > l.limits.MaxContentRunes {
+		r.add(Finding{
+			Rule:     RuleContentTooLong,
+			Severity: SeverityError,
+			Field:    "content",
+			Message:  fmt.Sprintf("正文 %d 字超过限制 %d 字", n, l.limits.MaxContentRunes),
+		})
+	}
+}
+
+func (l *Linter) lintTags(r *Result, tags []string) {
+	if len(tags) > l.limits.MaxTags {
+		r.add(Finding{
+			Rule:     RuleTagCount,
+			Severity: SeverityError,
+			Field:    "tags",
+			Message:  fmt.Sprintf("标签数量 %d 超过 %d 个", len(tags), l.limits.MaxTags),
+		})
+	}
+
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		name := strings.TrimSpace(strings.TrimLeft(tag, "#"))
+		if name == "" {
+			r.add(Finding{Rule: RuleTagInvalid, Severity: SeverityWarning, Field: "tags", Message: "存在空标签", Match: tag})
+			continue
+		}
+		if strings.ContainsAny(name, "# \t\n") {
+			r.add(Finding{Rule: RuleTagInvalid, Severity: SeverityError, Field: "tags", Message: fmt.Sprintf("标签 %q 不能包含空格或 #", tag), Match: tag})
+		}
+
+		key := strings.ToLower(name)
+		if seen[key] {
+			r.add(Finding{Rule: RuleTagDuplicate, Severity: SeverityWarning, Field: "tags", Message: fmt.Sprintf("标签 %q 重复", tag), Match: tag})
+		}
+		seen[key] = true
+	}
+}
+
+// isForbiddenRune 控制字符、零宽字符和乱码替换符会导致发布失败或显示异常
+func isForbiddenRune(c rune) bool {
+	switch c {
+	case '\n', '\r', '\t':
+		return false
+	case '\u200b', '\u200c', '\u200d', '\u2060', '\ufeff', '\ufffd':
+		return true
+	}
+	return unicode.IsControl(c)
+}
+
+// isEmojiJoiner 零宽连接符（U+200D）位于两个表情之间时用于组合表情（如 ❤️‍🔥、👨‍👩‍👧），是合法字符
+func isEmojiJoiner(runes []rune, i int) bool {
+	if runes[i] != '\u200d' || i == 0 || i == len(runes)-1 {
+		return false
+	}
+
+	// 前一个表情后面可能跟着变体选择符或肤色修饰符
+	prev := i - 1
+	for prev > 0 && isEmojiModifier(runes[prev]) {
+		prev--
+	}
+	return isEmoji(runes[prev]) && isEmoji(runes[i+1])
+}
+
+func isEmoji(c rune) bool {
+	return unicode.Is(unicode.So, c) || (c >= 0x1f000 && c <= 0x1faff)
+}
+
+// isEmojiModifier 变体选择符 U+FE0F 和肤色修饰符 U+1F3FB-U+1F3FF
+func isEmojiModifier(c rune) bool {
+	return c == '\ufe0f' || (c >= 0x1f3fb && c <= 0x1f3ff)
+}
+
+func lintForbiddenChars(r *Result, field, text string) {
+	runes := []rune(text)
+	for i, c := range runes {
+		if !isForbiddenRune(c) || isEmojiJoiner(runes, i) {
+			continue
+		}
+		r.add(Finding{
+			Rule:      RuleForbiddenChar,
+			Severity:  SeverityError,
+			Field:     field,
+			Message:   fmt.Sprintf("包含不可见或非法字符 %U", c),
+			Match:     fmt.Sprintf("%U", c),
+			Offset:    i,
+			Highlight: highlight(runes, i, i+1),
+		})
+	}
+}
+
+func lintLinks(r *Result, field, text string) {
+	for _, loc := range linkPattern.FindAllStringIndex(text, -1) {
+		r.add(newMatchFinding(RuleExternalLink, SeverityWarning, field, text, loc[0], loc[1], "包含外部链接，可能导致笔记限流"))
+	}
+}
+
+func lintPhones(r *Result, field, text string) {
+	for _, loc := range phonePattern.FindAllStringSubmatchIndex(text, -1) {
+		// 第3组为手机号本身，不包含前后的分隔字符
+		start, end := loc[4], loc[7]
+		if loc[4] < 0 {
+			start = loc[6]
+		}
+		r.add(newMatchFinding(RulePhoneNumber, SeverityWarning, field, text, start, end, "包含手机号，可能导致笔记限流"))
+	}
+}
+
+func (l *Linter) lintSensitiveWords(r *Result, field, text string) {
+	if text == "" {
+		return
+	}
+
+	for i, pattern := range l.sensitivePatterns {
+		for _, loc := range pattern.FindAllStringIndex(text, -1) {
+			r.add(newMatchFinding(RuleSensitiveWord, SeverityError, field, text, loc[0], loc[1], fmt.Sprintf("包含敏感词 %q", l.sensitiveWords[i])))
+		}
+	}
+}
+
+// newMatchFinding 根据字节区间生成带高亮的检查结果
+func newMatchFinding(rule string, severity Severity, field, text string, start, end int, message string) Finding {
+	runeStart := len([]rune(text[:start]))
+	runeEnd := runeStart + len([]rune(text[start:end]))
+
+	return Finding{
+		Rule:      rule,
+		Severity:  severity,
+		Field:     field,
+		Message:   message,
+		Match:     text[start:end],
+		Offset:    runeStart,
+		Highlight: highlight([]rune(text), runeStart, runeEnd),
+	}
+}
+
+// highlight 返回命中位置附近的文本，命中部分用【】标出
+func highlight(runes []rune, start, end int) string {
+	from := max(0, start-highlightContextLen)
+	to := min(len(runes), end+highlightContextLen)
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	b.WriteString(string(runes[from:start]))
+	b.WriteString("【")
+	b.WriteString(string(runes[start:end]))
+	b.WriteString("】")
+	b.WriteString(string(runes[end:to]))
+	if to < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// LoadWordList 从文件加载敏感词，每行一个，# 开头的行为注释
+func LoadWordList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	return words, scanner.Err()
+}
@@ -0,0 +1,150 @@
+package contentlint
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func findRule(r *Result, rule string) []Finding {
+	var out []Finding
+	for _, f := range r.Findings {
+		if f.Rule == rule {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func TestLintClean(t *testing.T) {
+	r := New().Lint(Input{Title: "周末去哪儿", Content: "分享一家好吃的小店", Tags: []string{"美食", "探店"}})
+
+	assert.True(t, r.OK())
+	assert.Empty(t, r.Findings)
+}
+
+func TestLintLength(t *testing.T) {
+	r := New().Lint(Input{
+		Title:   strings.Repeat("标", 21),
+		Content: strings.Repeat("字", 1001),
+	})
+
+	assert.False(t, r.OK())
+	assert.Len(t, findRule(r, RuleTitleTooLong), 1)
+	assert.Len(t, findRule(r, RuleContentTooLong), 1)
+
+	r = New().Lint(Input{Title: " ", Content: ""})
+	assert.Len(t, findRule(r, RuleTitleEmpty), 1)
+	assert.Len(t, findRule(r, RuleContentEmpty), 1)
+
+	// 英文占1个单位
+	r = New().Lint(Input{Title: strings.Repeat("a", 40), Content: "正文"})
+	assert.True(t, r.OK())
+}
+
+func TestLintTags(t *testing.T) {
+	tags := []string{"美食", "#美食", "a b", ""}
+	for i := 0; i < 8; i++ {
+		tags = append(tags, "标签"+string(rune('A'+i)))
+	}
+
+	r := New().Lint(Input{Title: "标题", Content: "正文", Tags: tags})
+
+	assert.Len(t, findRule(r, RuleTagCount), 1)
+	assert.Len(t, findRule(r, RuleTagDuplicate), 1)
+	assert.Len(t, findRule(r, RuleTagInvalid), 2)
+	assert.Equal(t, 2, r.Errors)
+}
+
+func TestLintForbiddenChars(t *testing.T) {
+	r := New().Lint(Input{Title: "标题​", Content: "第一行\n第二行\x07"})
+
+	found := findRule(r, RuleForbiddenChar)
+	require.Len(t, found, 2)
+	assert.Equal(t, "title", found[0].Field)
+	assert.Equal(t, 2, found[0].Offset)
+	assert.Equal(t, "content", found[1].Field)
+}
+
+func TestLintZeroWidthJoiner(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		forbidden int
+	}{
+		{"heart on fire", "今天也要 ❤️\u200d🔥", 0},
+		{"family", "周末 👨\u200d👩\u200d👧 出游", 0},
+		{"skin tone", "👩🏻\u200d💻 加班", 0},
+		{"between text", "文字\u200d文字", 1},
+		{"trailing", "表情 🔥\u200d", 1},
+	}
+
+	for _, test := range tests {
+		r := New().Lint(Input{Title: "标题", Content: test.content})
+		assert.Len(t, findRule(r, RuleForbiddenChar), test.forbidden, test.name)
+	}
+}
+
+func TestLintLinksAndPhones(t *testing.T) {
+	r := New().Lint(Input{
+		Title:   "标题",
+		Content: "详情见 https://example.com/a 或 shop.taobao.com，电话 13812345678，座机 0101234567890",
+	})
+
+	assert.True(t, r.OK())
+
+	links := findRule(r, RuleExternalLink)
+	require.Len(t, links, 2)
+	assert.Equal(t, "https://example.com/a", links[0].Match)
+
+	phones := findRule(r, RulePhoneNumber)
+	require.Len(t, phones, 1)
+	assert.Equal(t, "13812345678", phones[0].Match)
+	assert.Contains(t, phones[0].Highlight, "【13812345678】")
+
+	r = New().Lint(Input{Title: "标题", Content: "加我 +86 138-1234-5678"})
+	phones = findRule(r, RulePhoneNumber)
+	require.Len(t, phones, 1)
+	assert.Equal(t, "+86 138-1234-5678", phones[0].Match)
+}
+
+func TestLintSensitiveWords(t *testing.T) {
+	linter := New(WithSensitiveWords([]string{"最好", "VX", " "}))
+	r := linter.Lint(Input{Title: "全网最好用", Content: "有需要加vx，真的最好", Tags: []string{"最好物"}})
+
+	found := findRule(r, RuleSensitiveWord)
+	require.Len(t, found, 4)
+	assert.False(t, r.OK())
+
+	assert.Equal(t, "title", found[0].Field)
+	assert.Equal(t, "全网【最好】用", found[0].Highlight)
+	assert.Equal(t, "vx", found[2].Match)
+	assert.Equal(t, "tags", found[3].Field)
+}
+
+func TestHighlightTruncates(t *testing.T) {
+	runes := []rune(strings.Repeat("a", 30) + "X" + strings.Repeat("b", 30))
+	h := highlight(runes, 30, 31)
+
+	assert.Equal(t, "…aaaaaaaaaa【X】bbbbbbbbbb…", h)
+}
+
+func TestLoadWordList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# 注释\n最好\n\n 第一 \n"), 0644))
+
+	words, err := LoadWordList(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"最好", "第一"}, words)
+}
+
+func TestLintError(t *testing.T) {
+	r := New().Lint(Input{Title: "", Content: "正文"})
+	err := &LintError{Result: r}
+
+	assert.Contains(t, err.Error(), "标题不能为空")
+}
@@ -0,0 +1,120 @@
+package downloader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CachePolicy 本地下载缓存的清理策略
+type CachePolicy struct {
+	TTL      time.Duration // 超过该时间未更新的文件会被删除，<= 0 表示不按时间清理
+	MaxBytes int64         // 目录总大小上限，超过时从最旧的文件开始删除，<= 0 表示不限制
+}
+
+// DefaultCachePolicy 默认保留 7 天，最多占用 1GB
+var DefaultCachePolicy = CachePolicy{
+	TTL:      defaultCacheTTL,
+	MaxBytes: 1 << 30,
+}
+
+// cacheEntryPattern 可清理的文件名：URL 下载缓存（见 ImageDownloader.generateFileName 和
+// VideoDownloader.generateFileName，视频包括未下载完的 .part/.partinfo）、
+// base64 图片（见 SaveBase64Image）和预处理结果（见 imageproc.Processor）。
+// 这些文件都按内容或 URL 命名，删除后下次使用时会重新生成；下载中的临时文件不会被清理
+var cacheEntryPattern = regexp.MustCompile(`^(img|video|b64|proc)_[0-9a-f]{16}\.[a-z0-9]+$`)
+
+// CleanupCache 按策略清理 dir 下的下载缓存和生成的图片，返回删除的文件数
+func CleanupCache(dir string, policy CachePolicy) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	type cacheFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	var files []cacheFile
+	var total int64
+	removed := 0
+	now := time.Now()
+
+	for _, entry := range entries {
+		if entry.IsDir() || !cacheEntryPattern.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		if policy.TTL > 0 && now.Sub(info.ModTime()) > policy.TTL {
+			if err := os.Remove(path); err == nil {
+				removed++
+			}
+			continue
+		}
+
+		files = append(files, cacheFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+	}
+
+	if policy.MaxBytes > 0 && total > policy.MaxBytes {
+		sort.Slice(files, func(i, j int) bool {
+			return files[i].modTime.Before(files[j].modTime)
+		})
+		for _, f := range files {
+			if total <= policy.MaxBytes {
+				break
+			}
+			if err := os.Remove(f.path); err == nil {
+				total -= f.size
+				removed++
+			}
+		}
+	}
+
+	return removed, nil
+}
+
+// StartCacheJanitor 启动后台协程，每隔 interval 清理一次 dir，ctx 取消后退出
+func StartCacheJanitor(ctx context.Context, dir string, policy CachePolicy, interval time.Duration) {
+	cleanup := func() {
+		removed, err := CleanupCache(dir, policy)
+		if err != nil {
+			logrus.Warnf("清理下载缓存失败: %v", err)
+			return
+		}
+		if removed > 0 {
+			logrus.Infof("清理下载缓存: dir=%s, removed=%d", dir, removed)
+		}
+	}
+
+	go func() {
+		cleanup()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				cleanup()
+			}
+		}
+	}()
+}
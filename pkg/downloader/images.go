@@ -9,56 +9,130 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/h2non/filetype"
 	"github.com/pkg/errors"
 )
 
+const (
+	defaultConcurrency = 4
+	defaultMaxRetries  = 3
+	defaultRetryDelay  = 500 * time.Millisecond
+	defaultCacheTTL    = 7 * 24 * time.Hour
+)
+
 // ImageDownloader 图片下载器
 type ImageDownloader struct {
 	savePath   string
 	httpClient *http.Client
+
+	concurrency int           // 并发下载数
+	maxBytes    int64         // 单张图片大小上限
+	maxRetries  int           // 失败重试次数
+	retryDelay  time.Duration // 首次重试等待时间，之后指数退避
+	cacheTTL    time.Duration // 缓存有效期，<= 0 表示永不过期
+	policy      URLPolicy     // 下载安全策略
+}
+
+// DownloaderOption 图片下载器选项
+type DownloaderOption func(*ImageDownloader)
+
+// WithConcurrency 设置并发下载数
+func WithConcurrency(n int) DownloaderOption {
+	return func(d *ImageDownloader) {
+		if n > 0 {
+			d.concurrency = n
+		}
+	}
+}
+
+// WithMaxBytes 设置单张图片大小上限
+func WithMaxBytes(n int64) DownloaderOption {
+	return func(d *ImageDownloader) {
+		if n > 0 {
+			d.maxBytes = n
+		}
+	}
 }
 
-// NewImageDownloader 创建图片下载器
-func NewImageDownloader(savePath string) *ImageDownloader {
+// WithRetry 设置失败重试次数和首次重试等待时间
+func WithRetry(maxRetries int, delay time.Duration) DownloaderOption {
+	return func(d *ImageDownloader) {
+		d.maxRetries = maxRetries
+		d.retryDelay = delay
+	}
+}
+
+// WithCacheTTL 设置缓存有效期
+func WithCacheTTL(ttl time.Duration) DownloaderOption {
+	return func(d *ImageDownloader) {
+		d.cacheTTL = ttl
+	}
+}
+
+// WithURLPolicy 设置下载安全策略
+func WithURLPolicy(policy URLPolicy) DownloaderOption {
+	return func(d *ImageDownloader) {
+		d.policy = policy
+	}
+}
+
+// NewImageDownloader 创建图片下载器，默认禁止访问内网地址
+func NewImageDownloader(savePath string, options ...DownloaderOption) *ImageDownloader {
 	// 确保保存目录存在
 	if err := os.MkdirAll(savePath, 0755); err != nil {
 		panic(fmt.Sprintf("failed to create save path: %v", err))
 	}
 
-	return &ImageDownloader{
-		savePath: savePath,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+	d := &ImageDownloader{
+		savePath:    savePath,
+		concurrency: defaultConcurrency,
+		maxBytes:    DefaultMaxImageBytes,
+		maxRetries:  defaultMaxRetries,
+		retryDelay:  defaultRetryDelay,
+		cacheTTL:    defaultCacheTTL,
+		policy:      DefaultURLPolicy(),
 	}
+	for _, opt := range options {
+		opt(d)
+	}
+	d.httpClient = d.policy.NewHTTPClient(30 * time.Second)
+
+	return d
 }
 
 // DownloadImage 下载图片
-// 返回本地文件路径
+// 返回本地文件路径。同一 URL 在缓存有效期内直接复用本地文件。
 func (d *ImageDownloader) DownloadImage(imageURL string) (string, error) {
 	// 验证URL格式
 	if !d.isValidImageURL(imageURL) {
 		return "", errors.New("invalid image URL format")
 	}
 
-	// 下载图片数据
-	resp, err := d.httpClient.Get(imageURL)
-	if err != nil {
-		return "", errors.Wrap(err, "failed to download image")
+	if err := d.policy.CheckURL(imageURL); err != nil {
+		return "", err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("download failed with status: %d", resp.StatusCode)
+	if path, ok := d.lookupCache(imageURL); ok {
+		return path, nil
 	}
 
-	// 读取图片数据
-	imageData, err := io.ReadAll(resp.Body)
+	var imageData []byte
+	var err error
+	delay := d.retryDelay
+	for attempt := 0; ; attempt++ {
+		var retryable bool
+		imageData, retryable, err = d.fetch(imageURL)
+		if err == nil || !retryable || attempt >= d.maxRetries {
+			break
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
 	if err != nil {
-		return "", errors.Wrap(err, "failed to read image data")
+		return "", err
 	}
 
 	// 检测图片格式
@@ -71,44 +145,149 @@ func (d *ImageDownloader) DownloadImage(imageURL string) (string, error) {
 		return "", errors.New("downloaded file is not a valid image")
 	}
 
-	// 生成唯一文件名
 	fileName := d.generateFileName(imageURL, kind.Extension)
 	filePath := filepath.Join(d.savePath, fileName)
 
-	// 如果文件已存在，直接返回路径
-	if _, err := os.Stat(filePath); err == nil {
-		return filePath, nil
-	}
-
-	// 保存到文件
-	if err := os.WriteFile(filePath, imageData, 0644); err != nil {
+	// 先写唯一的临时文件再重命名，并发下载同一 URL 时互不覆盖，也不会读到不完整的文件
+	if err := writeFileAtomic(filePath, imageData); err != nil {
 		return "", errors.Wrap(err, "failed to save image")
 	}
 
 	return filePath, nil
 }
 
+// writeFileAtomic 在同一目录写入临时文件后重命名为 path
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := f.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// fetch 下载一次图片数据，返回错误是否值得重试
+func (d *ImageDownloader) fetch(imageURL string) ([]byte, bool, error) {
+	resp, err := d.httpClient.Get(imageURL)
+	if err != nil {
+		// 被安全策略拒绝的请求不重试
+		return nil, !errors.Is(err, ErrURLNotAllowed), errors.Wrap(err, "failed to download image")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return nil, retryable, fmt.Errorf("download failed with status: %d", resp.StatusCode)
+	}
+
+	if resp.ContentLength > d.maxBytes {
+		return nil, false, fmt.Errorf("image size %d exceeds limit %d", resp.ContentLength, d.maxBytes)
+	}
+
+	// 多读 1 字节用于判断是否超过上限
+	imageData, err := io.ReadAll(io.LimitReader(resp.Body, d.maxBytes+1))
+	if err != nil {
+		return nil, true, errors.Wrap(err, "failed to read image data")
+	}
+	if int64(len(imageData)) > d.maxBytes {
+		return nil, false, fmt.Errorf("image size exceeds limit %d", d.maxBytes)
+	}
+
+	return imageData, false, nil
+}
+
+// lookupCache 查找 URL 对应的未过期缓存文件
+func (d *ImageDownloader) lookupCache(imageURL string) (string, bool) {
+	matches, err := filepath.Glob(filepath.Join(d.savePath, d.cacheKey(imageURL)+".*"))
+	if err != nil {
+		return "", false
+	}
+
+	for _, path := range matches {
+		if strings.HasSuffix(path, ".tmp") {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if d.cacheTTL > 0 && time.Since(info.ModTime()) > d.cacheTTL {
+			_ = os.Remove(path)
+			continue
+		}
+		return path, true
+	}
+
+	return "", false
+}
+
 // DownloadImages 批量下载图片
 func (d *ImageDownloader) DownloadImages(imageURLs []string) ([]string, error) {
-	var localPaths []string
-	var errs []error
+	paths, errs := d.downloadAll(imageURLs)
 
-	for _, imageURL := range imageURLs {
-		localPath, err := d.DownloadImage(imageURL)
-		if err != nil {
-			errs = append(errs, fmt.Errorf("failed to download %s: %w", imageURL, err))
+	var localPaths []string
+	var allErrs []error
+	for i, imageURL := range imageURLs {
+		if errs[i] != nil {
+			allErrs = append(allErrs, fmt.Errorf("failed to download %s: %w", imageURL, errs[i]))
 			continue
 		}
-		localPaths = append(localPaths, localPath)
+		localPaths = append(localPaths, paths[i])
 	}
 
-	if len(errs) > 0 {
-		return localPaths, fmt.Errorf("download errors occurred: %v", errs)
+	if len(allErrs) > 0 {
+		return localPaths, fmt.Errorf("download errors occurred: %v", allErrs)
 	}
 
 	return localPaths, nil
 }
 
+// downloadAll 并发下载图片，返回与输入一一对应的路径和错误。重复的 URL 只下载一次
+func (d *ImageDownloader) downloadAll(imageURLs []string) ([]string, []error) {
+	paths := make([]string, len(imageURLs))
+	errs := make([]error, len(imageURLs))
+
+	first := make(map[string]int, len(imageURLs))
+	sem := make(chan struct{}, d.concurrency)
+	var wg sync.WaitGroup
+	for i, imageURL := range imageURLs {
+		if _, ok := first[imageURL]; ok {
+			continue
+		}
+		first[imageURL] = i
+
+		wg.Add(1)
+		go func(i int, imageURL string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			paths[i], errs[i] = d.DownloadImage(imageURL)
+		}(i, imageURL)
+	}
+	wg.Wait()
+
+	for i, imageURL := range imageURLs {
+		if j := first[imageURL]; j != i {
+			paths[i], errs[i] = paths[j], errs[j]
+		}
+	}
+
+	return paths, errs
+}
+
 // isValidImageURL 检查是否为有效的图片URL
 func (d *ImageDownloader) isValidImageURL(rawURL string) bool {
 	// 检查是否以http/https开头
@@ -126,19 +305,15 @@ func (d *ImageDownloader) isValidImageURL(rawURL string) bool {
 	return parsedURL.Scheme != "" && parsedURL.Host != ""
 }
 
-// generateFileName 生成唯一的文件名
-func (d *ImageDownloader) generateFileName(imageURL, extension string) string {
-	// 使用URL的SHA256哈希作为文件名，确保唯一性
+// cacheKey 使用URL的SHA256哈希作为缓存键，同一URL总是对应同一个文件
+func (d *ImageDownloader) cacheKey(imageURL string) string {
 	hash := sha256.Sum256([]byte(imageURL))
-	hashStr := fmt.Sprintf("%x", hash)
-
-	// 取前16位哈希值作为文件名
-	shortHash := hashStr[:16]
-
-	// 添加时间戳确保更好的唯一性
-	timestamp := time.Now().Unix()
+	return fmt.Sprintf("img_%x", hash[:8])
+}
 
-	return fmt.Sprintf("img_%s_%d.%s", shortHash, timestamp, extension)
+// generateFileName 生成缓存文件名
+func (d *ImageDownloader) generateFileName(imageURL, extension string) string {
+	return fmt.Sprintf("%s.%s", d.cacheKey(imageURL), extension)
 }
 
 // IsImageURL 判断字符串是否为图片URL
@@ -1,10 +1,16 @@
 package downloader
 
 import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestIsImageURL(t *testing.T) {
@@ -100,3 +106,183 @@ func TestImageDownloader_generateFileName(t *testing.T) {
 		t.Errorf("different URLs should generate different file names")
 	}
 }
+
+func TestImageDownloader_CacheAndRetry(t *testing.T) {
+	data := testPNG(t)
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 第一次请求返回 503，验证重试
+		if atomic.AddInt32(&hits, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	d := NewImageDownloader(t.TempDir(), WithURLPolicy(localPolicy), WithRetry(2, time.Millisecond))
+
+	path1, err := d.DownloadImage(server.URL + "/brand.png")
+	if err != nil {
+		t.Fatalf("DownloadImage failed: %v", err)
+	}
+	path2, err := d.DownloadImage(server.URL + "/brand.png")
+	if err != nil {
+		t.Fatalf("DownloadImage failed: %v", err)
+	}
+
+	if path1 != path2 {
+		t.Errorf("cached path mismatch: %s != %s", path1, path2)
+	}
+	if got := atomic.LoadInt32(&hits); got != 2 {
+		t.Errorf("server hits = %d, expected 2 (one retry, then cache hit)", got)
+	}
+}
+
+func TestImageDownloader_MaxBytes(t *testing.T) {
+	data := testPNG(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	d := NewImageDownloader(t.TempDir(), WithURLPolicy(localPolicy), WithMaxBytes(10))
+	if _, err := d.DownloadImage(server.URL + "/big.png"); err == nil {
+		t.Error("expected error when image exceeds max bytes")
+	}
+}
+
+func TestImageDownloader_DownloadImagesConcurrency(t *testing.T) {
+	data := testPNG(t)
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	d := NewImageDownloader(t.TempDir(), WithURLPolicy(localPolicy), WithConcurrency(2))
+
+	var urls []string
+	for i := 0; i < 8; i++ {
+		urls = append(urls, fmt.Sprintf("%s/%d.png", server.URL, i))
+	}
+
+	paths, err := d.DownloadImages(urls)
+	if err != nil {
+		t.Fatalf("DownloadImages failed: %v", err)
+	}
+	if len(paths) != len(urls) {
+		t.Errorf("got %d paths, expected %d", len(paths), len(urls))
+	}
+	for i, path := range paths {
+		if filepath.Base(path) != d.generateFileName(urls[i], "png") {
+			t.Errorf("paths[%d] = %s, expected order to be preserved", i, path)
+		}
+	}
+	if got := atomic.LoadInt32(&maxInFlight); got > 2 {
+		t.Errorf("max in-flight requests = %d, expected <= 2", got)
+	}
+}
+
+func TestImageDownloader_DownloadImagesDuplicates(t *testing.T) {
+	data := testPNG(t)
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(20 * time.Millisecond)
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	d := NewImageDownloader(dir, WithURLPolicy(localPolicy), WithConcurrency(4))
+	imageURL := server.URL + "/same.png"
+
+	paths, err := d.DownloadImages([]string{imageURL, imageURL, imageURL})
+	if err != nil {
+		t.Fatalf("DownloadImages failed: %v", err)
+	}
+	if len(paths) != 3 || paths[0] != paths[1] || paths[1] != paths[2] {
+		t.Errorf("paths = %v, expected the same path three times", paths)
+	}
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("server hits = %d, expected 1", got)
+	}
+
+	got, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("downloaded image content mismatch")
+	}
+	if tmp, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(tmp) > 0 {
+		t.Errorf("temporary files left behind: %v", tmp)
+	}
+}
+
+func TestCleanupCache(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+
+	write := func(name string, size int, modTime time.Time) {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expired := "img_0000000000000001.jpg"
+	older := "img_0000000000000002.jpg"
+	newer := "img_0000000000000003.png"
+	write(expired, 10, old)
+	write(older, 100, time.Now().Add(-2*time.Hour))
+	write(newer, 100, time.Now())
+
+	// 视频和未下载完的视频同样按时间清理
+	expiredVideo := "video_0000000000000006.mp4"
+	expiredPart := "video_0000000000000007.part"
+	write(expiredVideo, 10, old)
+	write(expiredPart, 10, old)
+
+	// base64 图片和预处理结果同样过期清理，下载中的临时文件不清理
+	write("b64_0000000000000004.jpg", 100, old)
+	write("proc_0000000000000005.jpg", 100, old)
+	write(newer+".123.tmp", 100, old)
+
+	removed, err := CleanupCache(dir, CachePolicy{TTL: 24 * time.Hour, MaxBytes: 150})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 6 {
+		t.Errorf("removed = %d, expected 6", removed)
+	}
+
+	for name, exists := range map[string]bool{
+		expired:                     false,
+		expiredVideo:                false,
+		expiredPart:                 false,
+		older:                       false,
+		newer:                       true,
+		"b64_0000000000000004.jpg":  false,
+		"proc_0000000000000005.jpg": false,
+		newer + ".123.tmp":          true,
+	} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if (err == nil) != exists {
+			t.Errorf("%s exists = %v, expected %v", name, err == nil, exists)
+		}
+	}
+}
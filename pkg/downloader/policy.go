@@ -0,0 +1,249 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/xpzouying/xiaohongshu-mcp/configs"
+)
+
+const defaultMaxRedirects = 5
+
+// ErrURLNotAllowed URL 被下载安全策略拒绝
+var ErrURLNotAllowed = errors.New("url not allowed by download policy")
+
+// URLPolicy 下载安全策略，防止通过 MCP 调用让服务端访问内网地址（SSRF）
+type URLPolicy struct {
+	AllowPrivate   bool     // 是否允许访问内网/回环/链路本地地址，默认禁止
+	AllowedDomains []string // 非空时只允许这些域名及其子域名
+	MaxRedirects   int      // 最大重定向次数，<= 0 时使用默认值 5
+	Proxy          string   // 代理地址，如 http://127.0.0.1:7890；为空时直连（不读取环境变量代理）
+}
+
+// DefaultURLPolicy 默认策略：禁止内网地址，不限制域名，不使用代理
+func DefaultURLPolicy() URLPolicy {
+	return URLPolicy{MaxRedirects: defaultMaxRedirects}
+}
+
+// PolicyFromConfig 根据启动配置生成下载安全策略
+func PolicyFromConfig() URLPolicy {
+	return URLPolicy{
+		AllowPrivate:   configs.DownloadAllowPrivate(),
+		AllowedDomains: configs.GetDownloadAllowedDomains(),
+		MaxRedirects:   defaultMaxRedirects,
+		Proxy:          configs.GetDownloadProxy(),
+	}
+}
+
+// CheckURL 校验 URL 的协议、域名白名单以及 IP 地址
+func (p URLPolicy) CheckURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.Wrapf(ErrURLNotAllowed, "invalid url: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.Wrapf(ErrURLNotAllowed, "unsupported scheme %q", u.Scheme)
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		return errors.Wrap(ErrURLNotAllowed, "missing host")
+	}
+
+	if len(p.AllowedDomains) > 0 && !p.domainAllowed(host) {
+		return errors.Wrapf(ErrURLNotAllowed, "domain %s is not in allowlist", host)
+	}
+
+	if p.AllowPrivate {
+		return nil
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return checkIP(ip)
+	}
+
+	// 使用代理时由代理解析域名，连接阶段无法校验目标 IP，这里预先解析校验
+	if p.Proxy != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return errors.Wrapf(err, "failed to resolve %s", host)
+		}
+		for _, addr := range addrs {
+			if err := checkIP(addr.IP); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// domainAllowed 判断域名是否在白名单内（支持子域名）
+func (p URLPolicy) domainAllowed(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, domain := range p.AllowedDomains {
+		domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "."))
+		if domain == "" {
+			continue
+		}
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewHTTPClient 创建遵循策略的 HTTP 客户端：
+// 连接前对 DNS 解析结果逐一校验（防止 DNS rebinding），重定向时重新校验并限制次数。
+// 代理地址无效时，所有请求都会返回错误。
+func (p URLPolicy) NewHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+
+	transport := &http.Transport{
+		TLSHandshakeTimeout:   30 * time.Second,
+		ResponseHeaderTimeout: 60 * time.Second,
+	}
+
+	var proxyAddr string
+	if p.Proxy != "" {
+		proxyURL, err := ParseProxy(p.Proxy)
+		if err != nil {
+			transport.Proxy = func(*http.Request) (*url.URL, error) { return nil, err }
+		} else {
+			transport.Proxy = http.ProxyURL(proxyURL)
+			proxyAddr = canonicalAddr(proxyURL)
+		}
+	}
+
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		// 代理地址由配置指定，视为可信
+		if p.AllowPrivate || addr == proxyAddr {
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var lastErr error = errors.Wrapf(ErrURLNotAllowed, "no address for %s", host)
+		for _, ipAddr := range addrs {
+			if err := checkIP(ipAddr.IP); err != nil {
+				lastErr = err
+				continue
+			}
+			// 直接连接校验过的 IP，避免二次解析得到不同结果
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ipAddr.IP.String(), port))
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			return conn, nil
+		}
+		return nil, lastErr
+	}
+
+	maxRedirects := p.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = defaultMaxRedirects
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.Wrapf(ErrURLNotAllowed, "stopped after %d redirects", maxRedirects)
+			}
+			return p.CheckURL(req.URL.String())
+		},
+	}
+}
+
+// ParseProxy 解析代理地址，支持 http/https/socks5
+func ParseProxy(proxy string) (*url.URL, error) {
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy: %s", proxy)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return u, nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: %s", u.Scheme)
+	}
+}
+
+// checkIP 拒绝回环、内网、链路本地、组播等非公网地址
+func checkIP(ip net.IP) error {
+	if IsPublicIP(ip) {
+		return nil
+	}
+	return errors.Wrapf(ErrURLNotAllowed, "address %s is not public", ip)
+}
+
+// 除标准库已覆盖的类型外，额外拒绝的保留网段
+var reservedNets = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range []string{
+		"0.0.0.0/8",     // 本网络
+		"100.64.0.0/10", // 运营商级 NAT
+		"192.0.0.0/24",  // IETF 协议分配
+		"198.18.0.0/15", // 基准测试
+		"240.0.0.0/4",   // 保留
+		"64:ff9b::/96",  // NAT64
+	} {
+		_, n, _ := net.ParseCIDR(cidr)
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+// IsPublicIP 判断是否为可访问的公网地址
+func IsPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+
+	for _, n := range reservedNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// canonicalAddr 返回 host:port 形式的代理地址
+func canonicalAddr(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "https":
+			port = "443"
+		case "socks5", "socks5h":
+			port = "1080"
+		default:
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
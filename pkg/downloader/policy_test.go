@@ -0,0 +1,117 @@
+package downloader
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// localPolicy 测试使用的策略，允许访问 httptest 的本地地址
+var localPolicy = URLPolicy{AllowPrivate: true}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip       string
+		expected bool
+	}{
+		{"8.8.8.8", true},
+		{"2001:4860:4860::8888", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"224.0.0.1", false},
+	}
+
+	for _, test := range tests {
+		if got := IsPublicIP(net.ParseIP(test.ip)); got != test.expected {
+			t.Errorf("IsPublicIP(%s) = %v, expected %v", test.ip, got, test.expected)
+		}
+	}
+}
+
+func TestURLPolicy_CheckURL(t *testing.T) {
+	policy := URLPolicy{AllowedDomains: []string{"example.com", ".cdn.net"}}
+
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://example.com/a.jpg", false},
+		{"https://img.example.com/a.jpg", false},
+		{"https://static.cdn.net/a.jpg", false},
+		{"https://badexample.com/a.jpg", true},
+		{"https://evil.com/a.jpg", true},
+		{"ftp://example.com/a.jpg", true},
+		{"http://169.254.169.254/latest/meta-data", true},
+	}
+
+	for _, test := range tests {
+		err := policy.CheckURL(test.url)
+		if (err != nil) != test.wantErr {
+			t.Errorf("CheckURL(%s) error = %v, wantErr %v", test.url, err, test.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrURLNotAllowed) {
+			t.Errorf("CheckURL(%s) error should wrap ErrURLNotAllowed, got %v", test.url, err)
+		}
+	}
+
+	if err := DefaultURLPolicy().CheckURL("http://127.0.0.1:8080/admin"); err == nil {
+		t.Error("default policy should reject loopback address")
+	}
+	if err := localPolicy.CheckURL("http://127.0.0.1:8080/admin"); err != nil {
+		t.Errorf("AllowPrivate policy should accept loopback address: %v", err)
+	}
+}
+
+func TestImageDownloader_BlocksPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(testPNG(t))
+	}))
+	defer server.Close()
+
+	d := NewImageDownloader(t.TempDir())
+	_, err := d.DownloadImage(server.URL + "/a.png")
+	if !errors.Is(err, ErrURLNotAllowed) {
+		t.Errorf("expected ErrURLNotAllowed, got %v", err)
+	}
+}
+
+func TestURLPolicy_DialBlocksResolvedPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(testPNG(t))
+	}))
+	defer server.Close()
+
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	// localhost 是域名，只能在 DNS 解析后的连接阶段被拦截
+	client := DefaultURLPolicy().NewHTTPClient(0)
+	_, err := client.Get("http://localhost:" + port + "/a.png")
+	if !errors.Is(err, ErrURLNotAllowed) {
+		t.Errorf("expected ErrURLNotAllowed, got %v", err)
+	}
+}
+
+func TestURLPolicy_RedirectLimit(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, server.URL+"/loop", http.StatusFound)
+	}))
+	defer server.Close()
+
+	client := URLPolicy{AllowPrivate: true, MaxRedirects: 3}.NewHTTPClient(0)
+	_, err := client.Get(server.URL)
+	if !errors.Is(err, ErrURLNotAllowed) {
+		t.Errorf("expected redirect limit error, got %v", err)
+	}
+}
@@ -4,50 +4,103 @@ import (
 	"fmt"
 
 	"github.com/xpzouying/xiaohongshu-mcp/configs"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/imageproc"
 )
 
 // ImageProcessor 图片处理器
 type ImageProcessor struct {
 	downloader *ImageDownloader
+	preprocess *imageproc.Options
+}
+
+// ProcessorOption 图片处理器选项
+type ProcessorOption func(*ImageProcessor)
+
+// WithPreprocess 在下载后对图片进行预处理（格式转换、方向校正、比例调整等）
+func WithPreprocess(opts *imageproc.Options) ProcessorOption {
+	return func(p *ImageProcessor) {
+		p.preprocess = opts
+	}
 }
 
 // NewImageProcessor 创建图片处理器
-func NewImageProcessor() *ImageProcessor {
-	return &ImageProcessor{
-		downloader: NewImageDownloader(configs.GetImagesPath()),
+func NewImageProcessor(options ...ProcessorOption) *ImageProcessor {
+	p := &ImageProcessor{
+		downloader: NewImageDownloader(configs.GetImagesPath(), WithURLPolicy(PolicyFromConfig())),
+	}
+	for _, opt := range options {
+		opt(p)
 	}
+	return p
 }
 
-// ProcessImages 处理图片列表，返回本地文件路径
-// 支持两种输入格式：
+// ProcessImages 处理图片列表，返回与输入顺序一致的本地文件路径
+// 支持以下输入格式：
 // 1. URL格式 (http/https开头) - 自动下载到本地
-// 2. 本地文件路径 - 直接使用
+// 2. data URI (data:image/png;base64,...) 或纯 base64 - 解码保存到本地
+// 3. 本地文件路径 - 直接使用
+// 每张图片都会校验（存在、可读、是真实图片、大小），任意一张失败都返回
+// *ImageValidationError，不会静默丢弃图片。
+// 设置了预处理选项时，所有图片会再经过预处理流水线。
 func (p *ImageProcessor) ProcessImages(images []string) ([]string, error) {
-	var localPaths []string
-	var urlsToDownload []string
+	if len(images) == 0 {
+		return nil, fmt.Errorf("no valid images found")
+	}
+
+	localPaths := make([]string, len(images))
+	resolveErrs := make([]error, len(images))
 
-	// 分离URL和本地路径
-	for _, image := range images {
+	// URL 图片并发下载
+	var urlIndexes []int
+	var urls []string
+	for i, image := range images {
 		if IsImageURL(image) {
-			urlsToDownload = append(urlsToDownload, image)
-		} else {
-			// 本地路径直接添加
-			localPaths = append(localPaths, image)
+			urlIndexes = append(urlIndexes, i)
+			urls = append(urls, image)
+		}
+	}
+	if len(urls) > 0 {
+		paths, errs := p.downloader.downloadAll(urls)
+		for j, i := range urlIndexes {
+			localPaths[i], resolveErrs[i] = paths[j], errs[j]
 		}
 	}
 
-	// 批量下载URL图片
-	if len(urlsToDownload) > 0 {
-		downloadedPaths, err := p.downloader.DownloadImages(urlsToDownload)
+	var imageErrs []ImageError
+	for i, image := range images {
+		path, err := localPaths[i], resolveErrs[i]
+		if !IsImageURL(image) {
+			path, err = resolveLocalImage(image)
+		}
+		if err == nil {
+			err = ValidateImageFile(path, DefaultMaxImageBytes)
+		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to download images: %w", err)
+			imageErrs = append(imageErrs, ImageError{Index: i, Input: shortInput(image), Error: err.Error()})
+			continue
 		}
-		localPaths = append(localPaths, downloadedPaths...)
+		localPaths[i] = path
 	}
 
-	if len(localPaths) == 0 {
-		return nil, fmt.Errorf("no valid images found")
+	if len(imageErrs) > 0 {
+		return nil, &ImageValidationError{Errors: imageErrs}
+	}
+
+	if !p.preprocess.IsZero() {
+		processed, err := imageproc.NewProcessor(configs.GetImagesPath()).ProcessAll(localPaths, p.preprocess)
+		if err != nil {
+			return nil, fmt.Errorf("failed to preprocess images: %w", err)
+		}
+		localPaths = processed
 	}
 
 	return localPaths, nil
 }
+
+// resolveLocalImage 将非 URL 的图片输入（base64 或本地路径）转换为本地文件路径
+func resolveLocalImage(image string) (string, error) {
+	if IsDataURI(image) || IsBase64Image(image) {
+		return SaveBase64Image(image, configs.GetImagesPath())
+	}
+	return image, nil
+}
@@ -0,0 +1,173 @@
+package downloader
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/h2non/filetype"
+	"github.com/pkg/errors"
+)
+
+// DefaultMaxImageBytes 小红书单张图片大小上限
+const DefaultMaxImageBytes int64 = 32 << 20
+
+// ImageError 单张图片的校验错误
+type ImageError struct {
+	Index int    `json:"index"` // 在请求 images 中的位置，从 0 开始
+	Input string `json:"input"` // 原始输入（base64 会被截断）
+	Error string `json:"error"`
+}
+
+// ImageValidationError 图片校验失败，包含每张出错图片的原因
+type ImageValidationError struct {
+	Errors []ImageError `json:"errors"`
+}
+
+func (e *ImageValidationError) Error() string {
+	lines := make([]string, 0, len(e.Errors))
+	for _, ie := range e.Errors {
+		lines = append(lines, fmt.Sprintf("第%d张图片(%s): %s", ie.Index+1, ie.Input, ie.Error))
+	}
+	return fmt.Sprintf("%d 张图片校验失败: %s", len(e.Errors), strings.Join(lines, "; "))
+}
+
+// IsDataURI 判断是否为 data URI，如 data:image/png;base64,xxxx
+func IsDataURI(s string) bool {
+	return strings.HasPrefix(strings.ToLower(s), "data:")
+}
+
+// IsBase64Image 判断字符串是否为不带前缀的 base64 图片数据
+func IsBase64Image(s string) bool {
+	// 过短的字符串更可能是文件路径
+	if len(s) < 64 || strings.ContainsAny(s, `\.`) {
+		return false
+	}
+	data, err := decodeBase64(s)
+	if err != nil {
+		return false
+	}
+	return filetype.IsImage(data)
+}
+
+// SaveBase64Image 解码 data URI 或 base64 图片并保存到 savePath，返回本地文件路径。
+// 文件名使用内容哈希，相同图片只保存一份。
+func SaveBase64Image(s, savePath string) (string, error) {
+	payload := s
+	if IsDataURI(s) {
+		idx := strings.Index(s, ",")
+		if idx < 0 {
+			return "", errors.New("invalid data URI: missing comma")
+		}
+		if !strings.Contains(strings.ToLower(s[:idx]), ";base64") {
+			return "", errors.New("invalid data URI: only base64 encoding is supported")
+		}
+		payload = s[idx+1:]
+	}
+
+	data, err := decodeBase64(payload)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid base64 data")
+	}
+
+	kind, err := filetype.Match(data)
+	if err != nil || !filetype.IsImage(data) {
+		return "", errors.New("decoded data is not a valid image")
+	}
+
+	if err := os.MkdirAll(savePath, 0755); err != nil {
+		return "", errors.Wrap(err, "failed to create save path")
+	}
+
+	hash := sha256.Sum256(data)
+	filePath := filepath.Join(savePath, fmt.Sprintf("b64_%x.%s", hash[:8], kind.Extension))
+	if _, err := os.Stat(filePath); err == nil {
+		// 更新修改时间，避免使用中被缓存清理删除
+		now := time.Now()
+		_ = os.Chtimes(filePath, now, now)
+		return filePath, nil
+	}
+
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		return "", errors.Wrap(err, "failed to save image")
+	}
+
+	return filePath, nil
+}
+
+// ValidateImageFile 校验本地图片：存在、可读、是真实图片且大小在限制内
+func ValidateImageFile(path string, maxBytes int64) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.New("file does not exist")
+		}
+		return errors.Wrap(err, "failed to stat file")
+	}
+	if info.IsDir() {
+		return errors.New("path is a directory")
+	}
+	if info.Size() == 0 {
+		return errors.New("file is empty")
+	}
+	if maxBytes > 0 && info.Size() > maxBytes {
+		return fmt.Errorf("file size %d exceeds limit %d", info.Size(), maxBytes)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "file is not readable")
+	}
+	defer f.Close()
+
+	// filetype 只需要文件头
+	head := make([]byte, 261)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return errors.Wrap(err, "failed to read file")
+	}
+	if !filetype.IsImage(head[:n]) {
+		return errors.New("file is not a valid image")
+	}
+
+	return nil
+}
+
+// decodeBase64 兼容标准/URL 安全、带或不带填充的 base64
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\r' || r == ' ' || r == '\t' {
+			return -1
+		}
+		return r
+	}, s)
+
+	encodings := []*base64.Encoding{
+		base64.StdEncoding, base64.RawStdEncoding,
+		base64.URLEncoding, base64.RawURLEncoding,
+	}
+
+	var lastErr error
+	for _, enc := range encodings {
+		data, err := enc.DecodeString(s)
+		if err == nil {
+			return data, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// shortInput 截断过长的输入（如 base64），便于在错误信息中展示
+func shortInput(s string) string {
+	const maxLen = 48
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen] + "..."
+}
@@ -0,0 +1,137 @@
+package downloader
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 16, 16))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSaveBase64Image(t *testing.T) {
+	data := testPNG(t)
+	encoded := base64.StdEncoding.EncodeToString(data)
+	dir := t.TempDir()
+
+	inputs := []string{
+		"data:image/png;base64," + encoded,
+		encoded,
+		base64.RawURLEncoding.EncodeToString(data),
+	}
+
+	for _, input := range inputs {
+		path, err := SaveBase64Image(input, dir)
+		if err != nil {
+			t.Fatalf("SaveBase64Image(%q) failed: %v", shortInput(input), err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("saved content mismatch for %q", shortInput(input))
+		}
+		if filepath.Ext(path) != ".png" {
+			t.Errorf("path = %s, expected .png extension", path)
+		}
+	}
+
+	if _, err := SaveBase64Image("data:text/plain;base64,"+base64.StdEncoding.EncodeToString([]byte("hello world")), dir); err == nil {
+		t.Error("expected error for non-image data URI")
+	}
+	if _, err := SaveBase64Image("data:image/png,rawdata", dir); err == nil {
+		t.Error("expected error for non-base64 data URI")
+	}
+}
+
+func TestIsBase64Image(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(testPNG(t))
+
+	if !IsBase64Image(encoded) {
+		t.Error("expected base64 PNG to be detected")
+	}
+	if IsBase64Image("/Users/user/image.jpg") {
+		t.Error("local path should not be detected as base64")
+	}
+	if IsBase64Image(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("not an image"), 10))) {
+		t.Error("non-image base64 should not be detected as image")
+	}
+}
+
+func TestValidateImageFile(t *testing.T) {
+	dir := t.TempDir()
+
+	imagePath := filepath.Join(dir, "ok.png")
+	if err := os.WriteFile(imagePath, testPNG(t), 0644); err != nil {
+		t.Fatal(err)
+	}
+	textPath := filepath.Join(dir, "fake.jpg")
+	if err := os.WriteFile(textPath, []byte("definitely not an image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	emptyPath := filepath.Join(dir, "empty.jpg")
+	if err := os.WriteFile(emptyPath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path     string
+		maxBytes int64
+		wantErr  bool
+	}{
+		{imagePath, DefaultMaxImageBytes, false},
+		{imagePath, 10, true},
+		{textPath, DefaultMaxImageBytes, true},
+		{emptyPath, DefaultMaxImageBytes, true},
+		{filepath.Join(dir, "missing.jpg"), DefaultMaxImageBytes, true},
+		{dir, DefaultMaxImageBytes, true},
+	}
+
+	for _, test := range tests {
+		err := ValidateImageFile(test.path, test.maxBytes)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ValidateImageFile(%s, %d) error = %v, wantErr %v", test.path, test.maxBytes, err, test.wantErr)
+		}
+	}
+}
+
+func TestImageProcessor_ProcessImages_Report(t *testing.T) {
+	dir := t.TempDir()
+	okPath := filepath.Join(dir, "ok.png")
+	if err := os.WriteFile(okPath, testPNG(t), 0644); err != nil {
+		t.Fatal(err)
+	}
+	encoded := "data:image/png;base64," + base64.StdEncoding.EncodeToString(testPNG(t))
+
+	processor := NewImageProcessor()
+
+	paths, err := processor.ProcessImages([]string{okPath, encoded})
+	if err != nil {
+		t.Fatalf("ProcessImages failed: %v", err)
+	}
+	if len(paths) != 2 || paths[0] != okPath {
+		t.Errorf("paths = %v, expected order to be preserved", paths)
+	}
+
+	_, err = processor.ProcessImages([]string{okPath, filepath.Join(dir, "missing.jpg"), encoded})
+	var imageErr *ImageValidationError
+	if !errors.As(err, &imageErr) {
+		t.Fatalf("expected ImageValidationError, got %v", err)
+	}
+	if len(imageErr.Errors) != 1 || imageErr.Errors[0].Index != 1 {
+		t.Errorf("errors = %+v, expected one error at index 1", imageErr.Errors)
+	}
+}
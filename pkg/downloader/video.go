@@ -0,0 +1,317 @@
+package downloader
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/h2non/filetype"
+	"github.com/pkg/errors"
+)
+
+// DefaultMaxVideoBytes 默认允许通过 URL 下载的视频大小上限。
+// 平台允许上传 20GB，但通过链接下载的成品视频一般远小于此，过大的上限只会让异常链接占满磁盘
+const DefaultMaxVideoBytes int64 = 2 << 30
+
+// DefaultVideoCachePolicy 下载视频的清理策略，视频只在发布时使用，保留 1 天，
+// 总大小上限留出一个最大视频的余量，避免刚下载完的视频被清理
+var DefaultVideoCachePolicy = CachePolicy{
+	TTL:      24 * time.Hour,
+	MaxBytes: 2 * DefaultMaxVideoBytes,
+}
+
+// videoLocks 同一文件的下载串行执行，.part/.partinfo 按 URL 固定命名，并发写入会损坏视频
+var videoLocks = &keyedMutex{locks: map[string]*refMutex{}}
+
+type refMutex struct {
+	sync.Mutex
+	refs int
+}
+
+// keyedMutex 按 key 加锁，没有等待者时释放对应的锁
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*refMutex
+}
+
+func (k *keyedMutex) Lock(key string) (unlock func()) {
+	k.mu.Lock()
+	m, ok := k.locks[key]
+	if !ok {
+		m = &refMutex{}
+		k.locks[key] = m
+	}
+	m.refs++
+	k.mu.Unlock()
+
+	m.Lock()
+	return func() {
+		m.Unlock()
+		k.mu.Lock()
+		if m.refs--; m.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
+
+// VideoDownloader 视频下载器，支持大小限制和断点续传
+type VideoDownloader struct {
+	savePath   string
+	maxBytes   int64
+	policy     URLPolicy
+	httpClient *http.Client
+}
+
+// NewVideoDownloader 创建视频下载器，maxBytes <= 0 时使用 DefaultMaxVideoBytes
+func NewVideoDownloader(savePath string, maxBytes int64, policy URLPolicy) *VideoDownloader {
+	if err := os.MkdirAll(savePath, 0755); err != nil {
+		panic(fmt.Sprintf("failed to create save path: %v", err))
+	}
+
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxVideoBytes
+	}
+
+	return &VideoDownloader{
+		savePath: savePath,
+		maxBytes: maxBytes,
+		policy:   policy,
+		// 视频文件较大，不设置整体超时，只限制建连和响应头等待时间
+		httpClient: policy.NewHTTPClient(0),
+	}
+}
+
+// DownloadVideo 下载视频，返回本地文件路径。
+// 下载中的数据写入 .part 文件，响应的 ETag/Last-Modified 保存在 .partinfo 中；
+// 中断后再次下载同一 URL 会带上 If-Range 续传，远端文件已变化时从头下载。
+// 同一 URL 的并发请求串行执行，后到的请求直接复用下载好的文件。
+func (d *VideoDownloader) DownloadVideo(videoURL string) (string, error) {
+	if !IsVideoURL(videoURL) {
+		return "", errors.New("invalid video URL format")
+	}
+
+	if err := d.policy.CheckURL(videoURL); err != nil {
+		return "", err
+	}
+
+	baseName := d.generateFileName(videoURL)
+	partPath := filepath.Join(d.savePath, baseName+".part")
+	infoPath := filepath.Join(d.savePath, baseName+".partinfo")
+
+	unlock := videoLocks.Lock(filepath.Join(d.savePath, baseName))
+	defer unlock()
+
+	// 已经下载完成的文件直接复用，更新修改时间，避免使用中被缓存清理删除
+	if matches, _ := filepath.Glob(filepath.Join(d.savePath, baseName+".*")); len(matches) > 0 {
+		for _, m := range matches {
+			if !strings.HasSuffix(m, ".part") && !strings.HasSuffix(m, ".partinfo") {
+				now := time.Now()
+				_ = os.Chtimes(m, now, now)
+				return m, nil
+			}
+		}
+	}
+
+	err := d.download(videoURL, partPath, infoPath)
+	if errors.Is(err, errResumeMismatch) {
+		// 本地数据与远端文件对不上，丢弃后从头下载
+		_ = os.Remove(partPath)
+		_ = os.Remove(infoPath)
+		err = d.download(videoURL, partPath, infoPath)
+	}
+	if err != nil {
+		return "", err
+	}
+	_ = os.Remove(infoPath)
+
+	kind, err := filetype.MatchFile(partPath)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to detect file type")
+	}
+	if kind.MIME.Type != "video" {
+		_ = os.Remove(partPath)
+		return "", fmt.Errorf("downloaded file is not a valid video: %s", kind.MIME.Value)
+	}
+
+	filePath := filepath.Join(d.savePath, baseName+"."+kind.Extension)
+	if err := os.Rename(partPath, filePath); err != nil {
+		return "", errors.Wrap(err, "failed to save video")
+	}
+
+	return filePath, nil
+}
+
+// errResumeMismatch 本地 .part 无法续传（远端文件已变化或范围不一致），需要从头下载
+var errResumeMismatch = errors.New("partial video does not match remote file")
+
+// partInfo 续传校验信息，与 .part 文件一起保存
+type partInfo struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Size         int64  `json:"size,omitempty"` // 远端文件总大小，未知时为 0
+}
+
+// ifRange 续传时使用的 If-Range 值，弱 ETag 不能用于范围请求
+func (p *partInfo) ifRange() string {
+	if p.ETag != "" && !strings.HasPrefix(p.ETag, "W/") {
+		return p.ETag
+	}
+	return p.LastModified
+}
+
+// download 将 URL 内容写入 partPath。已有部分数据且保存了校验信息时通过 If-Range 续传，
+// 远端文件变化时服务端返回完整内容，从头写入
+func (d *VideoDownloader) download(videoURL, partPath, infoPath string) error {
+	var offset int64
+	info := readPartInfo(infoPath)
+	if stat, err := os.Stat(partPath); err == nil {
+		offset = stat.Size()
+	}
+	if offset > 0 && (info == nil || info.ifRange() == "") {
+		// 没有校验信息无法确认远端文件未变化，不续传
+		offset = 0
+	}
+
+	req, err := http.NewRequest(http.MethodGet, videoURL, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", info.ifRange())
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to download video")
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	var total int64
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		if offset == 0 {
+			return fmt.Errorf("unexpected partial content without range request")
+		}
+		start, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset || (info.Size > 0 && size > 0 && size != info.Size) {
+			return errResumeMismatch
+		}
+		flags |= os.O_APPEND
+		total = size
+	case http.StatusOK:
+		// 首次下载、服务端不支持续传或远端文件已变化，从头下载
+		offset = 0
+		flags |= os.O_TRUNC
+		total = resp.ContentLength
+		info = &partInfo{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			Size:         max(total, 0),
+		}
+		if err := writePartInfo(infoPath, info); err != nil {
+			return err
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// 只有远端总大小与本地数据一致时才认为已下载完成
+		if _, size, ok := parseContentRange(resp.Header.Get("Content-Range")); ok && info != nil && size == offset && size == info.Size {
+			return nil
+		}
+		return errResumeMismatch
+	default:
+		return fmt.Errorf("download failed with status: %d", resp.StatusCode)
+	}
+
+	if total > d.maxBytes {
+		return fmt.Errorf("video size %d exceeds limit %d", total, d.maxBytes)
+	}
+
+	f, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return errors.Wrap(err, "failed to open video file")
+	}
+	defer f.Close()
+
+	// 多读 1 字节用于判断是否超过上限
+	remaining := d.maxBytes - offset
+	n, err := io.Copy(f, io.LimitReader(resp.Body, remaining+1))
+	if err != nil {
+		return errors.Wrap(err, "failed to read video data")
+	}
+	if n > remaining {
+		_ = f.Close()
+		_ = os.Remove(partPath)
+		return fmt.Errorf("video size exceeds limit %d", d.maxBytes)
+	}
+	if total > 0 && offset+n != total {
+		return fmt.Errorf("video download incomplete: got %d of %d bytes", offset+n, total)
+	}
+
+	return nil
+}
+
+// parseContentRange 解析 "bytes start-end/size" 或 "bytes */size"，size 未知（*）时返回 0
+func parseContentRange(value string) (start, size int64, ok bool) {
+	var err error
+	rangePart, sizePart, found := strings.Cut(strings.TrimPrefix(value, "bytes "), "/")
+	if !found {
+		return 0, 0, false
+	}
+	if sizePart != "*" {
+		if size, err = strconv.ParseInt(sizePart, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	if rangePart == "*" {
+		return 0, size, true
+	}
+	startPart, _, found := strings.Cut(rangePart, "-")
+	if !found {
+		return 0, 0, false
+	}
+	if start, err = strconv.ParseInt(startPart, 10, 64); err != nil {
+		return 0, 0, false
+	}
+	return start, size, true
+}
+
+func readPartInfo(path string) *partInfo {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var info partInfo
+	if json.Unmarshal(data, &info) != nil {
+		return nil
+	}
+	return &info
+}
+
+func writePartInfo(path string, info *partInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(os.WriteFile(path, data, 0644), "failed to save download info")
+}
+
+// generateFileName 根据 URL 哈希生成固定的文件名（不含扩展名），便于续传和复用
+func (d *VideoDownloader) generateFileName(videoURL string) string {
+	hash := sha256.Sum256([]byte(videoURL))
+	return fmt.Sprintf("video_%x", hash[:8])
+}
+
+// IsVideoURL 判断字符串是否为视频URL
+func IsVideoURL(path string) bool {
+	return IsImageURL(path)
+}
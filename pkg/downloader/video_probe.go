@@ -0,0 +1,325 @@
+package downloader
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxMoovSize moov box 的最大读取大小，防止异常文件占用过多内存
+const maxMoovSize = 64 << 20
+
+// VideoInfo 视频容器信息
+type VideoInfo struct {
+	Path     string        `json:"path"`
+	Size     int64         `json:"size"`
+	Brand    string        `json:"brand"` // ftyp major brand，如 isom、mp42、qt
+	Duration time.Duration `json:"duration"`
+	Width    int           `json:"width"`
+	Height   int           `json:"height"`
+	Codec    string        `json:"codec"` // 视频轨道编码，如 avc1、hvc1
+}
+
+// VideoLimits 平台对视频的限制
+type VideoLimits struct {
+	MaxSize     int64
+	MaxDuration time.Duration
+	MinWidth    int // 短边最小像素
+	Codecs      []string
+}
+
+// DefaultVideoLimits 小红书网页端发布视频的限制：60 分钟以内，20GB 以内，MP4/MOV
+var DefaultVideoLimits = VideoLimits{
+	MaxSize:     20 << 30,
+	MaxDuration: 60 * time.Minute,
+	MinWidth:    360,
+	Codecs:      []string{"avc1", "avc3", "hvc1", "hev1", "mp4v"},
+}
+
+// Validate 校验视频是否满足平台限制
+func (l VideoLimits) Validate(info *VideoInfo) error {
+	var problems []string
+
+	if l.MaxSize > 0 && info.Size > l.MaxSize {
+		problems = append(problems, fmt.Sprintf("文件大小 %d 字节超过上限 %d 字节", info.Size, l.MaxSize))
+	}
+	if info.Duration <= 0 {
+		problems = append(problems, "无法获取视频时长")
+	} else if l.MaxDuration > 0 && info.Duration > l.MaxDuration {
+		problems = append(problems, fmt.Sprintf("视频时长 %s 超过上限 %s", info.Duration, l.MaxDuration))
+	}
+	if info.Width <= 0 || info.Height <= 0 {
+		problems = append(problems, "无法获取视频分辨率")
+	} else if l.MinWidth > 0 && min(info.Width, info.Height) < l.MinWidth {
+		problems = append(problems, fmt.Sprintf("分辨率 %dx%d 过低，短边至少 %d", info.Width, info.Height, l.MinWidth))
+	}
+	if len(l.Codecs) > 0 && !containsFold(l.Codecs, info.Codec) {
+		problems = append(problems, fmt.Sprintf("不支持的视频编码 %q，支持 %s", info.Codec, strings.Join(l.Codecs, "/")))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("视频不符合平台要求: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// InspectVideo 解析 MP4/MOV 容器，获取时长、分辨率、编码和文件大小
+func InspectVideo(path string) (*VideoInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open video")
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to stat video")
+	}
+
+	info := &VideoInfo{Path: path, Size: stat.Size()}
+
+	var moov []byte
+	var offset int64
+	for offset < info.Size {
+		boxType, headerSize, boxSize, err := readBoxHeader(f, offset, info.Size)
+		if err != nil {
+			return nil, err
+		}
+
+		switch boxType {
+		case "ftyp":
+			brand := make([]byte, 4)
+			if _, err := f.ReadAt(brand, offset+headerSize); err == nil {
+				info.Brand = strings.TrimSpace(string(brand))
+			}
+		case "moov":
+			if boxSize-headerSize > maxMoovSize {
+				return nil, fmt.Errorf("moov box too large: %d", boxSize)
+			}
+			moov = make([]byte, boxSize-headerSize)
+			if _, err := f.ReadAt(moov, offset+headerSize); err != nil {
+				return nil, errors.Wrap(err, "failed to read moov box")
+			}
+		}
+
+		offset += boxSize
+	}
+
+	if info.Brand == "" {
+		return nil, errors.New("not a MP4/MOV file: missing ftyp box")
+	}
+	if moov == nil {
+		return nil, errors.New("invalid MP4/MOV file: missing moov box")
+	}
+
+	parseMoov(moov, info)
+	return info, nil
+}
+
+// readBoxHeader 读取 offset 处的 box 头，返回类型、头长度和 box 总长度
+func readBoxHeader(r io.ReaderAt, offset, fileSize int64) (string, int64, int64, error) {
+	header := make([]byte, 16)
+	n, err := r.ReadAt(header, offset)
+	if n < 8 {
+		return "", 0, 0, errors.Wrapf(err, "failed to read box header at %d", offset)
+	}
+
+	size := int64(binary.BigEndian.Uint32(header[0:4]))
+	boxType := string(header[4:8])
+	headerSize := int64(8)
+
+	switch size {
+	case 0:
+		size = fileSize - offset
+	case 1:
+		if n < 16 {
+			return "", 0, 0, fmt.Errorf("truncated largesize box at %d", offset)
+		}
+		size = int64(binary.BigEndian.Uint64(header[8:16]))
+		headerSize = 16
+	}
+
+	if size < headerSize || offset+size > fileSize {
+		return "", 0, 0, fmt.Errorf("invalid box %q size %d at %d", boxType, size, offset)
+	}
+
+	return boxType, headerSize, size, nil
+}
+
+// box 内存中的 box
+type box struct {
+	typ  string
+	data []byte
+}
+
+// splitBoxes 将 data 拆分为子 box 列表，遇到非法数据时停止
+func splitBoxes(data []byte) []box {
+	var boxes []box
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data[0:4]))
+		typ := string(data[4:8])
+		headerSize := uint64(8)
+
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return boxes
+			}
+			size = binary.BigEndian.Uint64(data[8:16])
+			headerSize = 16
+		}
+
+		if size < headerSize || size > uint64(len(data)) {
+			return boxes
+		}
+
+		boxes = append(boxes, box{typ: typ, data: data[headerSize:size]})
+		data = data[size:]
+	}
+	return boxes
+}
+
+func parseMoov(moov []byte, info *VideoInfo) {
+	for _, b := range splitBoxes(moov) {
+		switch b.typ {
+		case "mvhd":
+			if d, ok := parseMvhd(b.data); ok {
+				info.Duration = d
+			}
+		case "trak":
+			// 只取第一个视频轨道
+			if info.Codec != "" {
+				continue
+			}
+			parseTrak(b.data, info)
+		}
+	}
+}
+
+func parseMvhd(data []byte) (time.Duration, bool) {
+	if len(data) < 4 {
+		return 0, false
+	}
+
+	var timescale, duration uint64
+	switch data[0] {
+	case 0:
+		if len(data) < 20 {
+			return 0, false
+		}
+		timescale = uint64(binary.BigEndian.Uint32(data[12:16]))
+		duration = uint64(binary.BigEndian.Uint32(data[16:20]))
+	case 1:
+		if len(data) < 32 {
+			return 0, false
+		}
+		timescale = uint64(binary.BigEndian.Uint32(data[20:24]))
+		duration = binary.BigEndian.Uint64(data[24:32])
+	default:
+		return 0, false
+	}
+
+	if timescale == 0 {
+		return 0, false
+	}
+
+	return time.Duration(float64(duration) / float64(timescale) * float64(time.Second)), true
+}
+
+func parseTrak(trak []byte, info *VideoInfo) {
+	var width, height int
+	var rotated bool
+	var handler, codec string
+
+	for _, b := range splitBoxes(trak) {
+		switch b.typ {
+		case "tkhd":
+			width, height, rotated = parseTkhd(b.data)
+		case "mdia":
+			handler, codec = parseMdia(b.data)
+		}
+	}
+
+	if handler != "vide" {
+		return
+	}
+
+	if rotated {
+		width, height = height, width
+	}
+
+	info.Width = width
+	info.Height = height
+	info.Codec = codec
+}
+
+// parseTkhd 解析轨道宽高，以及矩阵是否表示旋转 90/270 度
+func parseTkhd(data []byte) (int, int, bool) {
+	if len(data) < 4 {
+		return 0, 0, false
+	}
+
+	// version/flags 之后：v0 为 20 字节时间信息，v1 为 32 字节
+	offset := 4 + 20
+	if data[0] == 1 {
+		offset = 4 + 32
+	}
+	// reserved(8) layer(2) alternate_group(2) volume(2) reserved(2)
+	offset += 16
+
+	if len(data) < offset+36+8 {
+		return 0, 0, false
+	}
+
+	matrix := data[offset : offset+36]
+	a := int32(binary.BigEndian.Uint32(matrix[0:4]))
+	b := int32(binary.BigEndian.Uint32(matrix[4:8]))
+	rotated := a == 0 && b != 0
+
+	offset += 36
+	width := int(binary.BigEndian.Uint32(data[offset:offset+4]) >> 16)
+	height := int(binary.BigEndian.Uint32(data[offset+4:offset+8]) >> 16)
+
+	return width, height, rotated
+}
+
+func parseMdia(mdia []byte) (handler, codec string) {
+	for _, b := range splitBoxes(mdia) {
+		switch b.typ {
+		case "hdlr":
+			// version/flags(4) pre_defined(4) handler_type(4)
+			if len(b.data) >= 12 {
+				handler = string(b.data[8:12])
+			}
+		case "minf":
+			for _, mb := range splitBoxes(b.data) {
+				if mb.typ != "stbl" {
+					continue
+				}
+				for _, sb := range splitBoxes(mb.data) {
+					if sb.typ == "stsd" && len(sb.data) >= 16 {
+						// version/flags(4) entry_count(4) 之后是第一个 sample entry
+						codec = string(sb.data[12:16])
+					}
+				}
+			}
+		}
+	}
+	return handler, codec
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,321 @@
+package downloader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// mp4Box 构造一个 MP4 box
+func mp4Box(typ string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	buf := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(8+len(body)))
+	copy(buf[4:8], typ)
+	return append(buf, body...)
+}
+
+func u32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+// buildTestMP4 构造只包含 ftyp/moov/mdat 的最小 MP4 文件
+func buildTestMP4(timescale, duration uint32, width, height int, codec string) []byte {
+	ftyp := mp4Box("ftyp", []byte("isom"), u32(512), []byte("isomiso2avc1mp41"))
+
+	mvhd := mp4Box("mvhd", u32(0), u32(0), u32(0), u32(timescale), u32(duration), make([]byte, 80))
+
+	// 单位矩阵
+	matrix := bytes.Join([][]byte{u32(0x00010000), u32(0), u32(0), u32(0), u32(0x00010000), u32(0), u32(0), u32(0), u32(0x40000000)}, nil)
+	tkhd := mp4Box("tkhd", u32(0), make([]byte, 20), make([]byte, 16), matrix, u32(uint32(width)<<16), u32(uint32(height)<<16))
+
+	hdlr := mp4Box("hdlr", u32(0), u32(0), []byte("vide"), make([]byte, 12))
+	stsd := mp4Box("stsd", u32(0), u32(1), mp4Box(codec, make([]byte, 78)))
+	mdia := mp4Box("mdia", hdlr, mp4Box("minf", mp4Box("stbl", stsd)))
+	trak := mp4Box("trak", tkhd, mdia)
+
+	moov := mp4Box("moov", mvhd, trak)
+	mdat := mp4Box("mdat", make([]byte, 1024))
+
+	return bytes.Join([][]byte{ftyp, mdat, moov}, nil)
+}
+
+func TestInspectVideo(t *testing.T) {
+	data := buildTestMP4(1000, 12500, 1080, 1920, "avc1")
+	path := filepath.Join(t.TempDir(), "test.mp4")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := InspectVideo(path)
+	if err != nil {
+		t.Fatalf("InspectVideo failed: %v", err)
+	}
+
+	if info.Size != int64(len(data)) {
+		t.Errorf("Size = %d, expected %d", info.Size, len(data))
+	}
+	if info.Brand != "isom" {
+		t.Errorf("Brand = %q, expected isom", info.Brand)
+	}
+	if info.Duration != 12500*time.Millisecond {
+		t.Errorf("Duration = %s, expected 12.5s", info.Duration)
+	}
+	if info.Width != 1080 || info.Height != 1920 {
+		t.Errorf("resolution = %dx%d, expected 1080x1920", info.Width, info.Height)
+	}
+	if info.Codec != "avc1" {
+		t.Errorf("Codec = %q, expected avc1", info.Codec)
+	}
+
+	if err := DefaultVideoLimits.Validate(info); err != nil {
+		t.Errorf("Validate failed: %v", err)
+	}
+}
+
+func TestInspectVideo_NotMP4(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.txt")
+	if err := os.WriteFile(path, []byte("this is not a video file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := InspectVideo(path); err == nil {
+		t.Error("expected error for non-MP4 file")
+	}
+}
+
+func TestVideoLimits_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		info    VideoInfo
+		wantErr string
+	}{
+		{"ok", VideoInfo{Size: 1 << 20, Duration: time.Minute, Width: 720, Height: 1280, Codec: "hvc1"}, ""},
+		{"too long", VideoInfo{Size: 1 << 20, Duration: 2 * time.Hour, Width: 720, Height: 1280, Codec: "avc1"}, "时长"},
+		{"too large", VideoInfo{Size: DefaultVideoLimits.MaxSize + 1, Duration: time.Minute, Width: 720, Height: 1280, Codec: "avc1"}, "文件大小"},
+		{"low resolution", VideoInfo{Size: 1 << 20, Duration: time.Minute, Width: 320, Height: 240, Codec: "avc1"}, "分辨率"},
+		{"bad codec", VideoInfo{Size: 1 << 20, Duration: time.Minute, Width: 720, Height: 1280, Codec: "av01"}, "编码"},
+	}
+
+	for _, test := range tests {
+		err := DefaultVideoLimits.Validate(&test.info)
+		if test.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", test.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("%s: error = %v, expected to contain %q", test.name, err, test.wantErr)
+		}
+	}
+}
+
+func TestVideoDownloader_Resume(t *testing.T) {
+	data := buildTestMP4(600, 6000, 1280, 720, "avc1")
+	changed := buildTestMP4(600, 9000, 1280, 720, "avc1")
+
+	tests := []struct {
+		name       string
+		info       *partInfo // 上一次下载保存的校验信息
+		serve      []byte
+		etag       string
+		wantRange  bool // 是否发送了续传请求
+		wantResume bool // 服务端是否按范围返回
+	}{
+		{"same etag resumes", &partInfo{ETag: `"v1"`, Size: int64(len(data))}, data, `"v1"`, true, true},
+		{"changed etag restarts", &partInfo{ETag: `"v1"`, Size: int64(len(data))}, changed, `"v2"`, true, false},
+		{"no info restarts", nil, data, `"v1"`, false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var gotRange, gotPartial bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotRange = r.Header.Get("Range") != ""
+				w.Header().Set("ETag", test.etag)
+				rec := &statusRecorder{ResponseWriter: w}
+				http.ServeContent(rec, r, "video.mp4", time.Time{}, bytes.NewReader(test.serve))
+				gotPartial = rec.status == http.StatusPartialContent
+			}))
+			defer server.Close()
+
+			dir := t.TempDir()
+			d := NewVideoDownloader(dir, 0, localPolicy)
+			videoURL := server.URL + "/video.mp4"
+
+			// 模拟上一次下载中断，只留下一半数据
+			baseName := d.generateFileName(videoURL)
+			if err := os.WriteFile(filepath.Join(dir, baseName+".part"), data[:len(data)/2], 0644); err != nil {
+				t.Fatal(err)
+			}
+			if test.info != nil {
+				if err := writePartInfo(filepath.Join(dir, baseName+".partinfo"), test.info); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			path, err := d.DownloadVideo(videoURL)
+			if err != nil {
+				t.Fatalf("DownloadVideo failed: %v", err)
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, test.serve) {
+				t.Errorf("content mismatch: got %d bytes, expected %d", len(got), len(test.serve))
+			}
+			if gotRange != test.wantRange || gotPartial != test.wantResume {
+				t.Errorf("range = %v, partial = %v, expected %v, %v", gotRange, gotPartial, test.wantRange, test.wantResume)
+			}
+			if filepath.Ext(path) != ".mp4" {
+				t.Errorf("path = %s, expected .mp4 extension", path)
+			}
+			if _, err := os.Stat(filepath.Join(dir, baseName+".partinfo")); !os.IsNotExist(err) {
+				t.Errorf("partinfo should be removed after download, stat err = %v", err)
+			}
+		})
+	}
+}
+
+func TestVideoDownloader_RangeNotSatisfiable(t *testing.T) {
+	data := buildTestMP4(600, 6000, 1280, 720, "avc1")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			// 远端文件变短了，本地数据不再是它的前缀
+			w.Header().Set("Content-Range", "bytes */10")
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	d := NewVideoDownloader(dir, 0, localPolicy)
+	videoURL := server.URL + "/video.mp4"
+	baseName := d.generateFileName(videoURL)
+	if err := os.WriteFile(filepath.Join(dir, baseName+".part"), bytes.Repeat([]byte{1}, 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writePartInfo(filepath.Join(dir, baseName+".partinfo"), &partInfo{ETag: `"v1"`, Size: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := d.DownloadVideo(videoURL)
+	if err != nil {
+		t.Fatalf("DownloadVideo failed: %v", err)
+	}
+	got, _ := os.ReadFile(path)
+	if !bytes.Equal(got, data) {
+		t.Errorf("416 should restart the download, got %d bytes", len(got))
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		value       string
+		start, size int64
+		ok          bool
+	}{
+		{"bytes 100-199/200", 100, 200, true},
+		{"bytes 100-199/*", 100, 0, true},
+		{"bytes */200", 0, 200, true},
+		{"bytes abc/200", 0, 0, false},
+		{"", 0, 0, false},
+	}
+
+	for _, test := range tests {
+		start, size, ok := parseContentRange(test.value)
+		if start != test.start || size != test.size || ok != test.ok {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v, expected %d, %d, %v", test.value, start, size, ok, test.start, test.size, test.ok)
+		}
+	}
+}
+
+// statusRecorder 记录响应状态码
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func TestVideoDownloader_ConcurrentSameURL(t *testing.T) {
+	data := buildTestMP4(600, 6000, 1280, 720, "avc1")
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		// 分段慢速返回，让并发请求在下载过程中到达
+		for i := 0; i < len(data); i += 256 {
+			w.Write(data[i:min(i+256, len(data))])
+			w.(http.Flusher).Flush()
+			time.Sleep(time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	d := NewVideoDownloader(t.TempDir(), 0, localPolicy)
+	videoURL := server.URL + "/video.mp4"
+
+	const n = 5
+	paths := make([]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			paths[i], errs[i] = d.DownloadVideo(videoURL)
+		}()
+	}
+	wg.Wait()
+
+	for i := range n {
+		if errs[i] != nil {
+			t.Fatalf("DownloadVideo #%d failed: %v", i, errs[i])
+		}
+		if paths[i] != paths[0] {
+			t.Errorf("path #%d = %s, expected %s", i, paths[i], paths[0])
+		}
+	}
+	got, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("content mismatch: got %d bytes, expected %d", len(got), len(data))
+	}
+	if requests.Load() != 1 {
+		t.Errorf("requests = %d, expected 1", requests.Load())
+	}
+}
+
+func TestVideoDownloader_MaxBytes(t *testing.T) {
+	data := buildTestMP4(600, 6000, 1280, 720, "avc1")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	d := NewVideoDownloader(t.TempDir(), 100, localPolicy)
+	if _, err := d.DownloadVideo(server.URL + "/video.mp4"); err == nil {
+		t.Error("expected error when video exceeds max bytes")
+	}
+}
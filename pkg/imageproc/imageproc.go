@@ -0,0 +1,328 @@
+// Package imageproc 上传前的图片预处理：格式转换、EXIF 方向校正、去除元数据、
+// 按比例裁剪/填充、缩放和文件大小控制。
+package imageproc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/h2non/filetype"
+	"github.com/pkg/errors"
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	FitCrop = "crop" // 居中裁剪到目标比例
+	FitPad  = "pad"  // 白色填充到目标比例
+
+	defaultQuality = 90
+	minQuality     = 60
+
+	// processVersion 处理逻辑变化时修改，避免复用旧版本生成的处理结果
+	processVersion = "2"
+
+	// MaxPixels 允许解码的最大像素数（约 1 亿，覆盖高像素手机原图）。
+	// 解码前按文件头声明的尺寸检查，避免很小的文件声明超大尺寸后解码占满内存
+	MaxPixels = 100_000_000
+)
+
+// Options 图片预处理选项，零值表示不做任何处理
+type Options struct {
+	ConvertToJPEG bool   `json:"convert_to_jpeg,omitempty" jsonschema:"是否统一转换为JPEG（WebP/PNG/GIF等）"`
+	AutoOrient    bool   `json:"auto_orient,omitempty" jsonschema:"是否根据EXIF方向信息自动旋转。设置任意处理选项时图片都会重新编码并按EXIF方向旋转，只需校正方向时单独设置此项"`
+	StripMetadata bool   `json:"strip_metadata,omitempty" jsonschema:"是否去除EXIF/GPS等元数据"`
+	AspectRatio   string `json:"aspect_ratio,omitempty" jsonschema:"目标宽高比: 3:4|1:1|4:3，为空则保持原比例"`
+	Fit           string `json:"fit,omitempty" jsonschema:"比例调整方式: crop(居中裁剪)|pad(白边填充)，默认crop"`
+	MaxDimension  int    `json:"max_dimension,omitempty" jsonschema:"最长边像素上限，超过则等比缩小"`
+	MaxFileSize   int64  `json:"max_file_size,omitempty" jsonschema:"文件大小上限（字节），超过则降低质量或缩小尺寸"`
+	Quality       int    `json:"quality,omitempty" jsonschema:"JPEG质量 1-100，默认90"`
+}
+
+// IsZero 是否未设置任何处理
+func (o *Options) IsZero() bool {
+	return o == nil || *o == Options{}
+}
+
+// Validate 校验选项
+func (o *Options) Validate() error {
+	if o.IsZero() {
+		return nil
+	}
+	if o.AspectRatio != "" {
+		if _, _, err := parseAspectRatio(o.AspectRatio); err != nil {
+			return err
+		}
+	}
+	if o.Fit != "" && o.Fit != FitCrop && o.Fit != FitPad {
+		return fmt.Errorf("invalid fit mode: %s", o.Fit)
+	}
+	if o.Quality < 0 || o.Quality > 100 {
+		return fmt.Errorf("invalid quality: %d", o.Quality)
+	}
+	if o.MaxDimension < 0 || o.MaxFileSize < 0 {
+		return errors.New("max_dimension and max_file_size must not be negative")
+	}
+	return nil
+}
+
+// Processor 图片预处理器，处理结果保存到 outputDir
+type Processor struct {
+	outputDir string
+}
+
+// NewProcessor 创建图片预处理器
+func NewProcessor(outputDir string) *Processor {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		panic(fmt.Sprintf("failed to create output dir: %v", err))
+	}
+	return &Processor{outputDir: outputDir}
+}
+
+// Process 按选项处理单张图片，返回处理后的文件路径。
+// 选项为空时直接返回原路径。
+func (p *Processor) Process(path string, opts *Options) (string, error) {
+	if opts.IsZero() {
+		return path, nil
+	}
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read image")
+	}
+
+	kind, err := filetype.Match(data)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to detect file type")
+	}
+	if kind.MIME.Value == "image/heif" || kind.MIME.Value == "image/heic" {
+		return "", fmt.Errorf("HEIC/HEIF images are not supported, please convert to JPEG first: %s", path)
+	}
+	if !filetype.IsImage(data) {
+		return "", fmt.Errorf("not a valid image: %s", path)
+	}
+
+	outPath := filepath.Join(p.outputDir, p.outputName(data, opts))
+	if _, err := os.Stat(outPath); err == nil {
+		// 更新修改时间，避免使用中被缓存清理删除
+		now := time.Now()
+		_ = os.Chtimes(outPath, now, now)
+		return outPath, nil
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to decode image %s", path)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return "", fmt.Errorf("image %s is too large: %dx%d exceeds %d pixels", path, cfg.Width, cfg.Height, MaxPixels)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to decode image %s", path)
+	}
+
+	// 重新编码会丢弃 EXIF，必须先按方向旋转，否则裁剪方向错误、输出图片横倒
+	if kind.MIME.Value == "image/jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+
+	if opts.AspectRatio != "" {
+		w, h, _ := parseAspectRatio(opts.AspectRatio)
+		if opts.Fit == FitPad {
+			img = padToRatio(img, w, h)
+		} else {
+			img = cropToRatio(img, w, h)
+		}
+	}
+
+	if opts.MaxDimension > 0 {
+		img = fitWithin(img, opts.MaxDimension)
+	}
+
+	asPNG := kind.MIME.Value == "image/png" && !opts.ConvertToJPEG
+	out, err := encode(img, asPNG, opts)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(outPath, out, 0644); err != nil {
+		return "", errors.Wrap(err, "failed to save processed image")
+	}
+
+	return outPath, nil
+}
+
+// ProcessAll 批量处理图片，保持顺序，任意一张失败即返回错误
+func (p *Processor) ProcessAll(paths []string, opts *Options) ([]string, error) {
+	if opts.IsZero() {
+		return paths, nil
+	}
+
+	result := make([]string, 0, len(paths))
+	for _, path := range paths {
+		out, err := p.Process(path, opts)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, out)
+	}
+	return result, nil
+}
+
+// outputName 根据源文件内容和处理选项生成文件名，相同输入复用处理结果
+func (p *Processor) outputName(data []byte, opts *Options) string {
+	optsJSON, _ := json.Marshal(opts)
+	h := sha256.New()
+	h.Write([]byte(processVersion))
+	h.Write(data)
+	h.Write(optsJSON)
+
+	ext := "jpg"
+	if kind, _ := filetype.Match(data); kind.MIME.Value == "image/png" && !opts.ConvertToJPEG {
+		ext = "png"
+	}
+
+	return fmt.Sprintf("proc_%x.%s", h.Sum(nil)[:8], ext)
+}
+
+// encode 编码图片，JPEG 在超过大小上限时先降低质量，再逐步缩小尺寸
+func encode(img image.Image, asPNG bool, opts *Options) ([]byte, error) {
+	quality := opts.Quality
+	if quality == 0 {
+		quality = defaultQuality
+	}
+
+	for {
+		var buf bytes.Buffer
+		var err error
+		if asPNG {
+			err = png.Encode(&buf, img)
+		} else {
+			err = jpeg.Encode(&buf, flatten(img), &jpeg.Options{Quality: quality})
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to encode image")
+		}
+
+		if opts.MaxFileSize <= 0 || int64(buf.Len()) <= opts.MaxFileSize {
+			return buf.Bytes(), nil
+		}
+
+		if !asPNG && quality > minQuality {
+			quality = max(minQuality, quality-10)
+			continue
+		}
+
+		b := img.Bounds()
+		if b.Dx() < 64 || b.Dy() < 64 {
+			return nil, fmt.Errorf("cannot compress image below %d bytes", opts.MaxFileSize)
+		}
+		img = fitWithin(img, max(b.Dx(), b.Dy())*85/100)
+	}
+}
+
+// flatten 将透明图片合成到白色背景上（JPEG 不支持透明通道）
+func flatten(img image.Image) image.Image {
+	if _, ok := img.(*image.YCbCr); ok {
+		return img
+	}
+	if _, ok := img.(*image.Gray); ok {
+		return img
+	}
+
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	xdraw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, xdraw.Src)
+	xdraw.Draw(dst, dst.Bounds(), img, b.Min, xdraw.Over)
+	return dst
+}
+
+// parseAspectRatio 解析 "3:4" 形式的宽高比
+func parseAspectRatio(ratio string) (int, int, error) {
+	parts := strings.Split(ratio, ":")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid aspect ratio: %s", ratio)
+	}
+	w, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
+	h, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err1 != nil || err2 != nil || w <= 0 || h <= 0 {
+		return 0, 0, fmt.Errorf("invalid aspect ratio: %s", ratio)
+	}
+	return w, h, nil
+}
+
+// cropToRatio 居中裁剪到 rw:rh
+func cropToRatio(img image.Image, rw, rh int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	cw, ch := w, w*rh/rw
+	if ch > h {
+		cw, ch = h*rw/rh, h
+	}
+	if cw == w && ch == h {
+		return img
+	}
+
+	x0 := b.Min.X + (w-cw)/2
+	y0 := b.Min.Y + (h-ch)/2
+	dst := image.NewRGBA(image.Rect(0, 0, cw, ch))
+	xdraw.Draw(dst, dst.Bounds(), img, image.Pt(x0, y0), xdraw.Src)
+	return dst
+}
+
+// padToRatio 白色填充到 rw:rh，原图居中
+func padToRatio(img image.Image, rw, rh int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	pw, ph := w, w*rh/rw
+	if ph < h {
+		pw, ph = h*rw/rh, h
+	}
+	if pw == w && ph == h {
+		return img
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, pw, ph))
+	xdraw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, xdraw.Src)
+	offset := image.Pt((pw-w)/2, (ph-h)/2)
+	xdraw.Draw(dst, image.Rectangle{Min: offset, Max: offset.Add(image.Pt(w, h))}, img, b.Min, xdraw.Over)
+	return dst
+}
+
+// fitWithin 等比缩小，使最长边不超过 maxDim
+func fitWithin(img image.Image, maxDim int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxDim && h <= maxDim {
+		return img
+	}
+
+	nw, nh := maxDim, h*maxDim/w
+	if h > w {
+		nw, nh = w*maxDim/h, maxDim
+	}
+	nw, nh = max(nw, 1), max(nh, 1)
+
+	dst := image.NewRGBA(image.Rect(0, 0, nw, nh))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, b, xdraw.Src, nil)
+	return dst
+}
@@ -0,0 +1,266 @@
+package imageproc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestPNG(t *testing.T, dir string, w, h int) string {
+	t.Helper()
+
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 200})
+		}
+	}
+
+	path := filepath.Join(dir, "test.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeTestJPEGWithOrientation 生成带 EXIF Orientation 的 JPEG
+func writeTestJPEGWithOrientation(t *testing.T, dir string, w, h int, orientation uint16) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// TIFF: II*\0 + IFD0 offset(8) + 1 个 entry
+	tiff := []byte{'I', 'I', 0x2A, 0x00, 0x08, 0x00, 0x00, 0x00, 0x01, 0x00}
+	entry := make([]byte, 12)
+	binary.LittleEndian.PutUint16(entry[0:2], 0x0112)
+	binary.LittleEndian.PutUint16(entry[2:4], 3)
+	binary.LittleEndian.PutUint32(entry[4:8], 1)
+	binary.LittleEndian.PutUint16(entry[8:10], orientation)
+	tiff = append(tiff, entry...)
+	tiff = append(tiff, 0, 0, 0, 0)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:4], uint16(len(payload)+2))
+	app1 = append(app1, payload...)
+
+	jpg := buf.Bytes()
+	data := append([]byte{}, jpg[:2]...)
+	data = append(data, app1...)
+	data = append(data, jpg[2:]...)
+
+	path := filepath.Join(dir, "test.jpg")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func decodeFile(t *testing.T, path string) (image.Image, string) {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	img, format, err := image.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img, format
+}
+
+func TestProcess_NoOptions(t *testing.T) {
+	dir := t.TempDir()
+	src := writeTestPNG(t, dir, 10, 10)
+
+	out, err := NewProcessor(dir).Process(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != src {
+		t.Errorf("expected original path when no options, got %s", out)
+	}
+}
+
+func TestProcess_ConvertAndCrop(t *testing.T) {
+	dir := t.TempDir()
+	src := writeTestPNG(t, dir, 200, 100)
+
+	out, err := NewProcessor(dir).Process(src, &Options{ConvertToJPEG: true, AspectRatio: "3:4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, format := decodeFile(t, out)
+	if format != "jpeg" {
+		t.Errorf("format = %s, expected jpeg", format)
+	}
+	if b := img.Bounds(); b.Dx() != 75 || b.Dy() != 100 {
+		t.Errorf("size = %dx%d, expected 75x100", b.Dx(), b.Dy())
+	}
+}
+
+func TestProcess_Pad(t *testing.T) {
+	dir := t.TempDir()
+	src := writeTestPNG(t, dir, 200, 100)
+
+	out, err := NewProcessor(dir).Process(src, &Options{AspectRatio: "1:1", Fit: FitPad})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, format := decodeFile(t, out)
+	if format != "png" {
+		t.Errorf("format = %s, expected png", format)
+	}
+	if b := img.Bounds(); b.Dx() != 200 || b.Dy() != 200 {
+		t.Errorf("size = %dx%d, expected 200x200", b.Dx(), b.Dy())
+	}
+}
+
+func TestProcess_Orientation(t *testing.T) {
+	// 任何处理都会重新编码并丢弃 EXIF，方向都需要校正
+	tests := []struct {
+		name        string
+		orientation uint16
+		opts        Options
+		width       int
+		height      int
+	}{
+		{"auto orient", 6, Options{AutoOrient: true}, 20, 40},
+		{"strip metadata", 6, Options{StripMetadata: true}, 20, 40},
+		{"aspect ratio", 8, Options{AspectRatio: "3:4"}, 20, 26},
+		{"max file size", 6, Options{MaxFileSize: 1 << 20}, 20, 40},
+		{"upright", 1, Options{StripMetadata: true}, 40, 20},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := writeTestJPEGWithOrientation(t, dir, 40, 20, test.orientation)
+
+			data, err := os.ReadFile(src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if o := jpegOrientation(data); o != int(test.orientation) {
+				t.Fatalf("jpegOrientation = %d, expected %d", o, test.orientation)
+			}
+
+			out, err := NewProcessor(dir).Process(src, &test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			img, _ := decodeFile(t, out)
+			if b := img.Bounds(); b.Dx() != test.width || b.Dy() != test.height {
+				t.Errorf("size = %dx%d, expected %dx%d", b.Dx(), b.Dy(), test.width, test.height)
+			}
+
+			// 重新编码后不应再包含 EXIF
+			outData, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Contains(outData, []byte("Exif\x00\x00")) {
+				t.Error("processed image should not contain EXIF metadata")
+			}
+		})
+	}
+}
+
+func TestProcess_MaxFileSize(t *testing.T) {
+	dir := t.TempDir()
+
+	// 随机噪点图片压缩率低，便于触发大小限制
+	img := image.NewRGBA(image.Rect(0, 0, 400, 400))
+	r := rand.New(rand.NewSource(1))
+	r.Read(img.Pix)
+	src := filepath.Join(dir, "noise.png")
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	const maxSize = 30 * 1024
+	out, err := NewProcessor(dir).Process(src, &Options{ConvertToJPEG: true, MaxFileSize: maxSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() > maxSize {
+		t.Errorf("size = %d, expected <= %d", info.Size(), maxSize)
+	}
+}
+
+func TestProcess_MaxPixels(t *testing.T) {
+	dir := t.TempDir()
+	src := writeTestPNG(t, dir, 1, 1)
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 只修改 IHDR 中的宽高并重新计算 CRC，文件仍然只有几十字节
+	ihdr := data[8+8 : 8+8+13]
+	binary.BigEndian.PutUint32(ihdr[0:4], 50000)
+	binary.BigEndian.PutUint32(ihdr[4:8], 50000)
+	binary.BigEndian.PutUint32(data[8+8+13:], crc32.ChecksumIEEE(data[8+4:8+8+13]))
+	if err := os.WriteFile(src, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewProcessor(dir).Process(src, &Options{ConvertToJPEG: true})
+	if err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("error = %v, expected image too large", err)
+	}
+}
+
+func TestOptions_Validate(t *testing.T) {
+	tests := []struct {
+		opts    Options
+		wantErr bool
+	}{
+		{Options{AspectRatio: "3:4"}, false},
+		{Options{AspectRatio: "3-4"}, true},
+		{Options{AspectRatio: "0:1"}, true},
+		{Options{Fit: "stretch"}, true},
+		{Options{Quality: 101}, true},
+		{Options{MaxFileSize: -1}, true},
+	}
+
+	for _, test := range tests {
+		err := test.opts.Validate()
+		if (err != nil) != test.wantErr {
+			t.Errorf("Validate(%+v) error = %v, wantErr %v", test.opts, err, test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+// Package mdformat 将大模型生成的 Markdown 转换为适合小红书正文的纯文本。
+package mdformat
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Options 转换选项
+type Options struct {
+	Bullet       string // 无序列表符号，默认 🔸
+	NestedBullet string // 嵌套列表符号，默认 ▫️
+	HeadingMark  string // 标题前缀，默认 📌
+	MaxRunes     int    // 正文最大字数，超过时按段落截断，0 表示不限制
+	KeepTags     bool   // 保留正文中的 #标签，默认提取到 Tags
+}
+
+// DefaultOptions 默认转换选项，正文限制与小红书一致
+var DefaultOptions = Options{
+	Bullet:       "🔸",
+	NestedBullet: "▫️",
+	HeadingMark:  "📌",
+	MaxRunes:     1000,
+}
+
+// Result 转换结果
+type Result struct {
+	Content   string   `json:"content"`
+	Tags      []string `json:"tags"`
+	Truncated bool     `json:"truncated"`
+}
+
+var (
+	headingRe     = regexp.MustCompile(`^#{1,6}\s+(.*?)\s*#*\s*$`)
+	taskRe        = regexp.MustCompile(`^(\s*)[-*+]\s+\[([ xX])\]\s+(.*)$`)
+	bulletRe      = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	orderedRe     = regexp.MustCompile(`^(\s*)(\d+)[.)]\s+(.*)$`)
+	quoteRe       = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	ruleRe        = regexp.MustCompile(`^\s*([-*_])(\s*([-*_]))\s*([-*_]\s*)+$`)
+	fenceRe       = regexp.MustCompile("^\\s*(```|~~~)")
+	tableSepRe    = regexp.MustCompile(`^\s*\|?\s*:?-{3,}:?\s*(\|\s*:?-{3,}:?\s*)*\|?\s*$`)
+	imageRe       = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+	linkRe        = regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`)
+	autoLinkRe    = regexp.MustCompile(`<(https?://[^>]+)>`)
+	boldRe        = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	italicRe      = regexp.MustCompile(`(^|[^*\w])\*([^*\s][^*]*?)\*`)
+	strikeRe      = regexp.MustCompile(`~~(.+?)~~`)
+	codeRe        = regexp.MustCompile("`([^`]+)`")
+	brRe          = regexp.MustCompile(`(?i)<br\s*/?>`)
+	htmlTagRe     = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	escapeRe      = regexp.MustCompile(`\\([\\*_#\[\]()~>` + "`" + `|-])`)
+	topicRe       = regexp.MustCompile(`#([^\s#\[]+)\[话题\]#[ \t]?`)
+	tagRe         = regexp.MustCompile(`(^|\s)#([^\s#]+)`)
+	blankLinesRe  = regexp.MustCompile(`\n{3,}`)
+	tagTrimChars  = "，。！？、,.!?;；:：)）]】"
+	keycapNumbers = []string{"0️⃣", "1️⃣", "2️⃣", "3️⃣", "4️⃣", "5️⃣", "6️⃣", "7️⃣", "8️⃣", "9️⃣", "🔟"}
+)
+
+// escapeBase 转义字符临时替换使用的 Unicode 私有区起点
+const escapeBase = 0xE000
+
+// Format 使用默认选项转换 Markdown
+func Format(markdown string) Result {
+	return FormatWithOptions(markdown, DefaultOptions)
+}
+
+// FormatWithOptions 转换 Markdown：标题/列表转为表情符号，去除不支持的语法，
+// 提取正文中的 #标签，规范换行并按需截断
+func FormatWithOptions(markdown string, opts Options) Result {
+	if opts.Bullet == "" {
+		opts.Bullet = DefaultOptions.Bullet
+	}
+	if opts.NestedBullet == "" {
+		opts.NestedBullet = DefaultOptions.NestedBullet
+	}
+	if opts.HeadingMark == "" {
+		opts.HeadingMark = DefaultOptions.HeadingMark
+	}
+
+	text := strings.ReplaceAll(markdown, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	text = brRe.ReplaceAllString(text, "\n")
+
+	var (
+		lines []string
+		code  []bool // 对应行是否在代码块内
+	)
+	inFence := false
+	for _, line := range strings.Split(text, "\n") {
+		if fenceRe.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			// 代码块原样保留
+			lines = append(lines, strings.TrimRight(line, " \t"))
+			code = append(code, true)
+			continue
+		}
+		if tableSepRe.MatchString(line) && strings.Contains(line, "|") {
+			// 表格分隔行直接去除
+			continue
+		}
+		lines = append(lines, formatLine(line, opts))
+		code = append(code, false)
+	}
+
+	result := Result{}
+	if !opts.KeepTags {
+		result.Tags = extractTags(lines, code)
+	}
+
+	text = normalizeLines(strings.Join(lines, "\n"))
+	if opts.MaxRunes > 0 {
+		text, result.Truncated = truncate(text, opts.MaxRunes)
+	}
+	result.Content = text
+	if result.Tags == nil {
+		result.Tags = []string{}
+	}
+
+	return result
+}
+
+func formatLine(line string, opts Options) string {
+	if ruleRe.MatchString(line) {
+		return ""
+	}
+
+	if m := headingRe.FindStringSubmatch(line); m != nil {
+		return opts.HeadingMark + " " + formatInline(m[1])
+	}
+
+	if m := taskRe.FindStringSubmatch(line); m != nil {
+		mark := "⬜"
+		if m[2] != " " {
+			mark = "✅"
+		}
+		return indent(m[1]) + mark + " " + formatInline(m[3])
+	}
+
+	if m := bulletRe.FindStringSubmatch(line); m != nil {
+		bullet := opts.Bullet
+		if len(m[1]) >= 2 {
+			bullet = opts.NestedBullet
+		}
+		return indent(m[1]) + bullet + " " + formatInline(m[2])
+	}
+
+	if m := orderedRe.FindStringSubmatch(line); m != nil {
+		return indent(m[1]) + numberMark(m[2]) + " " + formatInline(m[3])
+	}
+
+	if m := quoteRe.FindStringSubmatch(line); m != nil {
+		return formatInline(m[1])
+	}
+
+	if isTableRow(line) {
+		cells := strings.Split(strings.Trim(strings.TrimSpace(line), "|"), "|")
+		for i := range cells {
+			cells[i] = formatInline(strings.TrimSpace(cells[i]))
+		}
+		return strings.Join(cells, " | ")
+	}
+
+	return formatInline(line)
+}
+
+// formatInline 去除行内 Markdown 语法，保留文字
+func formatInline(s string) string {
+	// 转义字符先替换为私有区字符，避免被当作语法处理
+	s = escapeRe.ReplaceAllStringFunc(s, func(m string) string {
+		return string(rune(escapeBase + rune(m[1])))
+	})
+
+	s = imageRe.ReplaceAllString(s, "")
+	s = linkRe.ReplaceAllString(s, "$1")
+	s = autoLinkRe.ReplaceAllString(s, "$1")
+	s = codeRe.ReplaceAllString(s, "$1")
+	s = boldRe.ReplaceAllString(s, "$1$2")
+	s = italicRe.ReplaceAllString(s, "$1$2")
+	s = strikeRe.ReplaceAllString(s, "$1")
+	s = htmlTagRe.ReplaceAllString(s, "")
+	s = strings.Map(func(r rune) rune {
+		if r >= escapeBase && r < escapeBase+128 {
+			return r - escapeBase
+		}
+		return r
+	}, s)
+	return strings.TrimRight(s, " \t")
+}
+
+func isTableRow(line string) bool {
+	t := strings.TrimSpace(line)
+	return len(t) > 1 && strings.HasPrefix(t, "|") && strings.HasSuffix(t, "|")
+}
+
+// indent 嵌套列表每级缩进两个空格
+func indent(prefix string) string {
+	level := len(strings.ReplaceAll(prefix, "\t", "  ")) / 2
+	return strings.Repeat("  ", level)
+}
+
+func numberMark(num string) string {
+	n, err := strconv.Atoi(num)
+	if err == nil && n >= 0 && n < len(keycapNumbers) {
+		return keycapNumbers[n]
+	}
+	return num + "."
+}
+
+// extractTags 提取 #标签 和 #标签[话题]#，直接从 lines 中去除标签，返回去重后的标签。
+// 代码块内的行（code 为 true）原样保留，不提取标签
+func extractTags(lines []string, code []bool) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	add := func(tag string) {
+		tag = strings.TrimRight(tag, tagTrimChars)
+		if tag == "" || seen[strings.ToLower(tag)] {
+			return
+		}
+		seen[strings.ToLower(tag)] = true
+		tags = append(tags, tag)
+	}
+
+	// 先提取所有话题标签，再提取普通标签
+	for i, line := range lines {
+		if code[i] {
+			continue
+		}
+		lines[i] = topicRe.ReplaceAllStringFunc(line, func(m string) string {
+			add(topicRe.FindStringSubmatch(m)[1])
+			return ""
+		})
+	}
+
+	for i, line := range lines {
+		if code[i] {
+			continue
+		}
+		replaced := tagRe.ReplaceAllStringFunc(line, func(m string) string {
+			sub := tagRe.FindStringSubmatch(m)
+			tag := sub[2]
+			trimmed := strings.TrimRight(tag, tagTrimChars)
+			add(trimmed)
+			// 去掉标签及其前面的空白，保留标签后的标点
+			return tag[len(trimmed):]
+		})
+		if replaced != line && strings.HasPrefix(line, "#") {
+			replaced = strings.TrimLeft(replaced, " \t")
+		}
+		lines[i] = strings.TrimRight(replaced, " \t")
+	}
+
+	return tags
+}
+
+// normalizeLines 去除行尾空白，最多保留一个空行，去除首尾空行
+func normalizeLines(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	text = strings.Join(lines, "\n")
+	text = blankLinesRe.ReplaceAllString(text, "\n\n")
+	return strings.Trim(text, "\n")
+}
+
+// truncate 超过 maxRunes 时优先在段落或句子末尾截断，并追加省略号
+func truncate(text string, maxRunes int) (string, bool) {
+	runes := []rune(text)
+	if len(runes) <= maxRunes {
+		return text, false
+	}
+
+	limit := maxRunes - 1 // 预留省略号
+	cut := limit
+	for i := limit; i > limit/2; i-- {
+		if runes[i-1] == '\n' || strings.ContainsRune("。！？!?", runes[i-1]) {
+			cut = i
+			break
+		}
+	}
+
+	return strings.TrimRight(string(runes[:cut]), " \n") + "…", true
+}
@@ -0,0 +1,123 @@
+package mdformat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatBlocks(t *testing.T) {
+	md := "# 周末好去处\r\n\r\n" +
+		"这家店**真的**很赞，详见[官网](https://example.com)。\n\n\n\n" +
+		"## 推荐理由\n" +
+		"- 环境 *安静*\n" +
+		"  - 适合~~工作~~看书\n" +
+		"* 价格 `便宜`\n\n" +
+		"1. 先点招牌\n" +
+		"2) 再点甜品\n" +
+		"12. 第十二条\n\n" +
+		"- [x] 已打卡\n" +
+		"- [ ] 待打卡\n\n" +
+		"> 老板说：欢迎再来\n" +
+		"---\n" +
+		"![图](a.png)结束<br>谢谢\n"
+
+	got := Format(md)
+
+	want := strings.Join([]string{
+		"📌 周末好去处",
+		"",
+		"这家店真的很赞，详见官网。",
+		"",
+		"📌 推荐理由",
+		"🔸 环境 安静",
+		"  ▫️ 适合工作看书",
+		"🔸 价格 便宜",
+		"",
+		"1️⃣ 先点招牌",
+		"2️⃣ 再点甜品",
+		"12. 第十二条",
+		"",
+		"✅ 已打卡",
+		"⬜ 待打卡",
+		"",
+		"老板说：欢迎再来",
+		"",
+		"结束",
+		"谢谢",
+	}, "\n")
+	assert.Equal(t, want, got.Content)
+	assert.False(t, got.Truncated)
+	assert.Empty(t, got.Tags)
+}
+
+func TestFormatTable(t *testing.T) {
+	got := Format("| 菜品 | 价格 |\n| --- | :---: |\n| **拿铁** | 28 |")
+
+	assert.Equal(t, "菜品 | 价格\n拿铁 | 28", got.Content)
+}
+
+func TestFormatCodeFence(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		content  string
+		tags     []string
+	}{
+		{
+			name:     "代码块原样保留",
+			markdown: "示例：\n```go\nfmt.Println(\"**hi**\")\n```\n",
+			content:  "示例：\nfmt.Println(\"**hi**\")",
+			tags:     []string{},
+		},
+		{
+			name:     "代码块内的 # 不提取为标签",
+			markdown: "配置示例 #运维\n```bash\n#include <stdio.h>\necho hi # 注释\n```\n",
+			content:  "配置示例\n#include <stdio.h>\necho hi # 注释",
+			tags:     []string{"运维"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Format(tt.markdown)
+
+			assert.Equal(t, tt.content, got.Content)
+			assert.Equal(t, tt.tags, got.Tags)
+		})
+	}
+}
+
+func TestExtractTags(t *testing.T) {
+	got := Format("今天去了外滩 #上海旅行 #美食，\n#周末去哪儿[话题]# 推荐 #上海旅行\n# 标题不是标签")
+
+	assert.Equal(t, []string{"周末去哪儿", "上海旅行", "美食"}, got.Tags)
+	assert.Equal(t, "今天去了外滩，\n推荐\n📌 标题不是标签", got.Content)
+
+	kept := FormatWithOptions("好吃 #美食", Options{KeepTags: true})
+	assert.Equal(t, "好吃 #美食", kept.Content)
+	assert.Empty(t, kept.Tags)
+}
+
+func TestFormatEscapes(t *testing.T) {
+	got := Format(`价格 \*限时\* 5\_折 <b>粗体</b> <https://example.com>`)
+
+	assert.Equal(t, "价格 *限时* 5_折 粗体 https://example.com", got.Content)
+}
+
+func TestTruncate(t *testing.T) {
+	text := strings.Repeat("字", 40) + "。\n" + strings.Repeat("多", 40)
+
+	got := FormatWithOptions(text, Options{MaxRunes: 60})
+	assert.True(t, got.Truncated)
+	assert.Equal(t, strings.Repeat("字", 40)+"。…", got.Content)
+
+	// 找不到合适的断点时直接截断
+	got = FormatWithOptions(strings.Repeat("字", 100), Options{MaxRunes: 10})
+	assert.Equal(t, strings.Repeat("字", 9)+"…", got.Content)
+	assert.Len(t, []rune(got.Content), 10)
+
+	got = FormatWithOptions("短文本", Options{MaxRunes: 10})
+	assert.False(t, got.Truncated)
+}
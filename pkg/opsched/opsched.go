@@ -0,0 +1,245 @@
+// Package opsched 按账号调度浏览器操作，避免同一账号的操作互相干扰：
+// 读操作在并发上限内并行，写操作（发布、评论、点赞等）按先进先出顺序逐个执行，
+// 修改登录状态的独占操作（保存/删除 cookies）等待所有进行中的操作结束后才执行。
+package opsched
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Kind 操作类型
+type Kind string
+
+const (
+	Read      Kind = "read"      // 只读，如浏览、搜索、查看详情
+	Write     Kind = "write"     // 以账号身份执行的操作，同一账号同时只能有一个
+	Exclusive Kind = "exclusive" // 修改登录状态，执行时不能有其他任何操作
+)
+
+// DefaultMaxReads 每个账号默认的最大并发读操作数
+const DefaultMaxReads = 3
+
+// Op 一个排队或执行中的操作
+type Op struct {
+	ID         uint64     `json:"id"`
+	Name       string     `json:"name"`
+	Kind       Kind       `json:"kind"`
+	Position   int        `json:"position,omitempty"` // 排队位置，从 1 开始，执行中为 0
+	EnqueuedAt time.Time  `json:"enqueued_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+}
+
+// Status 账号的调度状态
+type Status struct {
+	Account string `json:"account"`
+	Running []Op   `json:"running"`
+	Queued  []Op   `json:"queued"`
+}
+
+// Scheduler 按账号调度操作
+type Scheduler struct {
+	mu       sync.Mutex
+	maxReads int
+	accounts map[string]*account
+	nextID   uint64
+	now      func() time.Time
+}
+
+type account struct {
+	reads     int
+	writing   bool
+	exclusive bool
+	running   []*Op
+	queue     []*waiter
+}
+
+type waiter struct {
+	op      *Op
+	ready   chan struct{} // 开始执行时关闭
+	changed chan struct{} // 排队位置变化时通知
+}
+
+// New 创建调度器，maxReads 小于 1 时使用 DefaultMaxReads
+func New(maxReads int) *Scheduler {
+	if maxReads < 1 {
+		maxReads = DefaultMaxReads
+	}
+	return &Scheduler{
+		maxReads: maxReads,
+		accounts: make(map[string]*account),
+		now:      time.Now,
+	}
+}
+
+type listenerKey struct{}
+
+// WithQueueListener 返回带有排队位置回调的 context，操作排队时及位置变化时回调
+func WithQueueListener(ctx context.Context, fn func(position int)) context.Context {
+	return context.WithValue(ctx, listenerKey{}, fn)
+}
+
+// Acquire 等待轮到该操作执行，返回的 release 必须在操作结束后调用。
+// ctx 取消时放弃排队并返回 ctx.Err()
+func (s *Scheduler) Acquire(ctx context.Context, accountID string, kind Kind, name string) (release func(), err error) {
+	s.mu.Lock()
+	acc, ok := s.accounts[accountID]
+	if !ok {
+		acc = &account{}
+		s.accounts[accountID] = acc
+	}
+
+	s.nextID++
+	w := &waiter{
+		op:      &Op{ID: s.nextID, Name: name, Kind: kind, EnqueuedAt: s.now()},
+		ready:   make(chan struct{}),
+		changed: make(chan struct{}, 1),
+	}
+	acc.queue = append(acc.queue, w)
+	s.dispatch(acc)
+	s.mu.Unlock()
+
+	release = func() { s.release(accountID, w.op) }
+
+	listener, _ := ctx.Value(listenerKey{}).(func(int))
+	position := 0
+	for {
+		s.mu.Lock()
+		started, current := w.op.StartedAt != nil, w.op.Position
+		s.mu.Unlock()
+		if started {
+			return release, nil
+		}
+		if current != position {
+			position = current
+			if listener != nil {
+				listener(position)
+			}
+		}
+
+		select {
+		case <-w.ready:
+		case <-w.changed:
+		case <-ctx.Done():
+			s.mu.Lock()
+			if w.op.StartedAt != nil {
+				// 取消和开始同时发生，已经占用了执行资格，需要归还
+				s.mu.Unlock()
+				release()
+				return nil, ctx.Err()
+			}
+			acc.queue = slices.DeleteFunc(acc.queue, func(x *waiter) bool { return x == w })
+			s.dispatch(acc)
+			s.mu.Unlock()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Status 返回账号当前执行中和排队中的操作
+func (s *Scheduler) Status(accountID string) Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := Status{Account: accountID, Running: []Op{}, Queued: []Op{}}
+	acc, ok := s.accounts[accountID]
+	if !ok {
+		return status
+	}
+	for _, op := range acc.running {
+		status.Running = append(status.Running, *op)
+	}
+	for _, w := range acc.queue {
+		status.Queued = append(status.Queued, *w.op)
+	}
+	return status
+}
+
+func (s *Scheduler) release(accountID string, op *Op) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	acc := s.accounts[accountID]
+	idx := slices.Index(acc.running, op)
+	if idx < 0 {
+		return // 重复调用 release
+	}
+	acc.running = slices.Delete(acc.running, idx, idx+1)
+
+	switch op.Kind {
+	case Read:
+		acc.reads--
+	case Write:
+		acc.writing = false
+	case Exclusive:
+		acc.exclusive = false
+	}
+	s.dispatch(acc)
+}
+
+// dispatch 按队列顺序启动可以执行的操作并更新排队位置，调用方需持有锁。
+//
+// 排在独占操作之后的操作都要等待；写操作之间保持先进先出；
+// 读操作不受写操作影响，只受并发上限限制。
+func (s *Scheduler) dispatch(acc *account) {
+	var (
+		blockAll    bool // 前面有等待中的独占操作
+		blockWrites bool // 前面有等待中的写操作
+		blockReads  bool // 前面有等待中的读操作
+	)
+
+	remaining := acc.queue[:0]
+	for _, w := range acc.queue {
+		start := false
+		if !blockAll && !acc.exclusive {
+			switch w.op.Kind {
+			case Read:
+				start = !blockReads && acc.reads < s.maxReads
+			case Write:
+				start = !blockWrites && !acc.writing
+			case Exclusive:
+				start = !blockWrites && !blockReads && acc.reads == 0 && !acc.writing
+			}
+		}
+
+		if !start {
+			switch w.op.Kind {
+			case Read:
+				blockReads = true
+			case Write:
+				blockWrites = true
+			case Exclusive:
+				blockAll = true
+			}
+			remaining = append(remaining, w)
+			if w.op.Position != len(remaining) {
+				w.op.Position = len(remaining)
+				select {
+				case w.changed <- struct{}{}:
+				default:
+				}
+			}
+			continue
+		}
+
+		switch w.op.Kind {
+		case Read:
+			acc.reads++
+		case Write:
+			acc.writing = true
+		case Exclusive:
+			acc.exclusive = true
+		}
+		now := s.now()
+		w.op.StartedAt = &now
+		w.op.Position = 0
+		acc.running = append(acc.running, w.op)
+		close(w.ready)
+	}
+
+	// 清空尾部引用，避免已开始的 waiter 无法回收
+	clear(acc.queue[len(remaining):])
+	acc.queue = remaining
+}
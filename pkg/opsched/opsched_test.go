@@ -0,0 +1,157 @@
+package opsched
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const acc = "account"
+
+// acquireAsync 在后台排队，返回开始执行时收到 release 的 channel
+func acquireAsync(s *Scheduler, ctx context.Context, kind Kind, name string) <-chan func() {
+	ch := make(chan func(), 1)
+	go func() {
+		release, err := s.Acquire(ctx, acc, kind, name)
+		if err == nil {
+			ch <- release
+		}
+	}()
+	return ch
+}
+
+func waitQueued(t *testing.T, s *Scheduler, n int) {
+	require.Eventually(t, func() bool { return len(s.Status(acc).Queued) == n }, time.Second, time.Millisecond)
+}
+
+func started(ch <-chan func()) (func(), bool) {
+	select {
+	case release := <-ch:
+		return release, true
+	case <-time.After(50 * time.Millisecond):
+		return nil, false
+	}
+}
+
+func TestReadsRunInParallelUpToLimit(t *testing.T) {
+	s := New(2)
+	ctx := context.Background()
+
+	r1, err := s.Acquire(ctx, acc, Read, "r1")
+	require.NoError(t, err)
+	r2, err := s.Acquire(ctx, acc, Read, "r2")
+	require.NoError(t, err)
+
+	r3 := acquireAsync(s, ctx, Read, "r3")
+	waitQueued(t, s, 1)
+
+	// 写操作不受读并发上限影响
+	w1, err := s.Acquire(ctx, acc, Write, "w1")
+	require.NoError(t, err)
+
+	r1()
+	release, ok := started(r3)
+	require.True(t, ok)
+
+	status := s.Status(acc)
+	assert.Len(t, status.Running, 3)
+	assert.Empty(t, status.Queued)
+
+	r2()
+	release()
+	w1()
+	assert.Empty(t, s.Status(acc).Running)
+}
+
+func TestWritesAreFIFO(t *testing.T) {
+	s := New(2)
+	ctx := context.Background()
+
+	w1, err := s.Acquire(ctx, acc, Write, "w1")
+	require.NoError(t, err)
+
+	w2 := acquireAsync(s, ctx, Write, "w2")
+	waitQueued(t, s, 1)
+
+	var positions []int
+	w3 := acquireAsync(s, WithQueueListener(ctx, func(p int) { positions = append(positions, p) }), Write, "w3")
+	waitQueued(t, s, 2)
+
+	queued := s.Status(acc).Queued
+	assert.Equal(t, "w2", queued[0].Name)
+	assert.Equal(t, 1, queued[0].Position)
+	assert.Equal(t, "w3", queued[1].Name)
+	assert.Equal(t, 2, queued[1].Position)
+
+	w1()
+	release2, ok := started(w2)
+	require.True(t, ok)
+	_, ok = started(w3)
+	assert.False(t, ok)
+
+	release2()
+	release3, ok := started(w3)
+	require.True(t, ok)
+	release3()
+	assert.Equal(t, []int{2, 1}, positions)
+}
+
+func TestExclusiveWaitsForInFlightAndBlocksNewOps(t *testing.T) {
+	s := New(2)
+	ctx := context.Background()
+
+	r1, err := s.Acquire(ctx, acc, Read, "r1")
+	require.NoError(t, err)
+	w1, err := s.Acquire(ctx, acc, Write, "w1")
+	require.NoError(t, err)
+
+	ex := acquireAsync(s, ctx, Exclusive, "delete_cookies")
+	waitQueued(t, s, 1)
+
+	// 独占操作之后的读写都要等待
+	r2 := acquireAsync(s, ctx, Read, "r2")
+	waitQueued(t, s, 2)
+
+	r1()
+	_, ok := started(ex)
+	assert.False(t, ok)
+
+	w1()
+	releaseEx, ok := started(ex)
+	require.True(t, ok)
+	_, ok = started(r2)
+	assert.False(t, ok)
+
+	releaseEx()
+	release2, ok := started(r2)
+	require.True(t, ok)
+	release2()
+}
+
+func TestCancelWhileQueued(t *testing.T) {
+	s := New(1)
+	w1, err := s.Acquire(context.Background(), acc, Write, "w1")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := s.Acquire(ctx, acc, Write, "w2")
+		errCh <- err
+	}()
+	waitQueued(t, s, 1)
+
+	cancel()
+	assert.ErrorIs(t, <-errCh, context.Canceled)
+	assert.Empty(t, s.Status(acc).Queued)
+
+	// 重复 release 不影响状态
+	w1()
+	w1()
+	w3, err := s.Acquire(context.Background(), acc, Write, "w3")
+	require.NoError(t, err)
+	w3()
+}
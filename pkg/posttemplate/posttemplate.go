@@ -0,0 +1,324 @@
+// Package posttemplate 管理可复用的笔记模板：标题、正文、标签和图片都支持 Go 模板语法。
+package posttemplate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrNotFound 模板不存在
+	ErrNotFound = errors.New("模板不存在")
+
+	namePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+)
+
+// Variable 模板变量声明
+type Variable struct {
+	Name        string `json:"name" jsonschema:"变量名，在模板中通过 {{.变量名}} 引用"`
+	Description string `json:"description,omitempty" jsonschema:"变量说明"`
+	Required    bool   `json:"required,omitempty" jsonschema:"是否必填"`
+	Default     any    `json:"default,omitempty" jsonschema:"默认值"`
+}
+
+// Template 笔记模板
+type Template struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Title       string     `json:"title"`
+	Content     string     `json:"content"`
+	Tags        []string   `json:"tags,omitempty"`   // 每一项渲染后按行拆分，空行忽略
+	Images      []string   `json:"images,omitempty"` // 图片槽位，每一项渲染后按行拆分，空行忽略
+	Markdown    bool       `json:"markdown,omitempty"`
+	Variables   []Variable `json:"variables,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Rendered 渲染后的笔记内容
+type Rendered struct {
+	Title    string   `json:"title"`
+	Content  string   `json:"content"`
+	Tags     []string `json:"tags"`
+	Images   []string `json:"images"`
+	Markdown bool     `json:"markdown"`
+}
+
+var funcs = template.FuncMap{
+	"join":  joinAny,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	"add":   func(a, b int) int { return a + b },
+	"now":   func(layout string) string { return time.Now().Format(layout) },
+	"default": func(def, v any) any {
+		if v == nil || v == "" {
+			return def
+		}
+		return v
+	},
+}
+
+// joinAny 拼接列表变量，变量来自 JSON 时类型为 []any
+func joinAny(list any, sep string) string {
+	switch v := list.(type) {
+	case nil:
+		return ""
+	case []string:
+		return strings.Join(v, sep)
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, fmt.Sprint(item))
+		}
+		return strings.Join(parts, sep)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func parse(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+}
+
+// Validate 校验模板名称和模板语法
+func (t *Template) Validate() error {
+	if !namePattern.MatchString(t.Name) {
+		return fmt.Errorf("模板名称只能包含字母、数字、下划线和中划线，长度 1-64: %q", t.Name)
+	}
+	if strings.TrimSpace(t.Title) == "" || strings.TrimSpace(t.Content) == "" {
+		return errors.New("模板标题和正文不能为空")
+	}
+
+	fields := map[string]string{"title": t.Title, "content": t.Content}
+	for i, tag := range t.Tags {
+		fields[fmt.Sprintf("tags[%d]", i)] = tag
+	}
+	for i, img := range t.Images {
+		fields[fmt.Sprintf("images[%d]", i)] = img
+	}
+	for field, text := range fields {
+		if _, err := parse(field, text); err != nil {
+			return fmt.Errorf("模板 %s 语法错误: %w", field, err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, v := range t.Variables {
+		if v.Name == "" || seen[v.Name] {
+			return fmt.Errorf("变量名为空或重复: %q", v.Name)
+		}
+		seen[v.Name] = true
+	}
+	return nil
+}
+
+// Render 使用变量渲染模板。声明了默认值的变量未提供时使用默认值，必填变量缺失时返回错误
+func (t *Template) Render(vars map[string]any) (*Rendered, error) {
+	data := make(map[string]any, len(vars)+len(t.Variables))
+	for _, v := range t.Variables {
+		if v.Default != nil {
+			data[v.Name] = v.Default
+		}
+	}
+	for k, v := range vars {
+		data[k] = v
+	}
+
+	var missing []string
+	for _, v := range t.Variables {
+		if _, ok := data[v.Name]; !ok {
+			if v.Required {
+				missing = append(missing, v.Name)
+			} else {
+				data[v.Name] = ""
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("缺少必填变量: %s", strings.Join(missing, ", "))
+	}
+
+	title, err := execute("title", t.Title, data)
+	if err != nil {
+		return nil, err
+	}
+	content, err := execute("content", t.Content, data)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := executeList("tags", t.Tags, data)
+	if err != nil {
+		return nil, err
+	}
+	images, err := executeList("images", t.Images, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Rendered{
+		Title:    strings.TrimSpace(title),
+		Content:  strings.TrimSpace(content),
+		Tags:     tags,
+		Images:   images,
+		Markdown: t.Markdown,
+	}, nil
+}
+
+func execute(name, text string, data map[string]any) (string, error) {
+	tpl, err := parse(name, text)
+	if err != nil {
+		return "", fmt.Errorf("模板 %s 语法错误: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("渲染模板 %s 失败: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// executeList 渲染列表字段，每一项渲染结果按行拆分，便于一个槽位展开为多张图片或多个标签
+func executeList(name string, items []string, data map[string]any) ([]string, error) {
+	out := []string{}
+	for i, item := range items {
+		text, err := execute(fmt.Sprintf("%s[%d]", name, i), item, data)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(text, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				out = append(out, line)
+			}
+		}
+	}
+	return out, nil
+}
+
+// Store 模板存储，每个模板保存为目录下的 <name>.json
+type Store struct {
+	mu  sync.Mutex
+	dir string
+	now func() time.Time
+}
+
+// NewStore 创建模板存储
+func NewStore(dir string) *Store {
+	return &Store{dir: dir, now: time.Now}
+}
+
+func (s *Store) path(name string) string {
+	return filepath.Join(s.dir, name+".json")
+}
+
+// List 返回全部模板，按名称排序
+func (s *Store) List() ([]*Template, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*Template{}, nil
+		}
+		return nil, errors.Wrap(err, "读取模板目录失败")
+	}
+
+	templates := []*Template{}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		t, err := s.Get(strings.TrimSuffix(e.Name(), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+// Get 按名称读取模板
+func (s *Store) Get(name string) (*Template, error) {
+	if !namePattern.MatchString(name) {
+		return nil, ErrNotFound
+	}
+
+	data, err := os.ReadFile(s.path(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrap(err, "读取模板失败")
+	}
+
+	var t Template
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, errors.Wrapf(err, "解析模板 %s 失败", name)
+	}
+	return &t, nil
+}
+
+// Save 创建或更新模板，返回保存后的模板
+func (s *Store) Save(t *Template) (*Template, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := *t
+	now := s.now()
+	saved.CreatedAt = now
+	if existing, err := s.Get(t.Name); err == nil {
+		saved.CreatedAt = existing.CreatedAt
+	}
+	saved.UpdatedAt = now
+
+	data, err := json.MarshalIndent(&saved, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "序列化模板失败")
+	}
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "创建模板目录失败")
+	}
+
+	tmp := s.path(t.Name) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return nil, errors.Wrap(err, "保存模板失败")
+	}
+	if err := os.Rename(tmp, s.path(t.Name)); err != nil {
+		return nil, errors.Wrap(err, "保存模板失败")
+	}
+	return &saved, nil
+}
+
+// Delete 删除模板
+func (s *Store) Delete(name string) error {
+	if !namePattern.MatchString(name) {
+		return ErrNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(s.path(name)); err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotFound
+		}
+		return errors.Wrap(err, "删除模板失败")
+	}
+	return nil
+}
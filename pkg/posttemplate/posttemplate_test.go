@@ -0,0 +1,125 @@
+package posttemplate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func roundupTemplate() *Template {
+	return &Template{
+		Name:    "weekly-roundup",
+		Title:   "第{{.week}}周好物分享",
+		Content: "本周推荐 {{len .products}} 款好物：\n{{range $i, $p := .products}}{{add $i 1}}. {{$p}}\n{{end}}{{.outro | default \"下周见\"}}",
+		Tags:    []string{"好物分享", "{{.category}}"},
+		Images:  []string{"{{.cover}}", "{{join .photos \"\\n\"}}"},
+		Variables: []Variable{
+			{Name: "week", Required: true},
+			{Name: "products", Required: true},
+			{Name: "category", Default: "生活"},
+			{Name: "cover", Required: true},
+			{Name: "photos"},
+			{Name: "outro"},
+		},
+	}
+}
+
+func TestRender(t *testing.T) {
+	tpl := roundupTemplate()
+	require.NoError(t, tpl.Validate())
+
+	got, err := tpl.Render(map[string]any{
+		"week":     12,
+		"products": []any{"保温杯", "帆布包"},
+		"cover":    "/tmp/cover.jpg",
+		"photos":   []any{"/tmp/1.jpg", "/tmp/2.jpg"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "第12周好物分享", got.Title)
+	assert.Equal(t, "本周推荐 2 款好物：\n1. 保温杯\n2. 帆布包\n下周见", got.Content)
+	assert.Equal(t, []string{"好物分享", "生活"}, got.Tags)
+	assert.Equal(t, []string{"/tmp/cover.jpg", "/tmp/1.jpg", "/tmp/2.jpg"}, got.Images)
+}
+
+func TestRenderMissingVariables(t *testing.T) {
+	tpl := roundupTemplate()
+
+	_, err := tpl.Render(map[string]any{"week": 1})
+	assert.ErrorContains(t, err, "products")
+	assert.ErrorContains(t, err, "cover")
+
+	// 未声明也未提供的变量
+	tpl = &Template{Name: "x", Title: "{{.unknown}}", Content: "正文"}
+	_, err = tpl.Render(nil)
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	cases := []*Template{
+		{Name: "../etc", Title: "t", Content: "c"},
+		{Name: "ok", Title: "", Content: "c"},
+		{Name: "ok", Title: "{{.a", Content: "c"},
+		{Name: "ok", Title: "t", Content: "c", Images: []string{"{{end}}"}},
+		{Name: "ok", Title: "t", Content: "c", Variables: []Variable{{Name: "a"}, {Name: "a"}}},
+	}
+	for _, c := range cases {
+		assert.Error(t, c.Validate(), c)
+	}
+}
+
+func TestStoreCRUD(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "templates")
+	s := NewStore(dir)
+
+	list, err := s.List()
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return created }
+
+	saved, err := s.Save(roundupTemplate())
+	require.NoError(t, err)
+	assert.Equal(t, created, saved.CreatedAt)
+
+	updated := created.Add(time.Hour)
+	s.now = func() time.Time { return updated }
+
+	tpl := roundupTemplate()
+	tpl.Description = "每周好物"
+	saved, err = s.Save(tpl)
+	require.NoError(t, err)
+	assert.Equal(t, created, saved.CreatedAt)
+	assert.Equal(t, updated, saved.UpdatedAt)
+
+	_, err = s.Save(&Template{Name: "event-recap", Title: "{{.name}}回顾", Content: "正文"})
+	require.NoError(t, err)
+
+	got, err := s.Get("weekly-roundup")
+	require.NoError(t, err)
+	assert.Equal(t, "每周好物", got.Description)
+
+	list, err = s.List()
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "event-recap", list[0].Name)
+
+	require.NoError(t, s.Delete("event-recap"))
+	assert.ErrorIs(t, s.Delete("event-recap"), ErrNotFound)
+
+	_, err = s.Get("event-recap")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.Get("../secret")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// 无效模板不会写入
+	_, err = s.Save(&Template{Name: "bad", Title: "{{", Content: "c"})
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(dir, "bad.json"))
+	assert.True(t, os.IsNotExist(err))
+}
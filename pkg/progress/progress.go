@@ -0,0 +1,63 @@
+// Package progress 通过 context 传递长时间操作的进度回调：
+// 服务层按阶段上报进度，MCP 层将其转换为 notifications/progress 发送给客户端。
+package progress
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// Func 进度回调，progress 单调递增，total 为总阶段数
+type Func func(progress, total float64, message string)
+
+type funcKey struct{}
+
+// WithFunc 返回带有进度回调的 context
+func WithFunc(ctx context.Context, fn Func) context.Context {
+	return context.WithValue(ctx, funcKey{}, fn)
+}
+
+// Tracker 按阶段上报一个操作的进度，ctx 中没有进度回调时不做任何事
+type Tracker struct {
+	mu       sync.Mutex
+	fn       Func
+	total    float64
+	progress float64
+}
+
+// Start 开始跟踪有 total 个阶段的操作
+func Start(ctx context.Context, total int) *Tracker {
+	fn, _ := ctx.Value(funcKey{}).(Func)
+	return &Tracker{fn: fn, total: float64(total)}
+}
+
+// Step 进入下一个阶段
+func (t *Tracker) Step(message string) {
+	t.mu.Lock()
+	t.progress = math.Min(math.Floor(t.progress)+1, t.total)
+	t.report(message)
+}
+
+// Stepf 进入下一个阶段，message 按 fmt.Sprintf 格式化
+func (t *Tracker) Stepf(format string, args ...any) {
+	t.Step(fmt.Sprintf(format, args...))
+}
+
+// Queued 排队位置变化时上报，在当前阶段内推进一半剩余进度，保证进度单调递增
+func (t *Tracker) Queued(position int) {
+	t.mu.Lock()
+	t.progress += (math.Floor(t.progress) + 1 - t.progress) / 2
+	t.report(fmt.Sprintf("排队中，第 %d 位", position))
+}
+
+// report 释放锁后回调，调用方需持有锁
+func (t *Tracker) report(message string) {
+	progress, total := t.progress, t.total
+	t.mu.Unlock()
+
+	if t.fn != nil {
+		t.fn(progress, total, message)
+	}
+}
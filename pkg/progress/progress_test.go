@@ -0,0 +1,57 @@
+package progress
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type event struct {
+	progress, total float64
+	message         string
+}
+
+func record(events *[]event) context.Context {
+	return WithFunc(context.Background(), func(progress, total float64, message string) {
+		*events = append(*events, event{progress, total, message})
+	})
+}
+
+func TestSteps(t *testing.T) {
+	var events []event
+	tr := Start(record(&events), 3)
+	tr.Step("处理图片")
+	tr.Stepf("上传 %d 张图片", 2)
+	tr.Step("完成")
+
+	assert.Equal(t, []event{
+		{1, 3, "处理图片"},
+		{2, 3, "上传 2 张图片"},
+		{3, 3, "完成"},
+	}, events)
+}
+
+func TestQueuedStaysWithinStage(t *testing.T) {
+	var events []event
+	tr := Start(record(&events), 3)
+	tr.Step("等待执行")
+	tr.Queued(3)
+	tr.Queued(2)
+	tr.Step("打开页面")
+
+	assert.Equal(t, []event{
+		{1, 3, "等待执行"},
+		{1.5, 3, "排队中，第 3 位"},
+		{1.75, 3, "排队中，第 2 位"},
+		{2, 3, "打开页面"},
+	}, events)
+}
+
+func TestWithoutFunc(t *testing.T) {
+	tr := Start(context.Background(), 2)
+	assert.NotPanics(t, func() {
+		tr.Step("打开页面")
+		tr.Queued(1)
+	})
+}
@@ -0,0 +1,330 @@
+// Package publishlog 记录发布历史，用于幂等键重放和重复内容检测。
+package publishlog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Status 发布记录状态
+type Status string
+
+const (
+	StatusPending   Status = "pending"   // 发布中
+	StatusPublished Status = "published" // 发布成功
+	StatusFailed    Status = "failed"    // 发布失败，可以重试
+	StatusUnknown   Status = "unknown"   // 发布中途服务退出或点击发布后出错，结果未知
+)
+
+// DefaultRetention 记录保留时长，超过后自动清理
+const DefaultRetention = 30 * 24 * time.Hour
+
+var (
+	// ErrInProgress 相同幂等键的发布正在进行
+	ErrInProgress = errors.New("相同幂等键的发布正在进行中")
+	// ErrKeyConflict 幂等键已用于不同的内容
+	ErrKeyConflict = errors.New("幂等键已用于不同的内容")
+)
+
+// DuplicateError 发布的内容与已有记录重复
+type DuplicateError struct {
+	Record *Record
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("相同内容已于 %s 发布（状态: %s），如需重复发布请设置 force",
+		e.Record.CreatedAt.Format(time.DateTime), e.Record.Status)
+}
+
+// Record 一次发布的记录
+type Record struct {
+	ID             string          `json:"id"`
+	IdempotencyKey string          `json:"idempotency_key,omitempty"`
+	ContentHash    string          `json:"content_hash"`
+	Kind           string          `json:"kind"` // image / video
+	Title          string          `json:"title"`
+	Status         Status          `json:"status"`
+	NoteID         string          `json:"note_id,omitempty"`
+	Result         json.RawMessage `json:"result,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// Entry 开始一次发布时的参数
+type Entry struct {
+	IdempotencyKey string
+	ContentHash    string
+	Kind           string
+	Title          string
+	Window         time.Duration // 重复内容检测窗口，0 表示不检测
+	Force          bool          // 忽略重复内容检测
+}
+
+// Store 发布记录存储，path 为空时只保存在内存中
+type Store struct {
+	mu        sync.Mutex
+	path      string
+	retention time.Duration
+	records   []*Record
+	now       func() time.Time
+}
+
+// Open 打开发布记录文件，文件不存在时创建空记录。
+// 上次退出时仍处于 pending 的记录会被标记为 unknown。
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, retention: DefaultRetention, now: time.Now}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, errors.Wrap(err, "读取发布记录失败")
+	}
+
+	if err := json.Unmarshal(data, &s.records); err != nil {
+		return nil, errors.Wrap(err, "解析发布记录失败")
+	}
+
+	for _, r := range s.records {
+		if r.Status == StatusPending {
+			r.Status = StatusUnknown
+		}
+	}
+	return s, nil
+}
+
+// Begin 开始一次发布。
+//
+// 幂等键已有成功记录时返回该记录（replay 为 true），调用方应直接返回记录中的结果；
+// 否则创建一条 pending 记录，发布结束后需调用 Complete 或 Fail。
+func (s *Store) Begin(e Entry) (rec *Record, replay bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.prune(now)
+
+	if e.IdempotencyKey != "" {
+		if existing := s.findByKey(e.IdempotencyKey); existing != nil {
+			if existing.ContentHash != e.ContentHash {
+				return nil, false, ErrKeyConflict
+			}
+
+			switch existing.Status {
+			case StatusPublished:
+				return existing, true, nil
+			case StatusPending:
+				return nil, false, ErrInProgress
+			case StatusUnknown:
+				if !e.Force {
+					return nil, false, &DuplicateError{Record: existing}
+				}
+			}
+
+			// 失败或强制重试，复用原记录
+			existing.Status = StatusPending
+			existing.Error = ""
+			existing.UpdatedAt = now
+			return existing, false, s.save()
+		}
+	}
+
+	if !e.Force && e.Window > 0 {
+		if dup := s.findRecentByHash(e.ContentHash, now.Add(-e.Window)); dup != nil {
+			return nil, false, &DuplicateError{Record: dup}
+		}
+	}
+
+	rec = &Record{
+		ID:             newID(now, e.ContentHash),
+		IdempotencyKey: e.IdempotencyKey,
+		ContentHash:    e.ContentHash,
+		Kind:           e.Kind,
+		Title:          e.Title,
+		Status:         StatusPending,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	s.records = append(s.records, rec)
+
+	return rec, false, s.save()
+}
+
+// Complete 标记发布成功并保存结果
+func (s *Store) Complete(rec *Record, noteID string, result any) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return errors.Wrap(err, "序列化发布结果失败")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec.Status = StatusPublished
+	rec.NoteID = noteID
+	rec.Result = data
+	rec.UpdatedAt = s.now()
+	return s.save()
+}
+
+// Fail 标记发布失败，相同幂等键或相同内容可以重试
+func (s *Store) Fail(rec *Record, cause error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec.Status = StatusFailed
+	if cause != nil {
+		rec.Error = cause.Error()
+	}
+	rec.UpdatedAt = s.now()
+	return s.save()
+}
+
+// Unknown 标记发布结果未知（如点击发布按钮后出错），笔记可能已经发布。
+// 与服务中途退出的记录一样，相同幂等键需要 force 才能重试，相同内容仍参与重复检测
+func (s *Store) Unknown(rec *Record, cause error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec.Status = StatusUnknown
+	if cause != nil {
+		rec.Error = cause.Error()
+	}
+	rec.UpdatedAt = s.now()
+	return s.save()
+}
+
+// Get 按幂等键查找记录
+func (s *Store) Get(key string) (*Record, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.findByKey(key)
+	return r, r != nil
+}
+
+func (s *Store) findByKey(key string) *Record {
+	for _, r := range s.records {
+		if r.IdempotencyKey == key {
+			return r
+		}
+	}
+	return nil
+}
+
+// findRecentByHash 查找窗口内相同内容且未失败的记录，结果未知的记录同样视为重复
+func (s *Store) findRecentByHash(hash string, since time.Time) *Record {
+	for i := len(s.records) - 1; i >= 0; i-- {
+		r := s.records[i]
+		if r.ContentHash != hash || r.Status == StatusFailed {
+			continue
+		}
+		if r.CreatedAt.After(since) {
+			return r
+		}
+	}
+	return nil
+}
+
+func (s *Store) prune(now time.Time) {
+	kept := s.records[:0]
+	for _, r := range s.records {
+		if r.Status == StatusPending || now.Sub(r.UpdatedAt) < s.retention {
+			kept = append(kept, r)
+		}
+	}
+	s.records = kept
+}
+
+// save 先写临时文件再重命名，避免写入中途退出导致文件损坏
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.records, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "序列化发布记录失败")
+	}
+
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return errors.Wrap(err, "创建发布记录目录失败")
+		}
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return errors.Wrap(err, "写入发布记录失败")
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func newID(now time.Time, hash string) string {
+	return fmt.Sprintf("%d-%s", now.UnixNano(), hash[:min(8, len(hash))])
+}
+
+// HashContent 计算标题、正文和媒体文件摘要的内容哈希
+func HashContent(title, content string, mediaDigests ...string) string {
+	h := sha256.New()
+	io.WriteString(h, title)
+	h.Write([]byte{0})
+	io.WriteString(h, content)
+	for _, d := range mediaDigests {
+		h.Write([]byte{0})
+		io.WriteString(h, d)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// quickDigestThreshold 超过该大小的文件只对首尾部分计算摘要，避免大视频耗时过长
+const quickDigestThreshold = 16 << 20
+
+// FileDigest 计算文件内容摘要。大文件使用文件大小加首尾各 4MB 计算
+func FileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	if info.Size() <= quickDigestThreshold {
+		if _, err := io.Copy(h, f); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	const part = quickDigestThreshold / 4
+	fmt.Fprintf(h, "%d:", info.Size())
+	if _, err := io.CopyN(h, f, part); err != nil {
+		return "", err
+	}
+	if _, err := f.Seek(-part, io.SeekEnd); err != nil {
+		return "", err
+	}
+	if _, err := io.CopyN(h, f, part); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
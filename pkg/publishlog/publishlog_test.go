@@ -0,0 +1,242 @@
+package publishlog
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func newTestStore(t *testing.T) (*Store, *fakeClock, string) {
+	path := filepath.Join(t.TempDir(), "records.json")
+	s, err := Open(path)
+	require.NoError(t, err)
+
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	s.now = clock.now
+	return s, clock, path
+}
+
+func TestIdempotencyKeyReplay(t *testing.T) {
+	s, _, _ := newTestStore(t)
+	entry := Entry{IdempotencyKey: "k1", ContentHash: "hash1", Kind: "image", Title: "标题"}
+
+	rec, replay, err := s.Begin(entry)
+	require.NoError(t, err)
+	assert.False(t, replay)
+	assert.Equal(t, StatusPending, rec.Status)
+
+	// 发布进行中重试
+	_, _, err = s.Begin(entry)
+	assert.ErrorIs(t, err, ErrInProgress)
+
+	require.NoError(t, s.Complete(rec, "note1", map[string]string{"status": "ok"}))
+
+	got, replay, err := s.Begin(entry)
+	require.NoError(t, err)
+	assert.True(t, replay)
+	assert.Equal(t, "note1", got.NoteID)
+	assert.JSONEq(t, `{"status":"ok"}`, string(got.Result))
+
+	// 相同幂等键、不同内容
+	_, _, err = s.Begin(Entry{IdempotencyKey: "k1", ContentHash: "hash2"})
+	assert.ErrorIs(t, err, ErrKeyConflict)
+}
+
+func TestFailedRecordCanRetry(t *testing.T) {
+	s, _, _ := newTestStore(t)
+	entry := Entry{IdempotencyKey: "k1", ContentHash: "hash1", Window: time.Hour}
+
+	rec, _, err := s.Begin(entry)
+	require.NoError(t, err)
+	require.NoError(t, s.Fail(rec, errors.New("timeout")))
+	assert.Equal(t, "timeout", rec.Error)
+
+	retry, replay, err := s.Begin(entry)
+	require.NoError(t, err)
+	assert.False(t, replay)
+	assert.Same(t, rec, retry)
+	assert.Equal(t, StatusPending, retry.Status)
+	assert.Empty(t, retry.Error)
+}
+
+func TestDuplicateContentWindow(t *testing.T) {
+	tests := []struct {
+		name    string
+		advance time.Duration
+		force   bool
+		wantDup bool
+	}{
+		{name: "窗口内重复", advance: 30 * time.Minute, wantDup: true},
+		{name: "窗口内强制发布", advance: 30 * time.Minute, force: true},
+		{name: "超出窗口", advance: 2 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, clock, _ := newTestStore(t)
+			entry := Entry{ContentHash: "hash1", Window: time.Hour}
+
+			rec, _, err := s.Begin(entry)
+			require.NoError(t, err)
+			require.NoError(t, s.Complete(rec, "", nil))
+
+			clock.t = clock.t.Add(tt.advance)
+			entry.Force = tt.force
+			_, _, err = s.Begin(entry)
+			if !tt.wantDup {
+				assert.NoError(t, err)
+				return
+			}
+			var dupErr *DuplicateError
+			require.ErrorAs(t, err, &dupErr)
+			assert.Equal(t, rec.ID, dupErr.Record.ID)
+		})
+	}
+}
+
+func TestFinishedContentDuplicate(t *testing.T) {
+	tests := []struct {
+		name      string
+		finish    func(s *Store, rec *Record) error
+		duplicate bool
+	}{
+		{
+			name:   "点击发布前失败可以重新发布",
+			finish: func(s *Store, rec *Record) error { return s.Fail(rec, nil) },
+		},
+		{
+			name:      "点击发布后出错结果未知，视为重复",
+			finish:    func(s *Store, rec *Record) error { return s.Unknown(rec, errors.New("context canceled")) },
+			duplicate: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, _ := newTestStore(t)
+			entry := Entry{ContentHash: "hash1", Window: time.Hour}
+
+			rec, _, err := s.Begin(entry)
+			require.NoError(t, err)
+			require.NoError(t, tt.finish(s, rec))
+
+			_, _, err = s.Begin(entry)
+			if !tt.duplicate {
+				assert.NoError(t, err)
+				return
+			}
+			var dup *DuplicateError
+			require.ErrorAs(t, err, &dup)
+			assert.Equal(t, StatusUnknown, dup.Record.Status)
+			assert.Equal(t, "context canceled", dup.Record.Error)
+
+			entry.Force = true
+			_, _, err = s.Begin(entry)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestPersistAndRecoverPending(t *testing.T) {
+	s, clock, path := newTestStore(t)
+
+	rec, _, err := s.Begin(Entry{IdempotencyKey: "done", ContentHash: "h1"})
+	require.NoError(t, err)
+	require.NoError(t, s.Complete(rec, "note1", "ok"))
+	_, _, err = s.Begin(Entry{IdempotencyKey: "crashed", ContentHash: "h2"})
+	require.NoError(t, err)
+
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	reopened.now = clock.now
+
+	done, ok := reopened.Get("done")
+	require.True(t, ok)
+	assert.Equal(t, StatusPublished, done.Status)
+
+	crashed, ok := reopened.Get("crashed")
+	require.True(t, ok)
+	assert.Equal(t, StatusUnknown, crashed.Status)
+
+	// 结果未知时需要 force 才能重试
+	clock.t = clock.t.Add(time.Minute)
+	_, _, err = reopened.Begin(Entry{IdempotencyKey: "crashed", ContentHash: "h2"})
+	var dupErr *DuplicateError
+	require.ErrorAs(t, err, &dupErr)
+
+	_, _, err = reopened.Begin(Entry{IdempotencyKey: "crashed", ContentHash: "h2", Force: true})
+	assert.NoError(t, err)
+}
+
+func TestPruneOldRecords(t *testing.T) {
+	s, clock, path := newTestStore(t)
+
+	rec, _, err := s.Begin(Entry{IdempotencyKey: "old", ContentHash: "h1"})
+	require.NoError(t, err)
+	require.NoError(t, s.Complete(rec, "", nil))
+
+	clock.t = clock.t.Add(DefaultRetention + time.Hour)
+	_, _, err = s.Begin(Entry{IdempotencyKey: "new", ContentHash: "h2"})
+	require.NoError(t, err)
+
+	_, ok := s.Get("old")
+	assert.False(t, ok)
+
+	var saved []*Record
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &saved))
+	assert.Len(t, saved, 1)
+}
+
+func TestMemoryStore(t *testing.T) {
+	s, err := Open("")
+	require.NoError(t, err)
+
+	_, _, err = s.Begin(Entry{IdempotencyKey: "k", ContentHash: "h"})
+	assert.NoError(t, err)
+}
+
+func TestHashContentAndFileDigest(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.jpg")
+	b := filepath.Join(dir, "b.jpg")
+	require.NoError(t, os.WriteFile(a, []byte("same"), 0644))
+	require.NoError(t, os.WriteFile(b, []byte("same"), 0644))
+
+	da, err := FileDigest(a)
+	require.NoError(t, err)
+	db, err := FileDigest(b)
+	require.NoError(t, err)
+	assert.Equal(t, da, db)
+
+	assert.Equal(t, HashContent("t", "c", da), HashContent("t", "c", db))
+	assert.NotEqual(t, HashContent("t", "c", da), HashContent("t", "c"))
+	assert.NotEqual(t, HashContent("tc", ""), HashContent("t", "c"))
+}
+
+func TestFileDigestLargeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.mp4")
+	data := make([]byte, quickDigestThreshold+1024)
+	require.NoError(t, os.WriteFile(path, data, 0644))
+
+	d1, err := FileDigest(path)
+	require.NoError(t, err)
+
+	// 修改首部内容后摘要变化
+	data[0] = 1
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	d2, err := FileDigest(path)
+	require.NoError(t, err)
+	assert.NotEqual(t, d1, d2)
+}
@@ -0,0 +1,321 @@
+// Package ratelimit 按账号和操作类型限制发布、评论、点赞等操作的频率，
+// 使用令牌桶控制窗口内的总次数，并在两次操作之间加入随机的最小间隔，模拟真人操作节奏。
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Action 受限制的操作类型
+type Action string
+
+const (
+	ActionPublish  Action = "publish"  // 发布图文/视频
+	ActionComment  Action = "comment"  // 发表评论
+	ActionLike     Action = "like"     // 点赞/取消点赞
+	ActionFavorite Action = "favorite" // 收藏/取消收藏
+)
+
+// Duration 支持 "10m"、"24h" 格式的 JSON 时长
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return errors.New("时长应为字符串，如 \"10m\"")
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Budget 一类操作的频率限制
+type Budget struct {
+	Limit  int      `json:"limit"`   // 每个窗口最多的次数，0 表示不限制次数
+	Per    Duration `json:"per"`     // 窗口长度，令牌按 Limit/Per 的速度恢复
+	MinGap Duration `json:"min_gap"` // 与上一次同类操作的最小间隔
+	Jitter Duration `json:"jitter"`  // 在最小间隔上额外增加 [0, Jitter) 的随机间隔
+}
+
+// DefaultBudgets 默认限制，偏保守
+var DefaultBudgets = map[Action]Budget{
+	ActionPublish:  {Limit: 10, Per: Duration(24 * time.Hour), MinGap: Duration(3 * time.Minute), Jitter: Duration(2 * time.Minute)},
+	ActionComment:  {Limit: 30, Per: Duration(24 * time.Hour), MinGap: Duration(30 * time.Second), Jitter: Duration(time.Minute)},
+	ActionLike:     {Limit: 30, Per: Duration(time.Hour), MinGap: Duration(5 * time.Second), Jitter: Duration(10 * time.Second)},
+	ActionFavorite: {Limit: 30, Per: Duration(time.Hour), MinGap: Duration(5 * time.Second), Jitter: Duration(10 * time.Second)},
+}
+
+// Error 操作超过频率限制
+type Error struct {
+	Action     Action        `json:"action"`
+	Reason     string        `json:"reason"`
+	RetryAfter time.Duration `json:"-"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("操作过于频繁（%s: %s），请在 %s 后重试", e.Action, e.Reason, e.RetryAfter.Round(time.Second))
+}
+
+// RetryAfterSeconds 向上取整的重试等待秒数
+func (e *Error) RetryAfterSeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+// Limiter 按账号和操作类型限流，path 不为空时状态保存到文件，重启后额度不会重置
+type Limiter struct {
+	mu      sync.Mutex
+	path    string
+	budgets map[Action]Budget
+	states  map[stateKey]*state
+
+	now    func() time.Time
+	jitter func(max time.Duration) time.Duration
+}
+
+type stateKey struct {
+	account string
+	action  Action
+}
+
+// state 令牌桶按 GCRA 实现：tat 为令牌桶恢复满之前的理论时间，与浮点计数相比没有精度问题
+type state struct {
+	tat    time.Time
+	nextAt time.Time // 最小间隔结束的时间
+}
+
+// savedState 保存到文件的状态
+type savedState struct {
+	Account string    `json:"account"`
+	Action  Action    `json:"action"`
+	TAT     time.Time `json:"tat"`
+	NextAt  time.Time `json:"next_at"`
+}
+
+// New 创建限流器，budgets 中未配置的操作不限制
+func New(budgets map[Action]Budget) *Limiter {
+	return &Limiter{
+		budgets: budgets,
+		states:  make(map[stateKey]*state),
+		now:     time.Now,
+		jitter: func(max time.Duration) time.Duration {
+			if max <= 0 {
+				return 0
+			}
+			return rand.N(max)
+		},
+	}
+}
+
+// Open 创建限流器并从 path 恢复上次保存的状态，文件不存在时从空状态开始
+func Open(path string, budgets map[Action]Budget) (*Limiter, error) {
+	l := New(budgets)
+	l.path = path
+	if path == "" {
+		return l, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return l, nil
+		}
+		return nil, errors.Wrap(err, "读取频率限制状态失败")
+	}
+
+	var saved []savedState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, errors.Wrap(err, "解析频率限制状态失败")
+	}
+	for _, st := range saved {
+		l.states[stateKey{account: st.Account, action: st.Action}] = &state{tat: st.TAT, nextAt: st.NextAt}
+	}
+	return l, nil
+}
+
+// Reservation 一次已占用的操作额度
+type Reservation struct {
+	l          *Limiter
+	key        stateKey
+	interval   time.Duration // 占用的令牌对应的时长
+	prevNextAt time.Time
+	nextAt     time.Time
+}
+
+// Allow 检查并占用一次操作额度，超过限制时返回 *Error
+func (l *Limiter) Allow(account string, action Action) error {
+	_, err := l.Reserve(account, action)
+	return err
+}
+
+// Reserve 与 Allow 相同，同时返回占用的额度。操作在真正执行前失败时调用 Cancel 归还额度。
+// 限流器为空或操作不受限制时返回 nil
+func (l *Limiter) Reserve(account string, action Action) (*Reservation, error) {
+	if l == nil {
+		return nil, nil
+	}
+	budget, ok := l.budgets[action]
+	if !ok {
+		return nil, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	key := stateKey{account: account, action: action}
+	st, ok := l.states[key]
+	if !ok {
+		st = &state{}
+		l.states[key] = st
+	}
+
+	if now.Before(st.nextAt) {
+		return nil, &Error{
+			Action:     action,
+			Reason:     "距离上一次操作太近",
+			RetryAfter: st.nextAt.Sub(now),
+		}
+	}
+
+	var (
+		tat      time.Time
+		interval time.Duration
+	)
+	if budget.Limit > 0 && budget.Per > 0 {
+		interval = time.Duration(budget.Per) / time.Duration(budget.Limit) // 恢复一个令牌的时间
+		tat = st.tat
+		if tat.Before(now) {
+			tat = now
+		}
+		tat = tat.Add(interval)
+
+		if over := tat.Sub(now) - time.Duration(budget.Per); over > 0 {
+			return nil, &Error{
+				Action:     action,
+				Reason:     fmt.Sprintf("超过每 %s %d 次的限制", time.Duration(budget.Per), budget.Limit),
+				RetryAfter: over,
+			}
+		}
+	}
+
+	r := &Reservation{l: l, key: key, interval: interval, prevNextAt: st.nextAt}
+
+	st.tat = tat
+	st.nextAt = now.Add(time.Duration(budget.MinGap) + l.jitter(time.Duration(budget.Jitter)))
+	r.nextAt = st.nextAt
+
+	l.save(now)
+	return r, nil
+}
+
+// Cancel 归还占用的令牌。之后没有新的同类操作时同时取消最小间隔，可以立即重试
+func (r *Reservation) Cancel() {
+	if r == nil {
+		return
+	}
+	l := r.l
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	st, ok := l.states[r.key]
+	if !ok {
+		return
+	}
+	if r.interval > 0 {
+		st.tat = st.tat.Add(-r.interval)
+	}
+	if st.nextAt.Equal(r.nextAt) {
+		st.nextAt = r.prevNextAt
+	}
+	r.interval = 0 // 重复调用不会多归还
+
+	l.save(l.now())
+}
+
+// save 保存未恢复的状态，先写临时文件再重命名。保存失败只影响重启后的额度，不影响本次操作
+func (l *Limiter) save(now time.Time) {
+	if l.path == "" {
+		return
+	}
+
+	saved := make([]savedState, 0, len(l.states))
+	for key, st := range l.states {
+		if !st.tat.After(now) && !st.nextAt.After(now) {
+			continue // 额度已恢复满，不需要保存
+		}
+		saved = append(saved, savedState{Account: key.account, Action: key.action, TAT: st.tat, NextAt: st.nextAt})
+	}
+
+	err := func() error {
+		data, err := json.MarshalIndent(saved, "", "  ")
+		if err != nil {
+			return err
+		}
+		if dir := filepath.Dir(l.path); dir != "" {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+		}
+		tmp := l.path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0644); err != nil {
+			return err
+		}
+		return os.Rename(tmp, l.path)
+	}()
+	if err != nil {
+		logrus.Warnf("保存频率限制状态失败: %v", err)
+	}
+}
+
+// LoadBudgets 从 JSON 文件加载限制并覆盖默认值，limit 为 0 且未设置间隔表示不限制该操作：
+//
+//	{"publish": {"limit": 5, "per": "24h", "min_gap": "10m", "jitter": "5m"}}
+func LoadBudgets(path string) (map[Action]Budget, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "读取频率限制配置失败")
+	}
+
+	var overrides map[Action]Budget
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, errors.Wrap(err, "解析频率限制配置失败")
+	}
+
+	budgets := maps.Clone(DefaultBudgets)
+	for action, b := range overrides {
+		if _, ok := DefaultBudgets[action]; !ok {
+			return nil, fmt.Errorf("未知的操作类型 %q，可选 publish / comment / like / favorite", action)
+		}
+		if b.Limit < 0 || b.Per < 0 || b.MinGap < 0 || b.Jitter < 0 {
+			return nil, fmt.Errorf("操作 %s 的限制不能为负数", action)
+		}
+		if b.Limit > 0 && b.Per == 0 {
+			return nil, fmt.Errorf("操作 %s 设置了 limit 但未设置 per", action)
+		}
+		if b.Limit == 0 && b.MinGap == 0 && b.Jitter == 0 {
+			delete(budgets, action)
+			continue
+		}
+		budgets[action] = b
+	}
+	return budgets, nil
+}
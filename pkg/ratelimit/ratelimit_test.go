@@ -0,0 +1,200 @@
+package ratelimit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func newTestLimiter(budgets map[Action]Budget) (*Limiter, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	l := New(budgets)
+	l.now = clock.now
+	l.jitter = func(max time.Duration) time.Duration { return max / 2 }
+	return l, clock
+}
+
+func TestAllow(t *testing.T) {
+	type step struct {
+		advance   time.Duration
+		account   string
+		action    Action
+		wantRetry time.Duration // 0 表示允许执行
+	}
+
+	tests := []struct {
+		name    string
+		budgets map[Action]Budget
+		steps   []step
+	}{
+		{
+			name:    "令牌桶每 20 分钟恢复一个令牌",
+			budgets: map[Action]Budget{ActionLike: {Limit: 3, Per: Duration(time.Hour)}},
+			steps: []step{
+				{account: "a", action: ActionLike},
+				{account: "a", action: ActionLike},
+				{account: "a", action: ActionLike},
+				{account: "a", action: ActionLike, wantRetry: 20 * time.Minute},
+				// 其他账号和其他操作不受影响
+				{account: "b", action: ActionLike},
+				{account: "a", action: ActionComment},
+				{advance: 20 * time.Minute, account: "a", action: ActionLike},
+				{account: "a", action: ActionLike, wantRetry: 20 * time.Minute},
+			},
+		},
+		{
+			name:    "最小间隔加随机抖动",
+			budgets: map[Action]Budget{ActionComment: {MinGap: Duration(30 * time.Second), Jitter: Duration(20 * time.Second)}},
+			steps: []step{
+				{account: "a", action: ActionComment},
+				{advance: 35 * time.Second, account: "a", action: ActionComment, wantRetry: 5 * time.Second},
+				// 被拒绝的操作不占用额度，也不推迟下一次
+				{advance: 5 * time.Second, account: "a", action: ActionComment},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, clock := newTestLimiter(tt.budgets)
+			for i, st := range tt.steps {
+				clock.t = clock.t.Add(st.advance)
+				err := l.Allow(st.account, st.action)
+				if st.wantRetry == 0 {
+					assert.NoError(t, err, "第 %d 步", i+1)
+					continue
+				}
+				var rlErr *Error
+				require.ErrorAs(t, err, &rlErr, "第 %d 步", i+1)
+				assert.Equal(t, st.action, rlErr.Action)
+				assert.Equal(t, st.wantRetry, rlErr.RetryAfter, "第 %d 步", i+1)
+				assert.Equal(t, int(st.wantRetry/time.Second), rlErr.RetryAfterSeconds())
+			}
+		})
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	var l *Limiter
+	assert.NoError(t, l.Allow("a", ActionPublish))
+
+	r, err := l.Reserve("a", ActionPublish)
+	assert.NoError(t, err)
+	r.Cancel()
+}
+
+func TestReservationCancel(t *testing.T) {
+	budgets := map[Action]Budget{
+		ActionPublish: {Limit: 2, Per: Duration(24 * time.Hour), MinGap: Duration(10 * time.Minute)},
+	}
+
+	tests := []struct {
+		name          string
+		cancel        bool
+		wantRetry     bool // 立即重试是否允许
+		wantRemaining int  // 之后还能执行的次数
+	}{
+		{name: "执行前失败归还额度并取消间隔", cancel: true, wantRetry: true, wantRemaining: 1},
+		{name: "未归还时受最小间隔和次数限制", cancel: false, wantRetry: false, wantRemaining: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, clock := newTestLimiter(budgets)
+
+			r, err := l.Reserve("a", ActionPublish)
+			require.NoError(t, err)
+			if tt.cancel {
+				r.Cancel()
+				r.Cancel() // 重复调用不会多归还
+			}
+			assert.Equal(t, tt.wantRetry, l.Allow("a", ActionPublish) == nil)
+
+			remaining := 0
+			for range 3 {
+				clock.t = clock.t.Add(20 * time.Minute)
+				if l.Allow("a", ActionPublish) == nil {
+					remaining++
+				}
+			}
+			assert.Equal(t, tt.wantRemaining, remaining)
+		})
+	}
+}
+
+func TestPersistState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	budgets := map[Action]Budget{
+		ActionPublish: {Limit: 1, Per: Duration(24 * time.Hour)},
+	}
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+
+	l, err := Open(path, budgets)
+	require.NoError(t, err)
+	l.now = clock.now
+	require.NoError(t, l.Allow("a", ActionPublish))
+
+	// 重启后额度不会重置
+	clock.t = clock.t.Add(time.Hour)
+	restarted, err := Open(path, budgets)
+	require.NoError(t, err)
+	restarted.now = clock.now
+	var rlErr *Error
+	require.ErrorAs(t, restarted.Allow("a", ActionPublish), &rlErr)
+	assert.Equal(t, 23*time.Hour, rlErr.RetryAfter)
+	assert.NoError(t, restarted.Allow("b", ActionPublish))
+
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0644))
+	_, err = Open(path, budgets)
+	assert.Error(t, err)
+}
+
+func TestLoadBudgets(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    map[Action]Budget // 只检查列出的操作
+		absent  []Action
+		wantErr string
+	}{
+		{
+			name: "覆盖默认预算",
+			data: `{"publish": {"limit": 5, "per": "24h", "min_gap": "10m"}, "like": {"limit": 0}}`,
+			want: map[Action]Budget{
+				ActionPublish: {Limit: 5, Per: Duration(24 * time.Hour), MinGap: Duration(10 * time.Minute)},
+				ActionComment: DefaultBudgets[ActionComment],
+			},
+			absent: []Action{ActionLike},
+		},
+		{name: "未知操作", data: `{"follow": {"limit": 1, "per": "1h"}}`, wantErr: "follow"},
+		{name: "缺少周期", data: `{"publish": {"limit": 1}}`, wantErr: "per"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "limits.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.data), 0644))
+
+			budgets, err := LoadBudgets(path)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			for action, want := range tt.want {
+				assert.Equal(t, want, budgets[action], action)
+			}
+			for _, action := range tt.absent {
+				assert.NotContains(t, budgets, action)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+// Package rescache 缓存最近获取的笔记、用户主页等数据，按资源 URI 存取，
+// 数据更新时通知订阅方，供 MCP 资源读取和推送更新。
+package rescache
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultTTL     = 10 * time.Minute // 默认缓存有效期
+	DefaultMaxSize = 500              // 默认最多缓存的条目数
+)
+
+// Entry 缓存条目
+type Entry struct {
+	Value     any
+	UpdatedAt time.Time
+}
+
+// Cache 带有效期和容量上限的缓存，超过容量时淘汰最早更新的条目
+type Cache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	maxSize   int
+	entries   map[string]*Entry
+	listeners []func(key string)
+
+	now func() time.Time
+}
+
+// New 创建缓存，ttl 为 0 表示不过期，maxSize 小于 1 时使用 DefaultMaxSize
+func New(ttl time.Duration, maxSize int) *Cache {
+	if maxSize < 1 {
+		maxSize = DefaultMaxSize
+	}
+	return &Cache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		entries: make(map[string]*Entry),
+		now:     time.Now,
+	}
+}
+
+// OnUpdate 注册更新回调，Set 和 Notify 时调用
+func (c *Cache) OnUpdate(fn func(key string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, fn)
+}
+
+// Get 读取未过期的条目
+func (c *Cache) Get(key string) (Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return Entry{}, false
+	}
+	if c.expired(e) {
+		delete(c.entries, key)
+		return Entry{}, false
+	}
+	return *e, true
+}
+
+// Set 写入条目并通知订阅方
+func (c *Cache) Set(key string, value any) {
+	c.mu.Lock()
+	c.entries[key] = &Entry{Value: value, UpdatedAt: c.now()}
+	c.evict()
+	c.mu.Unlock()
+
+	c.Notify(key)
+}
+
+// Delete 删除条目并通知订阅方
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	delete(c.entries, key)
+	c.mu.Unlock()
+
+	c.Notify(key)
+}
+
+// Notify 通知订阅方 key 对应的数据已更新，用于不经过缓存的实时数据
+func (c *Cache) Notify(key string) {
+	c.mu.Lock()
+	listeners := slices.Clone(c.listeners)
+	c.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(key)
+	}
+}
+
+// Keys 返回以 prefix 开头的未过期 key，按更新时间从新到旧排序
+func (c *Cache) Keys(prefix string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var keys []string
+	for k, e := range c.entries {
+		if strings.HasPrefix(k, prefix) && !c.expired(e) {
+			keys = append(keys, k)
+		}
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		return c.entries[b].UpdatedAt.Compare(c.entries[a].UpdatedAt)
+	})
+	return keys
+}
+
+func (c *Cache) expired(e *Entry) bool {
+	return c.ttl > 0 && c.now().Sub(e.UpdatedAt) > c.ttl
+}
+
+// evict 超过容量时淘汰最早更新的条目，调用方需持有锁
+func (c *Cache) evict() {
+	for len(c.entries) > c.maxSize {
+		var oldestKey string
+		var oldest time.Time
+		for k, e := range c.entries {
+			if oldestKey == "" || e.UpdatedAt.Before(oldest) {
+				oldestKey, oldest = k, e.UpdatedAt
+			}
+		}
+		delete(c.entries, oldestKey)
+	}
+}
@@ -0,0 +1,84 @@
+package rescache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCache(ttl time.Duration, maxSize int) (*Cache, *time.Time) {
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	c := New(ttl, maxSize)
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func TestGetExpires(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		advance time.Duration
+		wantHit bool
+	}{
+		{name: "未过期", ttl: time.Minute, advance: 30 * time.Second, wantHit: true},
+		{name: "已过期", ttl: time.Minute, advance: 2 * time.Minute},
+		{name: "不过期", advance: 24 * time.Hour, wantHit: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, now := newTestCache(tt.ttl, 0)
+			c.Set("xhs://note/1", "detail")
+			setAt := *now
+
+			*now = now.Add(tt.advance)
+			e, ok := c.Get("xhs://note/1")
+			require.Equal(t, tt.wantHit, ok)
+			if ok {
+				assert.Equal(t, "detail", e.Value)
+				assert.Equal(t, setAt, e.UpdatedAt)
+			}
+		})
+	}
+}
+
+func TestEvictOldest(t *testing.T) {
+	c, now := newTestCache(0, 2)
+	c.Set("a", 1)
+	*now = now.Add(time.Second)
+	c.Set("b", 2)
+	*now = now.Add(time.Second)
+	c.Set("c", 3)
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"c", "b"}, c.Keys(""))
+}
+
+func TestNotify(t *testing.T) {
+	c, _ := newTestCache(0, 0)
+
+	var updated []string
+	c.OnUpdate(func(key string) { updated = append(updated, key) })
+
+	c.Set("xhs://me", "profile")
+	c.Delete("xhs://me")
+	c.Notify("xhs://jobs/1")
+
+	_, ok := c.Get("xhs://me")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"xhs://me", "xhs://me", "xhs://jobs/1"}, updated)
+}
+
+func TestKeysPrefix(t *testing.T) {
+	c, now := newTestCache(0, 0)
+	c.Set("xhs://note/1", 1)
+	*now = now.Add(time.Second)
+	c.Set("xhs://user/1", 2)
+	*now = now.Add(time.Second)
+	c.Set("xhs://note/2", 3)
+
+	assert.Equal(t, []string{"xhs://note/2", "xhs://note/1"}, c.Keys("xhs://note/"))
+}
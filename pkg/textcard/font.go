@@ -0,0 +1,80 @@
+package textcard
+
+import (
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+)
+
+// SystemFontPaths 未指定字体文件时依次尝试的系统中文字体
+var SystemFontPaths = []string{
+	"/System/Library/Fonts/PingFang.ttc",
+	"/System/Library/Fonts/STHeiti Medium.ttc",
+	"/System/Library/Fonts/Hiragino Sans GB.ttc",
+	"/usr/share/fonts/opentype/noto/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/noto-cjk/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/google-noto-cjk/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/truetype/wqy/wqy-microhei.ttc",
+	"/usr/share/fonts/truetype/wqy/wqy-zenhei.ttc",
+	"/usr/share/fonts/wqy-microhei/wqy-microhei.ttc",
+	`C:\Windows\Fonts\msyh.ttc`,
+	`C:\Windows\Fonts\simhei.ttf`,
+}
+
+// loadFont 加载字体文件，支持 TTF/OTF 和 TTC 字体集合（取第一个字体）。
+// path 为空时依次尝试系统中文字体，都不存在时使用内置的 Go 字体（不含中文）
+func loadFont(path string) (*opentype.Font, string, error) {
+	if path != "" {
+		f, err := parseFontFile(path)
+		return f, path, err
+	}
+
+	for _, p := range SystemFontPaths {
+		if _, err := os.Stat(p); err != nil {
+			continue
+		}
+		if f, err := parseFontFile(p); err == nil {
+			return f, p, nil
+		}
+	}
+
+	f, err := opentype.Parse(goregular.TTF)
+	return f, "goregular", err
+}
+
+func parseFontFile(path string) (*opentype.Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "读取字体文件失败: %s", path)
+	}
+
+	if strings.HasSuffix(strings.ToLower(path), ".ttc") || strings.HasPrefix(string(data), "ttcf") {
+		collection, err := opentype.ParseCollection(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "解析字体集合失败: %s", path)
+		}
+		f, err := collection.Font(0)
+		if err != nil {
+			return nil, errors.Wrapf(err, "读取字体集合失败: %s", path)
+		}
+		return f, nil
+	}
+
+	f, err := opentype.Parse(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "解析字体文件失败: %s", path)
+	}
+	return f, nil
+}
+
+func newFace(f *opentype.Font, size float64) (font.Face, error) {
+	return opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+}
@@ -0,0 +1,95 @@
+package textcard
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// noLineStart 不能出现在行首的标点，换行时跟随上一行
+const noLineStart = "，。、；：！？）》」』】,.;:!?)]}…—"
+
+// wrapText 按宽度折行：中文按字符断行，英文按单词断行，\n 强制换行
+func wrapText(face font.Face, text string, maxWidth int) []string {
+	var lines []string
+	for _, para := range strings.Split(text, "\n") {
+		lines = append(lines, wrapLine(face, para, fixed.I(maxWidth))...)
+	}
+	return lines
+}
+
+func wrapLine(face font.Face, text string, maxWidth fixed.Int26_6) []string {
+	tokens := tokenize(text)
+	if len(tokens) == 0 {
+		return []string{""}
+	}
+
+	var (
+		lines []string
+		line  strings.Builder
+		width fixed.Int26_6
+	)
+	flush := func() {
+		lines = append(lines, strings.TrimRight(line.String(), " "))
+		line.Reset()
+		width = 0
+	}
+
+	for _, tok := range tokens {
+		w := font.MeasureString(face, tok)
+		isSpace := strings.TrimSpace(tok) == ""
+
+		if width > 0 && width+w > maxWidth && !isSpace && !strings.Contains(noLineStart, tok) {
+			flush()
+		}
+		if width == 0 && isSpace {
+			continue
+		}
+
+		// 超长单词逐字符断开
+		if width == 0 && w > maxWidth {
+			for _, r := range tok {
+				rw := font.MeasureString(face, string(r))
+				if width > 0 && width+rw > maxWidth {
+					flush()
+				}
+				line.WriteRune(r)
+				width += rw
+			}
+			continue
+		}
+
+		line.WriteString(tok)
+		width += w
+	}
+	if line.Len() > 0 {
+		flush()
+	}
+
+	return lines
+}
+
+// tokenize 拆分为断行单元：连续的拉丁字母/数字组成单词，其余字符单独成为一个单元
+func tokenize(text string) []string {
+	var (
+		tokens []string
+		word   strings.Builder
+	)
+	for _, r := range text {
+		if r < 0x2E80 && (unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("'-_.,:;!?%/@#&", r)) {
+			word.WriteRune(r)
+			continue
+		}
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+		tokens = append(tokens, string(r))
+	}
+	if word.Len() > 0 {
+		tokens = append(tokens, word.String())
+	}
+	return tokens
+}
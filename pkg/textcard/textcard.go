@@ -0,0 +1,451 @@
+// Package textcard 将标题和段落渲染为适合小红书图文的文字卡片（PNG）。
+package textcard
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	_ "golang.org/x/image/webp"
+)
+
+// MaxPages 卡片页数上限，与小红书图文最多图片数量一致
+const MaxPages = 18
+
+// 模板参数上限，超过时按上限处理，避免分配过大的画布
+const (
+	MaxCanvasSize  = 4096 // 画布宽高上限（像素）
+	MaxFontSize    = 400  // 字号上限
+	MaxLineSpacing = 4    // 行距倍数上限
+)
+
+// Template 卡片模板，零值字段使用 DefaultTemplate 中的默认值
+type Template struct {
+	Width           int     `json:"width,omitempty" jsonschema:"画布宽度（像素），默认1080，最大4096"`
+	Height          int     `json:"height,omitempty" jsonschema:"画布高度（像素），默认1440（3:4），最大4096"`
+	Background      string  `json:"background,omitempty" jsonschema:"背景颜色，如 #FFF8E7，默认白色"`
+	BackgroundImage string  `json:"background_image,omitempty" jsonschema:"背景图片路径（可选），必须位于服务端配置的素材目录内，相对路径相对素材目录，会居中裁剪铺满画布"`
+	TextColor       string  `json:"text_color,omitempty" jsonschema:"正文颜色，默认 #333333"`
+	TitleColor      string  `json:"title_color,omitempty" jsonschema:"标题颜色，默认 #111111"`
+	FontFile        string  `json:"font_file,omitempty" jsonschema:"字体文件路径（TTF/OTF/TTC），必须位于服务端配置的素材目录内，为空时自动查找系统中文字体"`
+	TitleSize       float64 `json:"title_size,omitempty" jsonschema:"标题字号，默认72，最大400"`
+	BodySize        float64 `json:"body_size,omitempty" jsonschema:"正文字号，默认44，最大400"`
+	LineSpacing     float64 `json:"line_spacing,omitempty" jsonschema:"行距倍数，默认1.6，最大4"`
+	Padding         int     `json:"padding,omitempty" jsonschema:"四周留白（像素），默认108"`
+}
+
+// DefaultTemplate 默认模板：1080x1440 白底深色文字
+var DefaultTemplate = Template{
+	Width:       1080,
+	Height:      1440,
+	Background:  "#FFFFFF",
+	TextColor:   "#333333",
+	TitleColor:  "#111111",
+	TitleSize:   72,
+	BodySize:    44,
+	LineSpacing: 1.6,
+	Padding:     108,
+}
+
+// withDefaults 填充零值字段，超过上限的尺寸、字号和行距按上限处理
+func (t Template) withDefaults() Template {
+	d := DefaultTemplate
+	if t.Width <= 0 {
+		t.Width = d.Width
+	}
+	t.Width = min(t.Width, MaxCanvasSize)
+	if t.Height <= 0 {
+		t.Height = d.Height
+	}
+	t.Height = min(t.Height, MaxCanvasSize)
+	if t.Background == "" {
+		t.Background = d.Background
+	}
+	if t.TextColor == "" {
+		t.TextColor = d.TextColor
+	}
+	if t.TitleColor == "" {
+		t.TitleColor = d.TitleColor
+	}
+	if t.TitleSize <= 0 {
+		t.TitleSize = d.TitleSize
+	}
+	t.TitleSize = min(t.TitleSize, MaxFontSize)
+	if t.BodySize <= 0 {
+		t.BodySize = d.BodySize
+	}
+	t.BodySize = min(t.BodySize, MaxFontSize)
+	if t.LineSpacing <= 0 {
+		t.LineSpacing = d.LineSpacing
+	}
+	t.LineSpacing = min(t.LineSpacing, MaxLineSpacing)
+	if t.Padding <= 0 {
+		t.Padding = d.Padding
+	}
+	return t
+}
+
+// Renderer 文字卡片渲染器
+type Renderer struct {
+	tpl        Template
+	assetDir   string
+	fontName   string
+	font       *opentype.Font
+	titleFace  font.Face
+	bodyFace   font.Face
+	footerFace font.Face
+	background image.Image
+	textColor  color.Color
+	titleColor color.Color
+}
+
+// Option 渲染器配置选项
+type Option func(*Renderer)
+
+// WithAssetDir 设置模板可以引用的素材目录，背景图片和字体文件必须位于该目录内。
+// 未设置时模板不能指定 BackgroundImage 和 FontFile
+func WithAssetDir(dir string) Option {
+	return func(r *Renderer) {
+		r.assetDir = dir
+	}
+}
+
+// NewRenderer 根据模板创建渲染器，加载字体和背景图片
+func NewRenderer(tpl Template, opts ...Option) (*Renderer, error) {
+	tpl = tpl.withDefaults()
+
+	if tpl.Padding*2 >= tpl.Width || tpl.Padding*2 >= tpl.Height {
+		return nil, errors.New("留白过大，没有可用的绘制区域")
+	}
+
+	r := &Renderer{tpl: tpl}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	fontFile, err := r.resolveAsset(tpl.FontFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "字体文件")
+	}
+	if r.font, r.fontName, err = loadFont(fontFile); err != nil {
+		return nil, err
+	}
+	if r.titleFace, err = newFace(r.font, tpl.TitleSize); err != nil {
+		return nil, errors.Wrap(err, "创建标题字体失败")
+	}
+	if r.bodyFace, err = newFace(r.font, tpl.BodySize); err != nil {
+		return nil, errors.Wrap(err, "创建正文字体失败")
+	}
+	if r.footerFace, err = newFace(r.font, tpl.BodySize*0.6); err != nil {
+		return nil, errors.Wrap(err, "创建页码字体失败")
+	}
+
+	bg, err := parseHexColor(tpl.Background)
+	if err != nil {
+		return nil, errors.Wrap(err, "背景颜色错误")
+	}
+	if r.textColor, err = parseHexColor(tpl.TextColor); err != nil {
+		return nil, errors.Wrap(err, "正文颜色错误")
+	}
+	if r.titleColor, err = parseHexColor(tpl.TitleColor); err != nil {
+		return nil, errors.Wrap(err, "标题颜色错误")
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, tpl.Width, tpl.Height))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	if tpl.BackgroundImage != "" {
+		path, err := r.resolveAsset(tpl.BackgroundImage)
+		if err != nil {
+			return nil, errors.Wrap(err, "背景图片")
+		}
+		img, err := loadImage(path)
+		if err != nil {
+			return nil, err
+		}
+		coverDraw(canvas, img)
+	}
+	r.background = canvas
+
+	return r, nil
+}
+
+// resolveAsset 检查模板引用的文件是否位于素材目录内（解析符号链接后），返回实际路径。
+// 相对路径相对素材目录，path 为空时返回空
+func (r *Renderer) resolveAsset(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	if r.assetDir == "" {
+		return "", errors.Errorf("未配置素材目录，不能使用 %s", path)
+	}
+
+	dir, err := filepath.Abs(r.assetDir)
+	if err != nil {
+		return "", errors.Wrap(err, "解析素材目录失败")
+	}
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		return "", errors.Wrap(err, "解析素材目录失败")
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "读取 %s 失败", path)
+	}
+
+	rel, err := filepath.Rel(dir, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.Errorf("%s 不在素材目录 %s 内", path, r.assetDir)
+	}
+	return resolved, nil
+}
+
+// FontName 实际使用的字体文件
+func (r *Renderer) FontName() string {
+	return r.fontName
+}
+
+// line 一行待绘制的文字
+type line struct {
+	text  string
+	title bool
+	gap   int // 行前额外间距
+}
+
+// Render 渲染标题和段落，内容超过一页时自动分页，标题只出现在第一页
+func (r *Renderer) Render(title string, paragraphs []string) ([]image.Image, error) {
+	if err := r.checkGlyphs(title + strings.Join(paragraphs, "")); err != nil {
+		return nil, err
+	}
+
+	contentWidth := r.tpl.Width - r.tpl.Padding*2
+	titleLineHeight := int(r.tpl.TitleSize * 1.3)
+	bodyLineHeight := int(r.tpl.BodySize * r.tpl.LineSpacing)
+	paragraphGap := int(r.tpl.BodySize * 0.8)
+
+	var lines []line
+	if title = strings.TrimSpace(title); title != "" {
+		for _, text := range wrapText(r.titleFace, title, contentWidth) {
+			lines = append(lines, line{text: text, title: true})
+		}
+	}
+	for i, para := range paragraphs {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		for j, text := range wrapText(r.bodyFace, para, contentWidth) {
+			l := line{text: text}
+			if j == 0 && (i > 0 || len(lines) > 0) {
+				l.gap = paragraphGap
+			}
+			lines = append(lines, l)
+		}
+	}
+	if len(lines) == 0 {
+		return nil, errors.New("标题和正文不能都为空")
+	}
+
+	// 分页，页码占用底部一行
+	footerHeight := int(r.tpl.BodySize * 1.2)
+	maxHeight := r.tpl.Height - r.tpl.Padding*2 - footerHeight
+
+	var pages [][]line
+	var page []line
+	used := 0
+	for _, l := range lines {
+		h := bodyLineHeight
+		if l.title {
+			h = titleLineHeight
+		}
+		if len(page) > 0 {
+			h += l.gap
+		}
+		if used+h > maxHeight && len(page) > 0 {
+			pages = append(pages, page)
+			page, used = nil, 0
+			h -= l.gap
+		}
+		page = append(page, l)
+		used += h
+	}
+	pages = append(pages, page)
+
+	if len(pages) > MaxPages {
+		return nil, fmt.Errorf("内容过长，需要 %d 页，超过上限 %d 页", len(pages), MaxPages)
+	}
+
+	images := make([]image.Image, 0, len(pages))
+	for i, p := range pages {
+		images = append(images, r.drawPage(p, i+1, len(pages), titleLineHeight, bodyLineHeight))
+	}
+	return images, nil
+}
+
+func (r *Renderer) drawPage(lines []line, pageNo, total, titleLineHeight, bodyLineHeight int) image.Image {
+	canvas := image.NewRGBA(image.Rect(0, 0, r.tpl.Width, r.tpl.Height))
+	draw.Draw(canvas, canvas.Bounds(), r.background, image.Point{}, draw.Src)
+
+	y := r.tpl.Padding
+	for i, l := range lines {
+		face, c, h := r.bodyFace, r.textColor, bodyLineHeight
+		if l.title {
+			face, c, h = r.titleFace, r.titleColor, titleLineHeight
+		}
+		if i > 0 {
+			y += l.gap
+		}
+
+		// 基线位于行高的中间偏下
+		ascent := face.Metrics().Ascent.Ceil()
+		descent := face.Metrics().Descent.Ceil()
+		baseline := y + (h-ascent-descent)/2 + ascent
+		drawString(canvas, face, c, r.tpl.Padding, baseline, l.text)
+		y += h
+	}
+
+	if total > 1 {
+		text := fmt.Sprintf("%d/%d", pageNo, total)
+		w := font.MeasureString(r.footerFace, text).Ceil()
+		drawString(canvas, r.footerFace, r.textColor, r.tpl.Width-r.tpl.Padding-w, r.tpl.Height-r.tpl.Padding/2, text)
+	}
+
+	return canvas
+}
+
+// checkGlyphs 检查字体是否包含所有字符，避免渲染出方块
+func (r *Renderer) checkGlyphs(text string) error {
+	var missing []string
+	seen := make(map[rune]bool)
+	for _, c := range text {
+		if c == '\n' || c == ' ' || seen[c] {
+			continue
+		}
+		seen[c] = true
+		if _, ok := r.bodyFace.GlyphAdvance(c); !ok {
+			missing = append(missing, string(c))
+			if len(missing) >= 5 {
+				break
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("字体 %s 不支持字符 %s，请通过 font_file 指定包含这些字符的字体（如中文字体）",
+			r.fontName, strings.Join(missing, " "))
+	}
+	return nil
+}
+
+// RenderToFiles 渲染并保存为 PNG，返回按页排序的文件路径。
+// 文件名由内容和模板决定，相同输入会覆盖同名文件
+func (r *Renderer) RenderToFiles(title string, paragraphs []string, outputDir string) ([]string, error) {
+	images, err := r.Render(title, paragraphs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, errors.Wrap(err, "创建输出目录失败")
+	}
+
+	key := r.cacheKey(title, paragraphs)
+	paths := make([]string, 0, len(images))
+	for i, img := range images {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, errors.Wrap(err, "PNG 编码失败")
+		}
+
+		path := filepath.Join(outputDir, fmt.Sprintf("card_%s_%02d.png", key, i+1))
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			return nil, errors.Wrap(err, "保存卡片失败")
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func (r *Renderer) cacheKey(title string, paragraphs []string) string {
+	data, _ := json.Marshal(struct {
+		Template   Template
+		Font       string
+		Title      string
+		Paragraphs []string
+	}{r.tpl, r.fontName, title, paragraphs})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+func drawString(dst draw.Image, face font.Face, c color.Color, x, y int, text string) {
+	d := &font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(text)
+}
+
+// coverDraw 将图片等比缩放后居中裁剪铺满画布
+func coverDraw(dst *image.RGBA, src image.Image) {
+	db, sb := dst.Bounds(), src.Bounds()
+	scale := max(float64(db.Dx())/float64(sb.Dx()), float64(db.Dy())/float64(sb.Dy()))
+	w := int(float64(sb.Dx())*scale + 0.5)
+	h := int(float64(sb.Dy())*scale + 0.5)
+	x := (db.Dx() - w) / 2
+	y := (db.Dy() - h) / 2
+	xdraw.CatmullRom.Scale(dst, image.Rect(x, y, x+w, y+h), src, sb, xdraw.Over, nil)
+}
+
+func loadImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "打开背景图片失败: %s", path)
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "解码背景图片失败: %s", path)
+	}
+	return img, nil
+}
+
+// parseHexColor 解析 #RGB、#RRGGBB 或 #RRGGBBAA 格式的颜色
+func parseHexColor(s string) (color.Color, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	if len(hex) != 8 {
+		return nil, fmt.Errorf("无效的颜色: %s", s)
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("无效的颜色: %s", s)
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
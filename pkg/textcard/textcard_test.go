@@ -0,0 +1,206 @@
+package textcard
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// goFontFile 素材目录中的内置 Go 字体，保证测试不依赖系统字体
+const goFontFile = "goregular.ttf"
+
+// newAssetDir 创建包含 goFontFile 的素材目录
+func newAssetDir(t *testing.T) string {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, goFontFile), goregular.TTF, 0644))
+	return dir
+}
+
+// newTestRenderer 使用内置 Go 字体创建渲染器
+func newTestRenderer(t *testing.T, tpl Template) (*Renderer, error) {
+	tpl.FontFile = goFontFile
+	return NewRenderer(tpl, WithAssetDir(newAssetDir(t)))
+}
+
+func TestRenderSinglePage(t *testing.T) {
+	r, err := newTestRenderer(t, Template{Background: "#FFF8E7"})
+	require.NoError(t, err)
+
+	images, err := r.Render("Weekend Notes", []string{"A short paragraph.", "Another one."})
+	require.NoError(t, err)
+	require.Len(t, images, 1)
+
+	b := images[0].Bounds()
+	assert.Equal(t, 1080, b.Dx())
+	assert.Equal(t, 1440, b.Dy())
+
+	// 角落是背景色
+	cr, cg, cb, _ := images[0].At(1, 1).RGBA()
+	assert.Equal(t, [3]uint32{0xff, 0xf8, 0xe7}, [3]uint32{cr >> 8, cg >> 8, cb >> 8})
+}
+
+func TestRenderPaginates(t *testing.T) {
+	r, err := newTestRenderer(t, Template{})
+	require.NoError(t, err)
+
+	para := strings.Repeat("lorem ipsum dolor sit amet ", 40)
+	images, err := r.Render("Title", []string{para, para, para})
+	require.NoError(t, err)
+	assert.Greater(t, len(images), 1)
+
+	_, err = r.Render("Title", []string{strings.Repeat(para, 30)})
+	assert.ErrorContains(t, err, "超过上限")
+}
+
+func TestRenderMissingGlyph(t *testing.T) {
+	r, err := newTestRenderer(t, Template{})
+	require.NoError(t, err)
+
+	_, err = r.Render("标题", []string{"正文"})
+	assert.ErrorContains(t, err, "不支持字符")
+}
+
+func TestRenderEmpty(t *testing.T) {
+	r, err := newTestRenderer(t, Template{})
+	require.NoError(t, err)
+
+	_, err = r.Render(" ", []string{"", " "})
+	assert.Error(t, err)
+}
+
+func TestRenderToFiles(t *testing.T) {
+	dir := newAssetDir(t)
+
+	bgPath := filepath.Join(dir, "bg.png")
+	bg := image.NewRGBA(image.Rect(0, 0, 30, 40))
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 30; x++ {
+			bg.Set(x, y, color.RGBA{B: 200, A: 255})
+		}
+	}
+	f, err := os.Create(bgPath)
+	require.NoError(t, err)
+	require.NoError(t, png.Encode(f, bg))
+	require.NoError(t, f.Close())
+
+	r, err := NewRenderer(Template{FontFile: goFontFile, BackgroundImage: "bg.png", Width: 600, Height: 800, Padding: 60}, WithAssetDir(dir))
+	require.NoError(t, err)
+
+	out := filepath.Join(dir, "cards")
+	paths, err := r.RenderToFiles("Hello", []string{"World"}, out)
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+
+	data, err := os.Open(paths[0])
+	require.NoError(t, err)
+	defer data.Close()
+	img, err := png.Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 600, 800), img.Bounds())
+
+	_, _, b, _ := img.At(1, 1).RGBA()
+	assert.InDelta(t, 200, b>>8, 2)
+
+	// 相同输入得到相同文件名
+	again, err := r.RenderToFiles("Hello", []string{"World"}, out)
+	require.NoError(t, err)
+	assert.Equal(t, paths, again)
+}
+
+func TestNewRendererErrors(t *testing.T) {
+	_, err := newTestRenderer(t, Template{Background: "red"})
+	assert.Error(t, err)
+
+	_, err = NewRenderer(Template{FontFile: "missing.ttf"}, WithAssetDir(t.TempDir()))
+	assert.Error(t, err)
+
+	_, err = newTestRenderer(t, Template{Width: 100, Padding: 60})
+	assert.Error(t, err)
+}
+
+func TestNewRendererAssetDir(t *testing.T) {
+	dir := newAssetDir(t)
+	outside := filepath.Join(t.TempDir(), "outside.ttf")
+	require.NoError(t, os.WriteFile(outside, goregular.TTF, 0644))
+	require.NoError(t, os.Symlink(outside, filepath.Join(dir, "link.ttf")))
+
+	tests := []struct {
+		name     string
+		fontFile string
+		assetDir string
+		wantErr  string
+	}{
+		{name: "相对路径", fontFile: goFontFile, assetDir: dir},
+		{name: "目录内的绝对路径", fontFile: filepath.Join(dir, goFontFile), assetDir: dir},
+		{name: "未配置素材目录", fontFile: goFontFile, wantErr: "未配置素材目录"},
+		{name: "目录外的绝对路径", fontFile: outside, assetDir: dir, wantErr: "不在素材目录"},
+		{name: "上级目录", fontFile: "../" + filepath.Base(filepath.Dir(outside)) + "/outside.ttf", assetDir: dir, wantErr: "不在素材目录"},
+		{name: "指向目录外的符号链接", fontFile: "link.ttf", assetDir: dir, wantErr: "不在素材目录"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRenderer(Template{FontFile: tt.fontFile}, WithAssetDir(tt.assetDir))
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTemplateLimits(t *testing.T) {
+	tpl := Template{Width: 200000, Height: 200000, TitleSize: 1e6, BodySize: 1e6, LineSpacing: 100}.withDefaults()
+
+	assert.Equal(t, MaxCanvasSize, tpl.Width)
+	assert.Equal(t, MaxCanvasSize, tpl.Height)
+	assert.Equal(t, float64(MaxFontSize), tpl.TitleSize)
+	assert.Equal(t, float64(MaxFontSize), tpl.BodySize)
+	assert.Equal(t, float64(MaxLineSpacing), tpl.LineSpacing)
+
+	r, err := newTestRenderer(t, Template{Width: 200000, Height: 200000})
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, MaxCanvasSize, MaxCanvasSize), r.background.Bounds())
+}
+
+func TestParseHexColor(t *testing.T) {
+	c, err := parseHexColor("#f80")
+	require.NoError(t, err)
+	assert.Equal(t, color.NRGBA{R: 0xff, G: 0x88, B: 0x00, A: 0xff}, c)
+
+	c, err = parseHexColor("11223380")
+	require.NoError(t, err)
+	assert.Equal(t, color.NRGBA{R: 0x11, G: 0x22, B: 0x33, A: 0x80}, c)
+
+	_, err = parseHexColor("#12345")
+	assert.Error(t, err)
+}
+
+func TestWrapText(t *testing.T) {
+	r, err := newTestRenderer(t, Template{})
+	require.NoError(t, err)
+
+	lines := wrapText(r.bodyFace, "hello world hello world", 300)
+	for _, l := range lines {
+		assert.False(t, strings.HasPrefix(l, " "))
+		assert.NotContains(t, []string{"hel", "lo"}, l)
+	}
+	assert.Greater(t, len(lines), 1)
+	assert.Equal(t, "hello world hello world", strings.Join(lines, " "))
+
+	// 显式换行
+	assert.Equal(t, []string{"a", "", "b"}, wrapText(r.bodyFace, "a\n\nb", 300))
+}
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"今", "天", " ", "coffee,", " ", "好", "喝", "！"}, tokenize("今天 coffee, 好喝！"))
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/xpzouying/xiaohongshu-mcp/configs"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/publishlog"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/ratelimit"
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
+)
+
+// publishAttempt 一次发布的记录和占用的频率限制额度
+type publishAttempt struct {
+	record *publishlog.Record
+	quota  *ratelimit.Reservation
+}
+
+// beginPublish 发布前检查幂等键和重复内容。
+//
+// 幂等键已有成功记录时返回 replayed，调用方应直接返回历史结果；
+// 否则检查发布频率限制并返回本次发布，发布结束后调用 finishPublish。
+// dry run 不做任何记录，也不占用频率限制额度。
+func (s *XiaohongshuService) beginPublish(kind, key string, force, dryRun bool, title, content string, mediaPaths []string) (attempt *publishAttempt, replayed *publishlog.Record, err error) {
+	if dryRun {
+		return nil, nil, nil
+	}
+
+	digests := make([]string, 0, len(mediaPaths))
+	for _, path := range mediaPaths {
+		digest, err := publishlog.FileDigest(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		digests = append(digests, digest)
+	}
+
+	rec, replay, err := s.records.Begin(publishlog.Entry{
+		IdempotencyKey: key,
+		ContentHash:    publishlog.HashContent(title, content, digests...),
+		Kind:           kind,
+		Title:          title,
+		Window:         configs.GetDedupWindow(),
+		Force:          force,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if replay {
+		logrus.Infof("幂等键 %s 已发布，返回历史结果", key)
+		return nil, rec, nil
+	}
+
+	quota, err := s.limiter.Reserve(currentAccount(), ratelimit.ActionPublish)
+	if err != nil {
+		s.finishPublish(&publishAttempt{record: rec}, "", nil, err)
+		return nil, nil, err
+	}
+	return &publishAttempt{record: rec, quota: quota}, nil, nil
+}
+
+// finishPublish 记录发布结果，记录失败不影响发布结果。
+// 点击发布之前失败时归还频率限制额度
+func (s *XiaohongshuService) finishPublish(attempt *publishAttempt, postID string, result any, publishErr error) {
+	if attempt == nil {
+		return
+	}
+
+	var err error
+	switch {
+	case publishErr == nil:
+		err = s.records.Complete(attempt.record, postID, result)
+	case xiaohongshu.IsSubmitted(publishErr):
+		// 已点击发布，笔记可能已经发出，不能当作失败重试，也不归还额度
+		logrus.Warnf("点击发布后出错，发布结果未知: %v", publishErr)
+		err = s.records.Unknown(attempt.record, publishErr)
+	default:
+		attempt.quota.Cancel()
+		err = s.records.Fail(attempt.record, publishErr)
+	}
+	if err != nil {
+		logrus.Errorf("保存发布记录失败: %v", err)
+	}
+}
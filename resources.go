@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
+)
+
+// 资源 URI，供 MCP 资源读取和订阅
+const (
+	resourceMe     = "xhs://me"
+	resourceDrafts = "xhs://drafts"
+	resourceJobs   = "xhs://jobs"
+)
+
+func noteURI(id string) string { return "xhs://note/" + id }
+func userURI(id string) string { return "xhs://user/" + id }
+func jobURI(id string) string  { return "xhs://jobs/" + id }
+
+// maxDrafts 最多保留的草稿数
+const maxDrafts = 20
+
+// xsecTokenTTL 缓存的 xsec_token 有效期
+const xsecTokenTTL = 24 * time.Hour
+
+// Draft dry run 时填写的发布表单，未点击发布
+type Draft struct {
+	Kind      string                      `json:"kind"` // image / video
+	Filled    *xiaohongshu.PublishPreview `json:"filled"`
+	CreatedAt time.Time                   `json:"created_at"`
+}
+
+// ResourceResult 资源内容及更新时间
+type ResourceResult struct {
+	Data      any       `json:"data"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OnResourceUpdate 注册资源更新回调，笔记、主页、草稿、批量发布任务更新时以资源 URI 回调
+func (s *XiaohongshuService) OnResourceUpdate(fn func(uri string)) {
+	s.cache.OnUpdate(fn)
+}
+
+// rememberFeeds 记录 Feeds 中笔记和作者的 xsec_token，之后可以只用 ID 读取笔记和用户资源
+func (s *XiaohongshuService) rememberFeeds(feeds []xiaohongshu.Feed) {
+	for _, f := range feeds {
+		if f.ID == "" || f.XsecToken == "" {
+			continue
+		}
+		s.tokens.Set(noteURI(f.ID), f.XsecToken)
+		if f.NoteCard.User.UserID != "" {
+			s.tokens.Set(userURI(f.NoteCard.User.UserID), f.XsecToken)
+		}
+	}
+}
+
+// addDraft 记录 dry run 填写的表单，只保留最近的 maxDrafts 条
+func (s *XiaohongshuService) addDraft(kind string, filled *xiaohongshu.PublishPreview) {
+	if filled == nil {
+		return
+	}
+
+	s.draftsMu.Lock()
+	s.drafts = append([]Draft{{Kind: kind, Filled: filled, CreatedAt: time.Now()}}, s.drafts...)
+	if len(s.drafts) > maxDrafts {
+		s.drafts = s.drafts[:maxDrafts]
+	}
+	s.draftsMu.Unlock()
+
+	s.cache.Notify(resourceDrafts)
+}
+
+// NoteResource 读取笔记详情，优先使用缓存。xsecToken 为空时使用之前浏览、搜索时记录的令牌
+func (s *XiaohongshuService) NoteResource(ctx context.Context, id, xsecToken string) (*ResourceResult, error) {
+	uri := noteURI(id)
+	if e, ok := s.cache.Get(uri); ok {
+		return &ResourceResult{Data: e.Value, UpdatedAt: e.UpdatedAt}, nil
+	}
+
+	token, err := s.resourceToken(uri, xsecToken)
+	if err != nil {
+		return nil, err
+	}
+	detail, err := s.GetFeedDetail(ctx, id, token)
+	if err != nil {
+		return nil, err
+	}
+	return &ResourceResult{Data: detail, UpdatedAt: time.Now()}, nil
+}
+
+// UserResource 读取用户主页，优先使用缓存。xsecToken 为空时使用之前浏览、搜索时记录的令牌
+func (s *XiaohongshuService) UserResource(ctx context.Context, id, xsecToken string) (*ResourceResult, error) {
+	uri := userURI(id)
+	if e, ok := s.cache.Get(uri); ok {
+		return &ResourceResult{Data: e.Value, UpdatedAt: e.UpdatedAt}, nil
+	}
+
+	token, err := s.resourceToken(uri, xsecToken)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := s.UserProfile(ctx, id, token)
+	if err != nil {
+		return nil, err
+	}
+	return &ResourceResult{Data: profile, UpdatedAt: time.Now()}, nil
+}
+
+// MyProfileResource 读取当前账号的主页，优先使用缓存
+func (s *XiaohongshuService) MyProfileResource(ctx context.Context) (*ResourceResult, error) {
+	if e, ok := s.cache.Get(resourceMe); ok {
+		return &ResourceResult{Data: e.Value, UpdatedAt: e.UpdatedAt}, nil
+	}
+
+	profile, err := s.GetMyProfile(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ResourceResult{Data: profile, UpdatedAt: time.Now()}, nil
+}
+
+// Drafts 最近的 dry run 草稿，从新到旧排序
+func (s *XiaohongshuService) Drafts() []Draft {
+	s.draftsMu.Lock()
+	defer s.draftsMu.Unlock()
+	return append([]Draft{}, s.drafts...)
+}
+
+// resourceToken 返回请求中的 xsec_token，未提供时使用记录的令牌
+func (s *XiaohongshuService) resourceToken(uri, xsecToken string) (string, error) {
+	if xsecToken != "" {
+		return xsecToken, nil
+	}
+	if e, ok := s.tokens.Get(uri); ok {
+		return e.Value.(string), nil
+	}
+	return "", fmt.Errorf("没有 %s 的 xsec_token，请先通过 list_feeds / search_feeds 获取，或在 URI 中添加 ?xsec_token=", uri)
+}
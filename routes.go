@@ -5,6 +5,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/xpzouying/xiaohongshu-mcp/configs"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/apikey"
 )
 
 // setupRoutes 设置路由配置
@@ -18,7 +20,7 @@ func setupRoutes(appServer *AppServer) *gin.Engine {
 
 	// 添加中间件
 	router.Use(errorHandlingMiddleware())
-	router.Use(corsMiddleware())
+	router.Use(corsMiddleware(configs.GetCORSAllowedOrigins(), appServer.auth.Enabled()))
 
 	// 健康检查
 	router.GET("/health", healthHandler)
@@ -32,24 +34,59 @@ func setupRoutes(appServer *AppServer) *gin.Engine {
 			JSONResponse: true, // 支持 JSON 响应
 		},
 	)
-	router.Any("/mcp", gin.WrapH(mcpHandler))
-	router.Any("/mcp/*path", gin.WrapH(mcpHandler))
+	var mcpEndpoint http.Handler = mcpHandler
+	if appServer.auth.Enabled() {
+		mcpEndpoint = mcpAuthHandler(appServer.auth, mcpHandler)
+	}
+	router.Any("/mcp", gin.WrapH(mcpEndpoint))
+	router.Any("/mcp/*path", gin.WrapH(mcpEndpoint))
 
-	// API 路由组
+	// API 路由组，按 API Key 权限分组
 	api := router.Group("/api/v1")
+
+	// 只读：浏览、搜索、内容检查等
+	read := api.Group("", authMiddleware(appServer.auth, apikey.ScopeRead))
+	{
+		read.GET("/login/status", appServer.checkLoginStatusHandler)
+		read.POST("/publish/lint", appServer.lintContentHandler)
+		read.POST("/publish/format", appServer.formatContentHandler)
+		read.GET("/publish/bulk", appServer.listBulkJobsHandler)
+		read.GET("/publish/bulk/:id", appServer.getBulkJobHandler)
+		read.GET("/templates", appServer.listTemplatesHandler)
+		read.GET("/templates/:name", appServer.getTemplateHandler)
+		read.GET("/feeds/list", appServer.listFeedsHandler)
+		read.GET("/feeds/search", appServer.searchFeedsHandler)
+		read.POST("/feeds/search", appServer.searchFeedsHandler)
+		read.POST("/feeds/detail", appServer.getFeedDetailHandler)
+		read.POST("/user/profile", appServer.userProfileHandler)
+		read.GET("/user/me", appServer.myProfileHandler)
+		read.GET("/queue", appServer.queueStatusHandler)
+		read.GET("/approvals", appServer.listApprovalsHandler)
+		read.GET("/approvals/:id", appServer.getApprovalHandler)
+	}
+
+	// 发布：以账号身份发布、评论，管理模板和批量发布任务，以及生成文字卡片（写入本地文件）
+	publish := api.Group("", authMiddleware(appServer.auth, apikey.ScopePublish))
+	{
+		publish.POST("/cards/render", appServer.renderTextCardsHandler)
+		publish.POST("/publish", appServer.publishHandler)
+		publish.POST("/publish_video", appServer.publishVideoHandler)
+		publish.POST("/publish/bulk", appServer.bulkPublishHandler)
+		publish.DELETE("/publish/bulk/:id", appServer.cancelBulkJobHandler)
+		publish.PUT("/templates/:name", appServer.saveTemplateHandler)
+		publish.DELETE("/templates/:name", appServer.deleteTemplateHandler)
+		publish.POST("/templates/:name/publish", appServer.publishFromTemplateHandler)
+		publish.POST("/feeds/comment", appServer.postCommentHandler)
+	}
+
+	// 账号管理：登录、删除 cookies，以及审批 AI 提交的操作。
+	// 审批需要比发布更高的权限，避免只有发布权限的客户端自己批准自己的操作
+	admin := api.Group("", authMiddleware(appServer.auth, apikey.ScopeAdmin))
 	{
-		api.GET("/login/status", appServer.checkLoginStatusHandler)
-		api.GET("/login/qrcode", appServer.getLoginQrcodeHandler)
-		api.DELETE("/login/cookies", appServer.deleteCookiesHandler)
-		api.POST("/publish", appServer.publishHandler)
-		api.POST("/publish_video", appServer.publishVideoHandler)
-		api.GET("/feeds/list", appServer.listFeedsHandler)
-		api.GET("/feeds/search", appServer.searchFeedsHandler)
-		api.POST("/feeds/search", appServer.searchFeedsHandler)
-		api.POST("/feeds/detail", appServer.getFeedDetailHandler)
-		api.POST("/user/profile", appServer.userProfileHandler)
-		api.POST("/feeds/comment", appServer.postCommentHandler)
-		api.GET("/user/me", appServer.myProfileHandler)
+		admin.GET("/login/qrcode", appServer.getLoginQrcodeHandler)
+		admin.DELETE("/login/cookies", appServer.deleteCookiesHandler)
+		admin.POST("/approvals/:id/approve", appServer.approveHandler)
+		admin.POST("/approvals/:id/reject", appServer.rejectHandler)
 	}
 
 	return router
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xpzouying/xiaohongshu-mcp/configs"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/apikey"
+)
+
+const (
+	readKey    = "read-key-0123456789"
+	publishKey = "publish-key-0123456789"
+	adminKey   = "admin-key-0123456789"
+)
+
+// newTestAppServer 启用三种权限 Key 的服务，发布记录和模板写入临时目录，不需要浏览器
+func newTestAppServer(t *testing.T) *AppServer {
+	dir := t.TempDir()
+	t.Setenv("PUBLISH_RECORDS_PATH", filepath.Join(dir, "publish_records.json"))
+	t.Setenv("POST_TEMPLATES_DIR", filepath.Join(dir, "templates"))
+
+	authn, err := apikey.New([]apikey.Key{
+		{Name: "reader", Key: readKey, Scopes: []apikey.Scope{apikey.ScopeRead}},
+		{Name: "publisher", Key: publishKey, Scopes: []apikey.Scope{apikey.ScopePublish}},
+		{Name: "admin", Key: adminKey, Scopes: []apikey.Scope{apikey.ScopeAdmin}},
+	})
+	require.NoError(t, err)
+
+	s := NewAppServer(NewXiaohongshuService(), authn)
+	s.router = setupRoutes(s)
+	return s
+}
+
+func TestRoutesScopes(t *testing.T) {
+	// 每个权限组选一个不依赖浏览器的接口
+	routes := []struct {
+		name   string
+		method string
+		path   func(s *AppServer) string
+		body   string
+	}{
+		{
+			name:   "read",
+			method: http.MethodGet,
+			path:   func(*AppServer) string { return "/api/v1/approvals" },
+		},
+		{
+			name:   "publish",
+			method: http.MethodPut,
+			path:   func(*AppServer) string { return "/api/v1/templates/weekly" },
+			body:   `{"title": "周报", "content": "本周内容"}`,
+		},
+		{
+			name:   "admin",
+			method: http.MethodPost,
+			path: func(s *AppServer) string {
+				pending := s.approvals.Submit("delete_cookies", "删除 cookies", func(context.Context) (any, error) { return nil, nil })
+				return "/api/v1/approvals/" + pending.ID + "/reject"
+			},
+			body: `{"reason": "测试"}`,
+		},
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		key    string
+		want   map[string]int // 路由 -> 状态码
+	}{
+		{
+			name: "未携带 Key",
+			want: map[string]int{"read": http.StatusUnauthorized, "publish": http.StatusUnauthorized, "admin": http.StatusUnauthorized},
+		},
+		{
+			name:   "无效 Key",
+			header: "Authorization",
+			key:    "Bearer invalid-key-0123456789",
+			want:   map[string]int{"read": http.StatusUnauthorized, "publish": http.StatusUnauthorized, "admin": http.StatusUnauthorized},
+		},
+		{
+			name:   "read Key",
+			header: "Authorization",
+			key:    "Bearer " + readKey,
+			want:   map[string]int{"read": http.StatusOK, "publish": http.StatusForbidden, "admin": http.StatusForbidden},
+		},
+		{
+			name:   "publish Key",
+			header: "X-API-Key",
+			key:    publishKey,
+			want:   map[string]int{"read": http.StatusOK, "publish": http.StatusOK, "admin": http.StatusForbidden},
+		},
+		{
+			name:   "admin Key",
+			header: "Authorization",
+			key:    "Bearer " + adminKey,
+			want:   map[string]int{"read": http.StatusOK, "publish": http.StatusOK, "admin": http.StatusOK},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, route := range routes {
+			t.Run(tt.name+"/"+route.name, func(t *testing.T) {
+				s := newTestAppServer(t)
+
+				req := httptest.NewRequest(route.method, route.path(s), strings.NewReader(route.body))
+				req.Header.Set("Content-Type", "application/json")
+				if tt.header != "" {
+					req.Header.Set(tt.header, tt.key)
+				}
+				w := httptest.NewRecorder()
+				s.router.ServeHTTP(w, req)
+
+				assert.Equal(t, tt.want[route.name], w.Code, w.Body.String())
+			})
+		}
+	}
+}
+
+// headerTransport 给每个请求加上认证请求头
+type headerTransport struct {
+	header, value string
+}
+
+func (h headerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set(h.header, h.value)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestMCPAuth(t *testing.T) {
+	s := newTestAppServer(t)
+	srv := httptest.NewServer(s.router)
+	defer srv.Close()
+
+	t.Run("未携带 Key", func(t *testing.T) {
+		resp, err := http.Post(srv.URL+"/mcp", "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	tests := []struct {
+		name    string
+		header  string
+		value   string
+		tool    string
+		args    map[string]any
+		allowed bool
+	}{
+		{
+			name:    "read Key 调用只读工具",
+			header:  "X-API-Key",
+			value:   readKey,
+			tool:    "lint_content",
+			args:    map[string]any{"title": "标题", "content": "正文"},
+			allowed: true,
+		},
+		{
+			name:   "read Key 调用发布工具",
+			header: "X-API-Key",
+			value:  readKey,
+			tool:   "publish_content",
+			args:   map[string]any{"title": "标题", "content": "正文", "images": []string{"/tmp/1.jpg"}},
+		},
+		{
+			name:   "read Key 生成文字卡片",
+			header: "X-API-Key",
+			value:  readKey,
+			tool:   "render_text_cards",
+			args:   map[string]any{"title": "标题", "paragraphs": []string{"正文"}},
+		},
+		{
+			name:   "publish Key 调用账号管理工具",
+			header: "Authorization",
+			value:  "Bearer " + publishKey,
+			tool:   "delete_cookies",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			client := mcp.NewClient(&mcp.Implementation{Name: "test"}, nil)
+			session, err := client.Connect(ctx, &mcp.StreamableClientTransport{
+				Endpoint:   srv.URL + "/mcp",
+				HTTPClient: &http.Client{Transport: headerTransport{header: tt.header, value: tt.value}},
+				MaxRetries: -1,
+			}, nil)
+			require.NoError(t, err)
+			defer session.Close()
+
+			result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: tt.tool, Arguments: tt.args})
+			require.NoError(t, err)
+
+			text := resultText(result)
+			if tt.allowed {
+				assert.False(t, result.IsError, text)
+				return
+			}
+			assert.True(t, result.IsError)
+			assert.Contains(t, text, "API Key 权限不足")
+		})
+	}
+}
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name       string
+		origins    []string
+		auth       bool
+		origin     string
+		wantHeader string
+	}{
+		{name: "未启用认证时允许任意来源", origins: []string{"*"}, origin: "https://evil.example", wantHeader: "*"},
+		{name: "启用认证时不使用 *", origins: []string{"*"}, auth: true, origin: "https://evil.example"},
+		{name: "回显允许的来源", origins: []string{"https://app.example/"}, auth: true, origin: "https://app.example", wantHeader: "https://app.example"},
+		{name: "拒绝未列出的来源", origins: []string{"https://app.example"}, origin: "https://evil.example"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configs.SetCORSAllowedOrigins(tt.origins)
+			t.Cleanup(func() { configs.SetCORSAllowedOrigins([]string{"*"}) })
+
+			s := newTestAppServer(t)
+			if !tt.auth {
+				s = NewAppServer(s.xiaohongshuService, nil)
+			}
+			router := setupRoutes(s)
+
+			req := httptest.NewRequest(http.MethodOptions, "/api/v1/publish", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			assert.Equal(t, tt.wantHeader, w.Header().Get("Access-Control-Allow-Origin"))
+		})
+	}
+}
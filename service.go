@@ -2,36 +2,162 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-rod/rod"
-	"github.com/mattn/go-runewidth"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/xpzouying/headless_browser"
 	"github.com/xpzouying/xiaohongshu-mcp/browser"
 	"github.com/xpzouying/xiaohongshu-mcp/configs"
 	"github.com/xpzouying/xiaohongshu-mcp/cookies"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/bulkpublish"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/contentlint"
 	"github.com/xpzouying/xiaohongshu-mcp/pkg/downloader"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/imageproc"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/mdformat"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/opsched"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/posttemplate"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/progress"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/publishlog"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/ratelimit"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/rescache"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/textcard"
 	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
 )
 
 // XiaohongshuService 小红书业务服务
-type XiaohongshuService struct{}
+type XiaohongshuService struct {
+	records   *publishlog.Store    // 发布记录，用于幂等和重复内容检测
+	templates *posttemplate.Store  // 笔记模板
+	bulk      *bulkpublish.Manager // 批量发布任务
+	limiter   *ratelimit.Limiter   // 发布、评论、点赞等操作的频率限制，为空表示不限制
+	sched     *opsched.Scheduler   // 同一账号的浏览器操作调度
+	cache     *rescache.Cache      // 最近获取的笔记、主页，按资源 URI 缓存
+	tokens    *rescache.Cache      // 浏览、搜索时记录的 xsec_token，按资源 URI 缓存
+
+	draftsMu sync.Mutex
+	drafts   []Draft // 最近的 dry run 草稿
+
+	loginMu     sync.Mutex
+	loginCancel context.CancelFunc // 取消正在等待扫码的登录流程
+}
+
+// ServiceOption 小红书服务选项
+type ServiceOption func(*XiaohongshuService)
+
+// WithRateLimiter 设置频率限制，传入 nil 表示不限制
+func WithRateLimiter(l *ratelimit.Limiter) ServiceOption {
+	return func(s *XiaohongshuService) {
+		s.limiter = l
+	}
+}
+
+// WithMaxConcurrentReads 设置同一账号最多同时执行的读操作数
+func WithMaxConcurrentReads(n int) ServiceOption {
+	return func(s *XiaohongshuService) {
+		s.sched = opsched.New(n)
+	}
+}
 
 // NewXiaohongshuService 创建小红书服务实例
-func NewXiaohongshuService() *XiaohongshuService {
-	return &XiaohongshuService{}
+func NewXiaohongshuService(options ...ServiceOption) *XiaohongshuService {
+	records, err := publishlog.Open(configs.GetPublishRecordsPath())
+	if err != nil {
+		// 记录文件损坏时不影响发布，只是无法跨重启去重
+		logrus.Errorf("打开发布记录失败，仅在内存中记录: %v", err)
+		records, _ = publishlog.Open("")
+	}
+
+	s := &XiaohongshuService{
+		records:   records,
+		templates: posttemplate.NewStore(configs.GetTemplatesPath()),
+		bulk:      bulkpublish.NewManager(),
+		limiter:   ratelimit.New(ratelimit.DefaultBudgets),
+		sched:     opsched.New(opsched.DefaultMaxReads),
+		cache:     rescache.New(rescache.DefaultTTL, rescache.DefaultMaxSize),
+		tokens:    rescache.New(xsecTokenTTL, 10*rescache.DefaultMaxSize),
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
+}
+
+// currentAccount 当前账号标识。每个服务实例只使用一个 cookies 文件，即一个账号，
+// 使用 cookies 文件的绝对路径区分账号，相对路径和绝对路径指向同一文件时视为同一账号
+func currentAccount() string {
+	path := cookies.GetCookiesFilePath()
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// acquire 在当前账号的操作队列中等待执行，返回的 release 需在操作结束后调用
+func (s *XiaohongshuService) acquire(ctx context.Context, kind opsched.Kind, name string) (func(), error) {
+	start := time.Now()
+	release, err := s.sched.Acquire(ctx, currentAccount(), kind, name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "等待执行 %s 时取消", name)
+	}
+	if waited := time.Since(start); waited > time.Second {
+		logrus.Infof("操作 %s 排队 %s 后开始执行", name, waited.Round(time.Second))
+	}
+	return release, nil
+}
+
+// acquireWithProgress 上报“等待执行”阶段后排队，排队位置变化时更新进度
+func (s *XiaohongshuService) acquireWithProgress(ctx context.Context, tr *progress.Tracker, kind opsched.Kind, name string) (func(), error) {
+	tr.Step("等待执行")
+	return s.acquire(opsched.WithQueueListener(ctx, tr.Queued), kind, name)
+}
+
+// QueueStatus 当前账号执行中和排队中的操作
+func (s *XiaohongshuService) QueueStatus() opsched.Status {
+	return s.sched.Status(currentAccount())
 }
 
 // PublishRequest 发布请求
 type PublishRequest struct {
-	Title   string   `json:"title" binding:"required"`
-	Content string   `json:"content" binding:"required"`
-	Images  []string `json:"images" binding:"required,min=1"`
-	Tags    []string `json:"tags,omitempty"`
+	Title    string   `json:"title" binding:"required"`
+	Content  string   `json:"content" binding:"required"`
+	Images   []string `json:"images" binding:"required,min=1,max=18"`
+	Tags     []string `json:"tags,omitempty"`
+	Mentions []string `json:"mentions,omitempty"`
+
+	// ImageOptions 可选的图片预处理选项，为空时图片原样上传
+	ImageOptions *imageproc.Options `json:"image_options,omitempty"`
+
+	// ImageLabels 可选的单张图片标记
+	ImageLabels []ImageLabelOption `json:"image_labels,omitempty"`
+
+	// DryRun 只填写发布表单并截图，不点击发布
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// IdempotencyKey 幂等键，相同键重试时直接返回之前的发布结果
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	// Force 忽略重复内容检测，强制发布
+	Force bool `json:"force,omitempty"`
+
+	// Markdown 正文为 Markdown，发布前转换为小红书纯文本并提取 #标签
+	Markdown bool `json:"markdown,omitempty"`
+	// TruncateTags 标签超过上限时只保留前面的标签并继续发布，默认返回检查错误
+	TruncateTags bool `json:"truncate_tags,omitempty"`
+}
+
+// ImageLabelOption 单张图片的标记选项
+type ImageLabelOption struct {
+	Index  int      `json:"index"`  // 图片在 images 中的序号，从 0 开始
+	Labels []string `json:"labels"` // 标记文字，如品牌、地点、商品
 }
 
 // LoginStatusResponse 登录状态响应
@@ -54,14 +180,57 @@ type PublishResponse struct {
 	Images  int    `json:"images"`
 	Status  string `json:"status"`
 	PostID  string `json:"post_id,omitempty"`
+
+	Preview  *PublishPreview       `json:"preview,omitempty"`  // 仅 dry run 时返回
+	Replayed bool                  `json:"replayed,omitempty"` // 幂等键重试，返回的是之前的发布结果
+	Warnings []contentlint.Finding `json:"warnings,omitempty"` // 发布前检查的提示，如正文截断、标签截断
+}
+
+// PublishPreview dry run 预览结果
+type PublishPreview struct {
+	Filled     *xiaohongshu.PublishPreview `json:"filled"`               // 从表单读取的实际填写内容
+	Screenshot string                      `json:"screenshot,omitempty"` // base64 编码的整页 PNG 截图
+}
+
+func newPublishPreview(p *xiaohongshu.PublishPreview) *PublishPreview {
+	if p == nil {
+		return nil
+	}
+	return &PublishPreview{
+		Filled:     p,
+		Screenshot: base64.StdEncoding.EncodeToString(p.Screenshot),
+	}
 }
 
-// PublishVideoRequest 发布视频请求（仅支持本地单个视频文件）
+// statusDryRun dry run 完成时的状态
+const statusDryRun = "已填写，未发布"
+
+// 上报进度的阶段数
+const (
+	publishSteps = 5 // 处理素材、等待执行、打开发布页面、上传并填写、发布或预览
+	readSteps    = 3 // 等待执行、加载页面、获取完成
+)
+
+// PublishVideoRequest 发布视频请求（单个视频，支持本地文件或 HTTP/HTTPS 链接）
 type PublishVideoRequest struct {
-	Title   string   `json:"title" binding:"required"`
-	Content string   `json:"content" binding:"required"`
-	Video   string   `json:"video" binding:"required"`
-	Tags    []string `json:"tags,omitempty"`
+	Title    string            `json:"title" binding:"required"`
+	Content  string            `json:"content" binding:"required"`
+	Video    string            `json:"video" binding:"required"`
+	Tags     []string          `json:"tags,omitempty"`
+	Mentions []string          `json:"mentions,omitempty"`
+	Cover    *VideoCoverOption `json:"cover,omitempty"`
+	DryRun   bool              `json:"dry_run,omitempty"` // 只填写发布表单并截图，不点击发布
+
+	IdempotencyKey string `json:"idempotency_key,omitempty"` // 幂等键，相同键重试时直接返回之前的发布结果
+	Force          bool   `json:"force,omitempty"`           // 忽略重复内容检测，强制发布
+	Markdown       bool   `json:"markdown,omitempty"`        // 正文为 Markdown，发布前转换为小红书纯文本并提取 #标签
+	TruncateTags   bool   `json:"truncate_tags,omitempty"`   // 标签超过上限时只保留前面的标签并继续发布，默认返回检查错误
+}
+
+// VideoCoverOption 视频封面选项，image 与 timestamp 二选一
+type VideoCoverOption struct {
+	Image     string   `json:"image,omitempty"`     // 自定义封面：本地图片路径或 HTTP/HTTPS 链接
+	Timestamp *float64 `json:"timestamp,omitempty"` // 截取视频指定时间点（秒）的帧作为封面，0 表示第一帧
 }
 
 // PublishVideoResponse 发布视频响应
@@ -71,6 +240,10 @@ type PublishVideoResponse struct {
 	Video   string `json:"video"`
 	Status  string `json:"status"`
 	PostID  string `json:"post_id,omitempty"`
+
+	Preview  *PublishPreview       `json:"preview,omitempty"`  // 仅 dry run 时返回
+	Replayed bool                  `json:"replayed,omitempty"` // 幂等键重试，返回的是之前的发布结果
+	Warnings []contentlint.Finding `json:"warnings,omitempty"` // 发布前检查的提示，如标签超过上限已截断
 }
 
 // FeedsListResponse Feeds列表响应
@@ -88,13 +261,30 @@ type UserProfileResponse struct {
 
 // DeleteCookies 删除 cookies 文件，用于登录重置
 func (s *XiaohongshuService) DeleteCookies(ctx context.Context) error {
+	// 等待进行中的操作结束，避免删除正在使用的登录状态
+	release, err := s.acquire(ctx, opsched.Exclusive, "delete_cookies")
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	cookiePath := cookies.GetCookiesFilePath()
 	cookieLoader := cookies.NewLoadCookie(cookiePath)
-	return cookieLoader.DeleteCookies()
+	if err := cookieLoader.DeleteCookies(); err != nil {
+		return err
+	}
+	s.cache.Delete(resourceMe)
+	return nil
 }
 
 // CheckLoginStatus 检查登录状态
 func (s *XiaohongshuService) CheckLoginStatus(ctx context.Context) (*LoginStatusResponse, error) {
+	release, err := s.acquire(ctx, opsched.Read, "check_login_status")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	b := newBrowser()
 	defer b.Close()
 
@@ -118,6 +308,12 @@ func (s *XiaohongshuService) CheckLoginStatus(ctx context.Context) (*LoginStatus
 
 // GetLoginQrcode 获取登录的扫码二维码
 func (s *XiaohongshuService) GetLoginQrcode(ctx context.Context) (*LoginQrcodeResponse, error) {
+	release, err := s.acquire(ctx, opsched.Read, "get_login_qrcode")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	b := newBrowser()
 	page := b.NewPage()
 
@@ -139,15 +335,30 @@ func (s *XiaohongshuService) GetLoginQrcode(ctx context.Context) (*LoginQrcodeRe
 	timeout := 4 * time.Minute
 
 	if !loggedIn {
+		ctxTimeout, cancel := context.WithTimeout(context.Background(), timeout)
+		s.startLogin(cancel)
+
 		go func() {
-			ctxTimeout, cancel := context.WithTimeout(context.Background(), timeout)
 			defer cancel()
 			defer deferFunc()
 
-			if loginAction.WaitForLogin(ctxTimeout) {
-				if er := saveCookies(page); er != nil {
-					logrus.Errorf("failed to save cookies: %v", er)
-				}
+			if !loginAction.WaitForLogin(ctxTimeout) {
+				return
+			}
+
+			// 保存 cookies 是独占操作，等待进行中的发布等操作结束后再写入。
+			// 扫码已经成功，不再受二维码有效期限制
+			saveCtx, cancelSave := context.WithTimeout(context.Background(), timeout)
+			defer cancelSave()
+			release, err := s.acquire(saveCtx, opsched.Exclusive, "save_cookies")
+			if err != nil {
+				logrus.Errorf("failed to save cookies: %v", err)
+				return
+			}
+			defer release()
+
+			if er := saveCookies(page); er != nil {
+				logrus.Errorf("failed to save cookies: %v", er)
 			}
 		}()
 	}
@@ -164,81 +375,341 @@ func (s *XiaohongshuService) GetLoginQrcode(ctx context.Context) (*LoginQrcodeRe
 	}, nil
 }
 
+// startLogin 记录新的扫码登录流程并取消之前还在等待的流程，同一时间只有一个二维码有效
+func (s *XiaohongshuService) startLogin(cancel context.CancelFunc) {
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+
+	if s.loginCancel != nil {
+		s.loginCancel()
+	}
+	s.loginCancel = cancel
+}
+
 // PublishContent 发布内容
 func (s *XiaohongshuService) PublishContent(ctx context.Context, req *PublishRequest) (*PublishResponse, error) {
-	// 验证标题长度
-	// 小红书限制：最大40个单位长度
-	// 中文/日文/韩文占2个单位，英文/数字占1个单位
-	if titleWidth := runewidth.StringWidth(req.Title); titleWidth > 40 {
-		return nil, fmt.Errorf("标题长度超过限制")
+	text, err := s.preparePublishText(req.Title, req.Content, req.Tags, req.Markdown, req.TruncateTags)
+	if err != nil {
+		return nil, err
+	}
+	req.Content, req.Tags = text.Content, text.Tags
+	warnings := text.Warnings
+
+	if err := checkImageCount(len(req.Images)); err != nil {
+		return nil, err
+	}
+
+	imageLabels, err := buildImageLabels(req.ImageLabels, len(req.Images))
+	if err != nil {
+		return nil, err
+	}
+
+	tr := progress.Start(ctx, publishSteps)
+	tr.Stepf("处理 %d 张图片", len(req.Images))
+
+	// 处理图片：下载URL图片或使用本地路径，并按需预处理
+	imagePaths, err := s.processImages(req.Images, req.ImageOptions)
+	if err != nil {
+		return nil, err
 	}
 
-	// 处理图片：下载URL图片或使用本地路径
-	imagePaths, err := s.processImages(req.Images)
+	// 幂等键重放及重复内容检测
+	attempt, replayed, err := s.beginPublish("image", req.IdempotencyKey, req.Force, req.DryRun, req.Title, req.Content, imagePaths)
 	if err != nil {
 		return nil, err
 	}
+	if replayed != nil {
+		var resp PublishResponse
+		if err := json.Unmarshal(replayed.Result, &resp); err != nil {
+			return nil, fmt.Errorf("读取历史发布结果失败: %w", err)
+		}
+		resp.Replayed = true
+		return &resp, nil
+	}
 
 	// 构建发布内容
 	content := xiaohongshu.PublishImageContent{
 		Title:      req.Title,
 		Content:    req.Content,
 		Tags:       req.Tags,
+		Mentions:   req.Mentions,
 		ImagePaths: imagePaths,
+
+		ImageLabels: imageLabels,
+		DryRun:      req.DryRun,
 	}
 
 	// 执行发布
-	if err := s.publishContent(ctx, content); err != nil {
+	preview, postID, err := s.publishContent(ctx, tr, content)
+	if err != nil {
 		logrus.Errorf("发布内容失败: title=%s %v", content.Title, err)
+		s.finishPublish(attempt, "", nil, err)
 		return nil, err
 	}
 
 	response := &PublishResponse{
-		Title:   req.Title,
-		Content: req.Content,
-		Images:  len(imagePaths),
-		Status:  "发布完成",
+		Title:    req.Title,
+		Content:  req.Content,
+		Images:   len(imagePaths),
+		Status:   "发布完成",
+		PostID:   postID,
+		Warnings: warnings,
 	}
+	if req.DryRun {
+		response.Status = statusDryRun
+		response.Preview = newPublishPreview(preview)
+		s.addDraft("image", preview)
+	}
+	s.finishPublish(attempt, response.PostID, response, nil)
 
 	return response, nil
 }
 
+// FormatContent 将 Markdown 转换为小红书纯文本，并提取正文中的 #标签
+func (s *XiaohongshuService) FormatContent(markdown string, maxLength int) mdformat.Result {
+	opts := mdformat.DefaultOptions
+	if maxLength > 0 {
+		opts.MaxRunes = maxLength
+	}
+	return mdformat.FormatWithOptions(markdown, opts)
+}
+
+const (
+	// ruleMarkdownTruncated Markdown 正文转换后超过字数限制被截断
+	ruleMarkdownTruncated = "markdown_truncated"
+	// ruleTagsTruncated 标签超过上限，按 truncate_tags 只保留了前面的标签
+	ruleTagsTruncated = "tags_truncated"
+)
+
+// formatMarkdown 转换 Markdown 正文，提取的标签追加到已有标签之后并去重。
+// 正文超过字数限制被截断时 truncated 为 true，调用方需要把 truncatedWarning 返回给用户
+func formatMarkdown(content string, tags []string) (formatted string, merged []string, truncated bool) {
+	result := mdformat.Format(content)
+	if result.Truncated {
+		logrus.Warnf("正文超过 %d 字，已截断", mdformat.DefaultOptions.MaxRunes)
+	}
+
+	seen := make(map[string]bool, len(tags))
+	merged = make([]string, 0, len(tags)+len(result.Tags))
+	for _, tag := range slices.Concat(tags, result.Tags) {
+		key := strings.ToLower(strings.TrimLeft(tag, "#"))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		merged = append(merged, tag)
+	}
+
+	return result.Content, merged, result.Truncated
+}
+
+// truncatedWarning Markdown 正文被截断的提示，和内容检查的警告一起返回
+func truncatedWarning() contentlint.Finding {
+	return contentlint.Finding{
+		Rule:     ruleMarkdownTruncated,
+		Severity: contentlint.SeverityWarning,
+		Field:    "content",
+		Message:  fmt.Sprintf("Markdown 正文转换后超过 %d 字，已按段落截断，请检查结尾内容", mdformat.DefaultOptions.MaxRunes),
+	}
+}
+
+func tagsTruncatedWarning(count int) contentlint.Finding {
+	return contentlint.Finding{
+		Rule:     ruleTagsTruncated,
+		Severity: contentlint.SeverityWarning,
+		Field:    "tags",
+		Message:  fmt.Sprintf("标签数量 %d 超过 %d 个，已只保留前 %d 个", count, xiaohongshu.MaxTags, xiaohongshu.MaxTags),
+	}
+}
+
+// RenderTextCards 将标题和段落渲染为文字卡片图片
+func (s *XiaohongshuService) RenderTextCards(req *RenderTextCardsRequest) (*RenderTextCardsResponse, error) {
+	renderer, err := textcard.NewRenderer(req.Template, textcard.WithAssetDir(configs.GetCardAssetsDir()))
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := renderer.RenderToFiles(req.Title, req.Paragraphs, configs.GetCardsPath())
+	if err != nil {
+		return nil, err
+	}
+
+	return &RenderTextCardsResponse{
+		Paths: paths,
+		Pages: len(paths),
+		Font:  renderer.FontName(),
+	}, nil
+}
+
+// LintContent 检查待发布内容，返回全部检查结果
+func (s *XiaohongshuService) LintContent(input contentlint.Input) *contentlint.Result {
+	linter := contentlint.New(contentlint.WithSensitiveWords(configs.GetSensitiveWords()))
+	return linter.Lint(input)
+}
+
+// publishText 发布前处理后的正文和标签
+type publishText struct {
+	Content  string
+	Tags     []string
+	Warnings []contentlint.Finding // 包括 Markdown 截断、标签截断等提示
+}
+
+// preparePublishText 按 markdown、truncate_tags 处理正文和标签，并执行发布前检查。
+// 发布和执行前确认都使用这里的结果，确认时展示的就是将要发布的内容
+func (s *XiaohongshuService) preparePublishText(title, content string, tags []string, markdown, truncateTags bool) (*publishText, error) {
+	var truncated bool
+	if markdown {
+		content, tags, truncated = formatMarkdown(content, tags)
+	}
+
+	var notes []contentlint.Finding
+	if truncated {
+		notes = append(notes, truncatedWarning())
+	}
+	if truncateTags && len(tags) > xiaohongshu.MaxTags {
+		notes = append(notes, tagsTruncatedWarning(len(tags)))
+		tags = tags[:xiaohongshu.MaxTags]
+	}
+
+	// 发布前检查标题/正文长度、标签、敏感词等
+	warnings, err := s.lintBeforePublish(title, content, tags)
+	if err != nil {
+		return nil, err
+	}
+	return &publishText{Content: content, Tags: tags, Warnings: append(notes, warnings...)}, nil
+}
+
+// errVideoRequired 发布视频时没有提供视频
+var errVideoRequired = errors.New("必须提供视频文件")
+
+// checkImageCount 检查图文笔记的图片数量
+func checkImageCount(n int) error {
+	if n == 0 {
+		return errors.New("图片不能为空")
+	}
+	if n > xiaohongshu.MaxImages {
+		return fmt.Errorf("图片数量不能超过 %d 张", xiaohongshu.MaxImages)
+	}
+	return nil
+}
+
+// lintBeforePublish 发布前检查内容，存在 error 级别问题时返回 *contentlint.LintError，
+// 否则返回 warning 级别的提示，随发布结果返回给调用方
+func (s *XiaohongshuService) lintBeforePublish(title, content string, tags []string) ([]contentlint.Finding, error) {
+	result := s.LintContent(contentlint.Input{Title: title, Content: content, Tags: tags})
+	if !result.OK() {
+		return nil, &contentlint.LintError{Result: result}
+	}
+
+	var warnings []contentlint.Finding
+	for _, f := range result.Findings {
+		if f.Severity == contentlint.SeverityWarning {
+			logrus.Warnf("内容检查提示: %s %s", f.Message, f.Highlight)
+			warnings = append(warnings, f)
+		}
+	}
+	return warnings, nil
+}
+
+// buildImageLabels 校验图片标记的序号并转换为发布参数
+func buildImageLabels(opts []ImageLabelOption, imageCount int) ([]xiaohongshu.ImageLabel, error) {
+	var labels []xiaohongshu.ImageLabel
+	for _, opt := range opts {
+		if opt.Index < 0 || opt.Index >= imageCount {
+			return nil, fmt.Errorf("图片标记序号 %d 超出范围，共 %d 张图片", opt.Index, imageCount)
+		}
+		if len(opt.Labels) == 0 {
+			continue
+		}
+		labels = append(labels, xiaohongshu.ImageLabel{Index: opt.Index, Labels: opt.Labels})
+	}
+	return labels, nil
+}
+
 // processImages 处理图片列表，支持URL下载和本地路径
-func (s *XiaohongshuService) processImages(images []string) ([]string, error) {
-	processor := downloader.NewImageProcessor()
+func (s *XiaohongshuService) processImages(images []string, opts *imageproc.Options) ([]string, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("图片预处理选项错误: %w", err)
+	}
+
+	processor := downloader.NewImageProcessor(downloader.WithPreprocess(opts))
 	return processor.ProcessImages(images)
 }
 
-// publishContent 执行内容发布
-func (s *XiaohongshuService) publishContent(ctx context.Context, content xiaohongshu.PublishImageContent) error {
+// publishContent 执行内容发布，返回发布后的笔记 ID，dry run 时返回表单预览
+func (s *XiaohongshuService) publishContent(ctx context.Context, tr *progress.Tracker, content xiaohongshu.PublishImageContent) (*xiaohongshu.PublishPreview, string, error) {
+	release, err := s.acquireWithProgress(ctx, tr, opsched.Write, "publish_content")
+	if err != nil {
+		return nil, "", err
+	}
+	defer release()
+
 	b := newBrowser()
 	defer b.Close()
 
 	page := b.NewPage()
 	defer page.Close()
 
-	action, err := xiaohongshu.NewPublishImageAction(page)
+	tr.Step("打开发布页面")
+	action, err := xiaohongshu.NewPublishImageAction(page.Context(ctx))
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
 	// 执行发布
-	return action.Publish(ctx, content)
+	tr.Stepf("上传 %d 张图片并填写表单", len(content.ImagePaths))
+	if err := action.Publish(ctx, content); err != nil {
+		return nil, "", err
+	}
+
+	if !content.DryRun {
+		tr.Step("发布完成")
+		return nil, action.NoteID(), nil
+	}
+	tr.Step("生成预览")
+	preview, err := action.Preview(ctx)
+	return preview, "", err
 }
 
-// PublishVideo 发布视频（本地文件）
+// PublishVideo 发布视频（本地文件或URL）
 func (s *XiaohongshuService) PublishVideo(ctx context.Context, req *PublishVideoRequest) (*PublishVideoResponse, error) {
-	// 标题长度校验
-	if titleWidth := runewidth.StringWidth(req.Title); titleWidth > 40 {
-		return nil, fmt.Errorf("标题长度超过限制")
+	text, err := s.preparePublishText(req.Title, req.Content, req.Tags, req.Markdown, req.TruncateTags)
+	if err != nil {
+		return nil, err
 	}
+	req.Content, req.Tags = text.Content, text.Tags
+	warnings := text.Warnings
 
-	// 本地视频文件校验
 	if req.Video == "" {
-		return nil, fmt.Errorf("必须提供本地视频文件")
+		return nil, errVideoRequired
+	}
+
+	tr := progress.Start(ctx, publishSteps)
+	tr.Step("处理视频和封面")
+
+	// 下载URL视频，并在上传前校验时长、分辨率、编码和大小
+	videoPath, err := s.processVideo(req.Video)
+	if err != nil {
+		return nil, err
+	}
+
+	cover, err := s.processVideoCover(req.Cover)
+	if err != nil {
+		return nil, err
+	}
+
+	// 幂等键重放及重复内容检测
+	attempt, replayed, err := s.beginPublish("video", req.IdempotencyKey, req.Force, req.DryRun, req.Title, req.Content, []string{videoPath})
+	if err != nil {
+		return nil, err
 	}
-	if _, err := os.Stat(req.Video); err != nil {
-		return nil, fmt.Errorf("视频文件不存在或不可访问: %v", err)
+	if replayed != nil {
+		var resp PublishVideoResponse
+		if err := json.Unmarshal(replayed.Result, &resp); err != nil {
+			return nil, fmt.Errorf("读取历史发布结果失败: %w", err)
+		}
+		resp.Replayed = true
+		return &resp, nil
 	}
 
 	// 构建发布内容
@@ -246,41 +717,134 @@ func (s *XiaohongshuService) PublishVideo(ctx context.Context, req *PublishVideo
 		Title:     req.Title,
 		Content:   req.Content,
 		Tags:      req.Tags,
-		VideoPath: req.Video,
+		Mentions:  req.Mentions,
+		VideoPath: videoPath,
+		Cover:     cover,
+		DryRun:    req.DryRun,
 	}
 
 	// 执行发布
-	if err := s.publishVideo(ctx, content); err != nil {
+	preview, postID, err := s.publishVideo(ctx, tr, content)
+	if err != nil {
+		s.finishPublish(attempt, "", nil, err)
 		return nil, err
 	}
 
 	resp := &PublishVideoResponse{
-		Title:   req.Title,
-		Content: req.Content,
-		Video:   req.Video,
-		Status:  "发布完成",
+		Title:    req.Title,
+		Content:  req.Content,
+		Video:    req.Video,
+		Status:   "发布完成",
+		PostID:   postID,
+		Warnings: warnings,
+	}
+	if req.DryRun {
+		resp.Status = statusDryRun
+		resp.Preview = newPublishPreview(preview)
+		s.addDraft("video", preview)
 	}
+	s.finishPublish(attempt, resp.PostID, resp, nil)
 	return resp, nil
 }
 
-// publishVideo 执行视频发布
-func (s *XiaohongshuService) publishVideo(ctx context.Context, content xiaohongshu.PublishVideoContent) error {
+// processVideo 处理视频：下载URL视频或使用本地路径，并校验是否满足平台限制
+func (s *XiaohongshuService) processVideo(video string) (string, error) {
+	videoPath := video
+	if downloader.IsVideoURL(video) {
+		d := downloader.NewVideoDownloader(configs.GetVideosPath(), 0, downloader.PolicyFromConfig())
+		path, err := d.DownloadVideo(video)
+		if err != nil {
+			return "", fmt.Errorf("下载视频失败: %w", err)
+		}
+		videoPath = path
+	} else if _, err := os.Stat(video); err != nil {
+		return "", fmt.Errorf("视频文件不存在或不可访问: %v", err)
+	}
+
+	info, err := downloader.InspectVideo(videoPath)
+	if err != nil {
+		return "", fmt.Errorf("解析视频失败（仅支持 MP4/MOV）: %w", err)
+	}
+
+	logrus.Infof("视频信息: size=%d, duration=%s, resolution=%dx%d, codec=%s",
+		info.Size, info.Duration, info.Width, info.Height, info.Codec)
+
+	if err := downloader.DefaultVideoLimits.Validate(info); err != nil {
+		return "", err
+	}
+
+	return videoPath, nil
+}
+
+// processVideoCover 处理视频封面选项，封面图片支持URL下载和本地路径
+func (s *XiaohongshuService) processVideoCover(opt *VideoCoverOption) (*xiaohongshu.VideoCover, error) {
+	if opt == nil || (opt.Image == "" && opt.Timestamp == nil) {
+		return nil, nil
+	}
+
+	if opt.Image != "" && opt.Timestamp != nil {
+		return nil, fmt.Errorf("封面图片和封面时间点只能二选一")
+	}
+
+	if opt.Timestamp != nil {
+		if *opt.Timestamp < 0 {
+			return nil, fmt.Errorf("封面时间点不能为负数")
+		}
+		timestamp := time.Duration(*opt.Timestamp * float64(time.Second))
+		return &xiaohongshu.VideoCover{Timestamp: &timestamp}, nil
+	}
+
+	imagePaths, err := s.processImages([]string{opt.Image}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("处理封面图片失败: %w", err)
+	}
+
+	return &xiaohongshu.VideoCover{ImagePath: imagePaths[0]}, nil
+}
+
+// publishVideo 执行视频发布，返回发布后的笔记 ID，dry run 时返回表单预览
+func (s *XiaohongshuService) publishVideo(ctx context.Context, tr *progress.Tracker, content xiaohongshu.PublishVideoContent) (*xiaohongshu.PublishPreview, string, error) {
+	release, err := s.acquireWithProgress(ctx, tr, opsched.Write, "publish_with_video")
+	if err != nil {
+		return nil, "", err
+	}
+	defer release()
+
 	b := newBrowser()
 	defer b.Close()
 
 	page := b.NewPage()
 	defer page.Close()
 
-	action, err := xiaohongshu.NewPublishVideoAction(page)
+	tr.Step("打开发布页面")
+	action, err := xiaohongshu.NewPublishVideoAction(page.Context(ctx))
 	if err != nil {
-		return err
+		return nil, "", err
+	}
+
+	tr.Step("上传视频并填写表单")
+	if err := action.PublishVideo(ctx, content); err != nil {
+		return nil, "", err
 	}
 
-	return action.PublishVideo(ctx, content)
+	if !content.DryRun {
+		tr.Step("发布完成")
+		return nil, action.NoteID(), nil
+	}
+	tr.Step("生成预览")
+	preview, err := action.Preview(ctx)
+	return preview, "", err
 }
 
 // ListFeeds 获取Feeds列表
 func (s *XiaohongshuService) ListFeeds(ctx context.Context) (*FeedsListResponse, error) {
+	tr := progress.Start(ctx, readSteps)
+	release, err := s.acquireWithProgress(ctx, tr, opsched.Read, "list_feeds")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	b := newBrowser()
 	defer b.Close()
 
@@ -291,11 +855,14 @@ func (s *XiaohongshuService) ListFeeds(ctx context.Context) (*FeedsListResponse,
 	action := xiaohongshu.NewFeedsListAction(page)
 
 	// 获取 Feeds 列表
+	tr.Step("加载首页推荐")
 	feeds, err := action.GetFeedsList(ctx)
 	if err != nil {
 		logrus.Errorf("获取 Feeds 列表失败: %v", err)
 		return nil, err
 	}
+	s.rememberFeeds(feeds)
+	tr.Stepf("获取到 %d 条笔记", len(feeds))
 
 	response := &FeedsListResponse{
 		Feeds: feeds,
@@ -306,6 +873,13 @@ func (s *XiaohongshuService) ListFeeds(ctx context.Context) (*FeedsListResponse,
 }
 
 func (s *XiaohongshuService) SearchFeeds(ctx context.Context, keyword string, filters ...xiaohongshu.FilterOption) (*FeedsListResponse, error) {
+	tr := progress.Start(ctx, readSteps)
+	release, err := s.acquireWithProgress(ctx, tr, opsched.Read, "search_feeds")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	b := newBrowser()
 	defer b.Close()
 
@@ -314,10 +888,13 @@ func (s *XiaohongshuService) SearchFeeds(ctx context.Context, keyword string, fi
 
 	action := xiaohongshu.NewSearchAction(page)
 
+	tr.Stepf("搜索「%s」", keyword)
 	feeds, err := action.Search(ctx, keyword, filters...)
 	if err != nil {
 		return nil, err
 	}
+	s.rememberFeeds(feeds)
+	tr.Stepf("获取到 %d 条笔记", len(feeds))
 
 	response := &FeedsListResponse{
 		Feeds: feeds,
@@ -329,6 +906,13 @@ func (s *XiaohongshuService) SearchFeeds(ctx context.Context, keyword string, fi
 
 // GetFeedDetail 获取Feed详情
 func (s *XiaohongshuService) GetFeedDetail(ctx context.Context, feedID, xsecToken string) (*FeedDetailResponse, error) {
+	tr := progress.Start(ctx, readSteps)
+	release, err := s.acquireWithProgress(ctx, tr, opsched.Read, "get_feed_detail")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	b := newBrowser()
 	defer b.Close()
 
@@ -339,21 +923,32 @@ func (s *XiaohongshuService) GetFeedDetail(ctx context.Context, feedID, xsecToke
 	action := xiaohongshu.NewFeedDetailAction(page)
 
 	// 获取 Feed 详情
+	tr.Step("加载笔记详情和评论")
 	result, err := action.GetFeedDetail(ctx, feedID, xsecToken)
 	if err != nil {
 		return nil, err
 	}
+	tr.Step("获取完成")
 
 	response := &FeedDetailResponse{
 		FeedID: feedID,
 		Data:   result,
 	}
+	s.tokens.Set(noteURI(feedID), xsecToken)
+	s.cache.Set(noteURI(feedID), response)
 
 	return response, nil
 }
 
 // UserProfile 获取用户信息
 func (s *XiaohongshuService) UserProfile(ctx context.Context, userID, xsecToken string) (*UserProfileResponse, error) {
+	tr := progress.Start(ctx, readSteps)
+	release, err := s.acquireWithProgress(ctx, tr, opsched.Read, "user_profile")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	b := newBrowser()
 	defer b.Close()
 
@@ -362,22 +957,36 @@ func (s *XiaohongshuService) UserProfile(ctx context.Context, userID, xsecToken
 
 	action := xiaohongshu.NewUserProfileAction(page)
 
+	tr.Step("加载用户主页")
 	result, err := action.UserProfile(ctx, userID, xsecToken)
 	if err != nil {
 		return nil, err
 	}
+	tr.Step("获取完成")
 	response := &UserProfileResponse{
 		UserBasicInfo: result.UserBasicInfo,
 		Interactions:  result.Interactions,
 		Feeds:         result.Feeds,
 	}
+	s.rememberFeeds(result.Feeds)
+	s.tokens.Set(userURI(userID), xsecToken)
+	s.cache.Set(userURI(userID), response)
 
 	return response, nil
-
 }
 
 // PostCommentToFeed 发表评论到Feed
 func (s *XiaohongshuService) PostCommentToFeed(ctx context.Context, feedID, xsecToken, content string) (*PostCommentResponse, error) {
+	if err := s.limiter.Allow(currentAccount(), ratelimit.ActionComment); err != nil {
+		return nil, err
+	}
+
+	release, err := s.acquire(ctx, opsched.Write, "post_comment")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	b := newBrowser()
 	defer b.Close()
 
@@ -395,6 +1004,16 @@ func (s *XiaohongshuService) PostCommentToFeed(ctx context.Context, feedID, xsec
 
 // LikeFeed 点赞笔记
 func (s *XiaohongshuService) LikeFeed(ctx context.Context, feedID, xsecToken string) (*ActionResult, error) {
+	if err := s.limiter.Allow(currentAccount(), ratelimit.ActionLike); err != nil {
+		return nil, err
+	}
+
+	release, err := s.acquire(ctx, opsched.Write, "like_feed")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	b := newBrowser()
 	defer b.Close()
 
@@ -410,6 +1029,16 @@ func (s *XiaohongshuService) LikeFeed(ctx context.Context, feedID, xsecToken str
 
 // UnlikeFeed 取消点赞笔记
 func (s *XiaohongshuService) UnlikeFeed(ctx context.Context, feedID, xsecToken string) (*ActionResult, error) {
+	if err := s.limiter.Allow(currentAccount(), ratelimit.ActionLike); err != nil {
+		return nil, err
+	}
+
+	release, err := s.acquire(ctx, opsched.Write, "unlike_feed")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	b := newBrowser()
 	defer b.Close()
 
@@ -425,6 +1054,16 @@ func (s *XiaohongshuService) UnlikeFeed(ctx context.Context, feedID, xsecToken s
 
 // FavoriteFeed 收藏笔记
 func (s *XiaohongshuService) FavoriteFeed(ctx context.Context, feedID, xsecToken string) (*ActionResult, error) {
+	if err := s.limiter.Allow(currentAccount(), ratelimit.ActionFavorite); err != nil {
+		return nil, err
+	}
+
+	release, err := s.acquire(ctx, opsched.Write, "favorite_feed")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	b := newBrowser()
 	defer b.Close()
 
@@ -440,6 +1079,16 @@ func (s *XiaohongshuService) FavoriteFeed(ctx context.Context, feedID, xsecToken
 
 // UnfavoriteFeed 取消收藏笔记
 func (s *XiaohongshuService) UnfavoriteFeed(ctx context.Context, feedID, xsecToken string) (*ActionResult, error) {
+	if err := s.limiter.Allow(currentAccount(), ratelimit.ActionFavorite); err != nil {
+		return nil, err
+	}
+
+	release, err := s.acquire(ctx, opsched.Write, "unfavorite_feed")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	b := newBrowser()
 	defer b.Close()
 
@@ -485,8 +1134,13 @@ func withBrowserPage(fn func(*rod.Page) error) error {
 
 // GetMyProfile 获取当前登录用户的个人信息
 func (s *XiaohongshuService) GetMyProfile(ctx context.Context) (*UserProfileResponse, error) {
+	release, err := s.acquire(ctx, opsched.Read, "get_my_profile")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	var result *xiaohongshu.UserProfileResponse
-	var err error
 
 	err = withBrowserPage(func(page *rod.Page) error {
 		action := xiaohongshu.NewUserProfileAction(page)
@@ -503,6 +1157,8 @@ func (s *XiaohongshuService) GetMyProfile(ctx context.Context) (*UserProfileResp
 		Interactions:  result.Interactions,
 		Feeds:         result.Feeds,
 	}
+	s.rememberFeeds(result.Feeds)
+	s.cache.Set(resourceMe, response)
 
 	return response, nil
 }
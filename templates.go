@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/posttemplate"
+)
+
+// PublishFromTemplateRequest 使用模板发布请求
+type PublishFromTemplateRequest struct {
+	Name      string         `json:"name"`
+	Variables map[string]any `json:"variables,omitempty"`
+	Images    []string       `json:"images,omitempty"` // 追加在模板图片之后
+
+	DryRun         bool   `json:"dry_run,omitempty"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	Force          bool   `json:"force,omitempty"`
+
+	// Rendered 已确认的渲染结果，设置时直接发布，不再读取和渲染模板
+	Rendered *posttemplate.Rendered `json:"-"`
+}
+
+// ListTemplates 列出全部笔记模板
+func (s *XiaohongshuService) ListTemplates() ([]*posttemplate.Template, error) {
+	return s.templates.List()
+}
+
+// GetTemplate 获取笔记模板
+func (s *XiaohongshuService) GetTemplate(name string) (*posttemplate.Template, error) {
+	return s.templates.Get(name)
+}
+
+// SaveTemplate 创建或更新笔记模板
+func (s *XiaohongshuService) SaveTemplate(t *posttemplate.Template) (*posttemplate.Template, error) {
+	return s.templates.Save(t)
+}
+
+// DeleteTemplate 删除笔记模板
+func (s *XiaohongshuService) DeleteTemplate(name string) error {
+	return s.templates.Delete(name)
+}
+
+// PublishFromTemplate 使用变量渲染模板并发布
+func (s *XiaohongshuService) PublishFromTemplate(ctx context.Context, req *PublishFromTemplateRequest) (*PublishResponse, error) {
+	rendered := req.Rendered
+	if rendered == nil {
+		tpl, err := s.templates.Get(req.Name)
+		if err != nil {
+			return nil, err
+		}
+		if rendered, err = tpl.Render(req.Variables); err != nil {
+			return nil, err
+		}
+	}
+
+	images := append(rendered.Images, req.Images...)
+	if len(images) == 0 {
+		return nil, fmt.Errorf("模板 %s 没有图片，请通过 images 提供", req.Name)
+	}
+
+	logrus.Infof("使用模板发布: template=%s, title=%s, images=%d", req.Name, rendered.Title, len(images))
+
+	return s.PublishContent(ctx, &PublishRequest{
+		Title:    rendered.Title,
+		Content:  rendered.Content,
+		Images:   images,
+		Tags:     rendered.Tags,
+		Markdown: rendered.Markdown,
+
+		DryRun:         req.DryRun,
+		IdempotencyKey: req.IdempotencyKey,
+		Force:          req.Force,
+	})
+}
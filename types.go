@@ -1,6 +1,10 @@
 package main
 
-import "github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
+import (
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/posttemplate"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/textcard"
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
+)
 
 // HTTP API 响应类型
 
@@ -22,8 +26,9 @@ type SuccessResponse struct {
 
 // MCPToolResult MCP 工具结果（内部使用）
 type MCPToolResult struct {
-	Content []MCPContent `json:"content"`
-	IsError bool         `json:"isError,omitempty"`
+	Content    []MCPContent `json:"content"`
+	Structured any          `json:"structuredContent,omitempty"` // 与工具输出 schema 对应的结构化结果
+	IsError    bool         `json:"isError,omitempty"`
 }
 
 // MCPContent MCP 内容（内部使用）
@@ -47,8 +52,8 @@ type SearchFeedsRequest struct {
 
 // FeedDetailResponse Feed详情响应
 type FeedDetailResponse struct {
-	FeedID string `json:"feed_id"`
-	Data   any    `json:"data"`
+	FeedID string                          `json:"feed_id"`
+	Data   *xiaohongshu.FeedDetailResponse `json:"data"`
 }
 
 // PostCommentRequest 发表评论请求
@@ -71,9 +76,53 @@ type UserProfileRequest struct {
 	XsecToken string `json:"xsec_token" binding:"required"`
 }
 
+// LoginQrcodeResult 获取登录二维码的 MCP 结构化结果，二维码图片在图片内容中返回
+type LoginQrcodeResult struct {
+	IsLoggedIn bool   `json:"is_logged_in"`
+	Timeout    string `json:"timeout"`
+	Deadline   string `json:"deadline,omitempty"` // 扫码截止时间，已登录时为空
+}
+
+// DeleteCookiesResult 删除 cookies 的 MCP 结构化结果
+type DeleteCookiesResult struct {
+	Deleted    bool   `json:"deleted"`
+	CookiePath string `json:"cookie_path"`
+}
+
+// ListTemplatesResult 模板列表的 MCP 结构化结果
+type ListTemplatesResult struct {
+	Templates []*posttemplate.Template `json:"templates"`
+	Count     int                      `json:"count"`
+}
+
 // ActionResult 通用动作响应（点赞/收藏等）
 type ActionResult struct {
 	FeedID  string `json:"feed_id"`
 	Success bool   `json:"success"`
 	Message string `json:"message"`
 }
+
+// FormatContentRequest Markdown 转换请求
+type FormatContentRequest struct {
+	Content   string `json:"content" binding:"required"`
+	MaxLength int    `json:"max_length,omitempty"`
+}
+
+// RenderTextCardsRequest 文字卡片渲染请求
+type RenderTextCardsRequest struct {
+	Title      string            `json:"title"`
+	Paragraphs []string          `json:"paragraphs"`
+	Template   textcard.Template `json:"template,omitempty"`
+}
+
+// RenderTextCardsResponse 文字卡片渲染响应
+type RenderTextCardsResponse struct {
+	Paths []string `json:"paths"` // 按页排序的 PNG 文件路径，可直接用于发布的 images
+	Pages int      `json:"pages"`
+	Font  string   `json:"font"` // 实际使用的字体
+}
+
+// RejectApprovalRequest 拒绝审批请求
+type RejectApprovalRequest struct {
+	Reason string `json:"reason,omitempty"` // 拒绝原因，会保存在审批记录中
+}
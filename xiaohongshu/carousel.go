@@ -0,0 +1,440 @@
+package xiaohongshu
+
+import (
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/pkg/errors"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// MaxImages 小红书图文笔记最多支持的图片数量
+	MaxImages = 18
+
+	// MaxTags 小红书笔记最多支持的标签数量
+	MaxTags = 10
+
+	// uploadBatchSize 每批上传的图片数量，避免一次提交过多文件导致上传失败
+	uploadBatchSize = 6
+
+	// fingerprintSize 图片指纹的边长（像素），用于比对预览区的图片顺序
+	fingerprintSize = 4
+
+	// maxFingerprintDistance 预览图与原图指纹的最大距离（各格子 RGB 平均相差约 20），
+	// 超过时认为预览区的图片与请求中的任何一张都对不上
+	maxFingerprintDistance = 140
+
+	// minFingerprintGap 预览图与最接近、次接近两张原图的距离至少相差多少才认为匹配可靠。
+	// 内容相近的图片（如同一模板生成的文字卡片）指纹几乎相同，无法据此判断顺序
+	minFingerprintGap = 16
+
+	// maxFingerprintPixels 计算指纹时允许解码的最大像素数，与图片预处理的上限一致
+	maxFingerprintPixels = 100_000_000
+)
+
+// errOrderUnverifiable 预览区图片无法与请求图片可靠匹配
+var errOrderUnverifiable = errors.New("预览区图片无法与请求图片可靠匹配")
+
+// ImageLabel 单张图片的标记
+type ImageLabel struct {
+	Index  int      // 图片在 ImagePaths 中的位置，从 0 开始
+	Labels []string // 标记文字，如品牌、地点、商品
+}
+
+// uploadImagesInBatches 分批上传图片，每批上传完成后再上传下一批，保证上传顺序
+func uploadImagesInBatches(page *rod.Page, imagePaths []string) error {
+	for start := 0; start < len(imagePaths); start += uploadBatchSize {
+		end := min(start+uploadBatchSize, len(imagePaths))
+
+		pp := page.Timeout(30 * time.Second)
+		uploadInput, err := pp.Element(".upload-input")
+		if err != nil {
+			return errors.Wrap(err, "未找到图片上传输入框")
+		}
+
+		if err := uploadInput.SetFiles(imagePaths[start:end]); err != nil {
+			return errors.Wrap(err, "设置上传图片失败")
+		}
+
+		if err := waitForUploadComplete(page, end); err != nil {
+			return err
+		}
+
+		slog.Info("图片批次上传完成", "uploaded", end, "total", len(imagePaths))
+	}
+
+	return nil
+}
+
+// ensureImageOrder 校验预览区图片顺序与请求一致，不一致时拖拽调整。
+// 预览区图片数量不一致或确认顺序错误但无法调整时返回错误；
+// 无法取得指纹（图片格式无法解码、预览图跨域无法读取）或图片内容相近无法可靠匹配时，
+// 跳过校验并记录警告，不阻止发布
+func ensureImageOrder(page *rod.Page, imagePaths []string) error {
+	if len(imagePaths) < 2 {
+		return nil
+	}
+
+	expected := make([][]float64, len(imagePaths))
+	for i, path := range imagePaths {
+		fp, err := fileFingerprint(path)
+		if err != nil {
+			slog.Warn("无法计算图片指纹，跳过图片顺序校验", "path", path, "error", err)
+			return nil
+		}
+		expected[i] = fp
+	}
+
+	for attempt := 0; attempt < len(imagePaths)*2; attempt++ {
+		actual, err := waitPreviewFingerprints(page)
+		if err != nil {
+			slog.Warn("跳过图片顺序校验", "error", err)
+			return nil
+		}
+		if len(actual) != len(expected) {
+			return errors.Errorf("预览区图片数量 %d 与请求的 %d 张不一致", len(actual), len(expected))
+		}
+
+		order, err := matchImageOrder(expected, actual)
+		if err != nil {
+			slog.Warn("跳过图片顺序校验", "error", err)
+			return nil
+		}
+		pos, target := firstMisplaced(order)
+		if pos < 0 {
+			if attempt > 0 {
+				slog.Info("图片顺序已调整完成")
+			}
+			return nil
+		}
+
+		slog.Info("图片顺序与请求不一致，拖拽调整", "from", pos, "to", target, "order", order)
+		if err := dragPreview(page, pos, target); err != nil {
+			return err
+		}
+		time.Sleep(1 * time.Second)
+	}
+
+	return errors.New("图片顺序与请求不一致，且无法自动调整")
+}
+
+// firstMisplaced 找到第一个位置不正确的目标位置 target，以及应该放在该位置的图片当前所在位置 pos
+func firstMisplaced(order []int) (pos, target int) {
+	for target = range order {
+		if order[target] == target {
+			continue
+		}
+		for pos = range order {
+			if order[pos] == target {
+				return pos, target
+			}
+		}
+	}
+	return -1, -1
+}
+
+// dragPreview 将预览区第 from 张图片拖拽到第 to 张的位置
+func dragPreview(page *rod.Page, from, to int) error {
+	items, err := page.Elements(".img-preview-area .pr")
+	if err != nil || from >= len(items) || to >= len(items) {
+		return errors.New("预览区图片数量异常，无法调整顺序")
+	}
+
+	src, err := items[from].Shape()
+	if err != nil || len(src.Quads) == 0 {
+		return errors.Wrap(err, "获取图片位置失败")
+	}
+	dst, err := items[to].Shape()
+	if err != nil || len(dst.Quads) == 0 {
+		return errors.Wrap(err, "获取图片位置失败")
+	}
+
+	start := src.OnePointInside()
+	end := dst.OnePointInside()
+
+	mouse := page.Mouse
+	if err := mouse.MoveTo(*start); err != nil {
+		return err
+	}
+	if err := mouse.Down(proto.InputMouseButtonLeft, 1); err != nil {
+		return err
+	}
+	// 分步移动，触发拖拽排序组件的 move 事件
+	if err := mouse.MoveLinear(*end, 20); err != nil {
+		return err
+	}
+	time.Sleep(300 * time.Millisecond)
+	return mouse.Up(proto.InputMouseButtonLeft, 1)
+}
+
+// waitPreviewFingerprints 读取预览区图片指纹，预览图可能还在加载，失败时重试
+func waitPreviewFingerprints(page *rod.Page) ([][]float64, error) {
+	for attempt := 0; attempt < 5; attempt++ {
+		if fps, ok := previewFingerprints(page); ok {
+			return fps, nil
+		}
+		time.Sleep(1 * time.Second)
+	}
+	return nil, errors.New("无法读取预览区图片")
+}
+
+// previewFingerprints 读取预览区图片的指纹，跨域图片无法读取时返回 false
+func previewFingerprints(page *rod.Page) ([][]float64, bool) {
+	res, err := page.Eval(`(size) => Array.from(document.querySelectorAll('.img-preview-area .pr img')).map(img => {
+		if (!img.complete || img.naturalWidth === 0) {
+			return null;
+		}
+		try {
+			const canvas = document.createElement('canvas');
+			canvas.width = size;
+			canvas.height = size;
+			const ctx = canvas.getContext('2d');
+			ctx.drawImage(img, 0, 0, size, size);
+			const data = ctx.getImageData(0, 0, size, size).data;
+			const rgb = [];
+			for (let i = 0; i < data.length; i += 4) {
+				rgb.push(data[i], data[i + 1], data[i + 2]);
+			}
+			return rgb;
+		} catch (e) {
+			return null;
+		}
+	})`, fingerprintSize)
+	if err != nil {
+		return nil, false
+	}
+
+	var fps [][]float64
+	if err := res.Value.Unmarshal(&fps); err != nil {
+		return nil, false
+	}
+	for _, fp := range fps {
+		if len(fp) != fingerprintSize*fingerprintSize*3 {
+			return nil, false
+		}
+	}
+	return fps, true
+}
+
+// fileFingerprint 将图片缩小到 fingerprintSize x fingerprintSize，返回各格子的平均 RGB。
+// 无法解码的格式（BMP、HEIC 等）和尺寸过大的图片返回错误
+func fileFingerprint(path string) ([]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return nil, err
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxFingerprintPixels {
+		return nil, errors.Errorf("图片尺寸 %dx%d 过大", cfg.Width, cfg.Height)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return imageFingerprint(img), nil
+}
+
+func imageFingerprint(img image.Image) []float64 {
+	b := img.Bounds()
+	fp := make([]float64, 0, fingerprintSize*fingerprintSize*3)
+
+	for gy := 0; gy < fingerprintSize; gy++ {
+		y0 := b.Min.Y + gy*b.Dy()/fingerprintSize
+		y1 := b.Min.Y + (gy+1)*b.Dy()/fingerprintSize
+		for gx := 0; gx < fingerprintSize; gx++ {
+			x0 := b.Min.X + gx*b.Dx()/fingerprintSize
+			x1 := b.Min.X + (gx+1)*b.Dx()/fingerprintSize
+
+			var r, g, bl, n float64
+			for y := y0; y < max(y1, y0+1); y++ {
+				for x := x0; x < max(x1, x0+1); x++ {
+					cr, cg, cb, _ := img.At(x, y).RGBA()
+					r += float64(cr >> 8)
+					g += float64(cg >> 8)
+					bl += float64(cb >> 8)
+					n++
+				}
+			}
+			fp = append(fp, r/n, g/n, bl/n)
+		}
+	}
+
+	return fp
+}
+
+// matchImageOrder 将预览区图片与请求图片按指纹距离一一匹配，
+// 返回 order[i] 表示预览区第 i 张对应请求中的第几张。
+// 有预览图与所有原图都相差过大，或与两张原图同样接近时返回 errOrderUnverifiable
+func matchImageOrder(expected, actual [][]float64) ([]int, error) {
+	for j, a := range actual {
+		best, second := math.Inf(1), math.Inf(1)
+		for _, e := range expected {
+			d := fingerprintDistance(e, a)
+			if d < best {
+				best, second = d, best
+			} else if d < second {
+				second = d
+			}
+		}
+		if best > maxFingerprintDistance {
+			return nil, errors.Wrapf(errOrderUnverifiable, "预览区第 %d 张与所有图片都不相似", j+1)
+		}
+		if second-best < minFingerprintGap {
+			return nil, errors.Wrapf(errOrderUnverifiable, "预览区第 %d 张与多张图片同样相似", j+1)
+		}
+	}
+
+	type pair struct {
+		exp, act int
+		dist     float64
+	}
+
+	var pairs []pair
+	for i, e := range expected {
+		for j, a := range actual {
+			pairs = append(pairs, pair{exp: i, act: j, dist: fingerprintDistance(e, a)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].dist < pairs[j].dist })
+
+	order := make([]int, len(actual))
+	for i := range order {
+		order[i] = -1
+	}
+	usedExp := make([]bool, len(expected))
+	for _, p := range pairs {
+		if usedExp[p.exp] || order[p.act] >= 0 {
+			continue
+		}
+		usedExp[p.exp] = true
+		order[p.act] = p.exp
+	}
+
+	return order, nil
+}
+
+func fingerprintDistance(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return math.Sqrt(sum)
+}
+
+// addImageLabels 为指定图片添加标记，需在上传及排序完成后调用
+func addImageLabels(page *rod.Page, imageCount int, labels []ImageLabel) error {
+	for _, label := range labels {
+		if len(label.Labels) == 0 {
+			continue
+		}
+		if label.Index < 0 || label.Index >= imageCount {
+			return errors.Errorf("图片标记的序号 %d 超出范围（共 %d 张图片）", label.Index, imageCount)
+		}
+
+		if err := addLabelsToImage(page, label.Index, label.Labels); err != nil {
+			return errors.Wrapf(err, "第 %d 张图片添加标记失败", label.Index+1)
+		}
+	}
+
+	return nil
+}
+
+// addLabelsToImage 打开第 index 张图片的编辑器，逐个添加标记后保存
+func addLabelsToImage(page *rod.Page, index int, labels []string) error {
+	pp := page.Timeout(30 * time.Second)
+
+	items, err := pp.Elements(".img-preview-area .pr")
+	if err != nil || index >= len(items) {
+		return errors.New("未找到对应的预览图片")
+	}
+
+	// 悬停后出现编辑按钮，没有时直接点击图片进入编辑器
+	items[index].MustHover()
+	time.Sleep(300 * time.Millisecond)
+	if editBtn, err := items[index].ElementR("div, span", `^\s*编辑\s*$`); err == nil {
+		editBtn.MustClick()
+	} else {
+		items[index].MustClick()
+	}
+
+	modal, err := pp.Element("div.d-modal")
+	if err != nil {
+		return errors.Wrap(err, "图片编辑弹窗没有出现")
+	}
+	modal.MustWaitVisible()
+	time.Sleep(1 * time.Second)
+
+	markTab, err := modal.ElementR("div, span", `^\s*标记\s*$`)
+	if err != nil {
+		return errors.Wrap(err, "没有找到标记按钮")
+	}
+	markTab.MustClick()
+	time.Sleep(500 * time.Millisecond)
+
+	for _, text := range labels {
+		if err := addLabel(modal, text); err != nil {
+			return err
+		}
+	}
+
+	confirmBtn, err := modal.ElementR("button", `^\s*(确定|完成)\s*$`)
+	if err != nil {
+		return errors.Wrap(err, "没有找到图片编辑确认按钮")
+	}
+	confirmBtn.MustClick()
+
+	if err := modal.Timeout(30 * time.Second).WaitInvisible(); err != nil {
+		return errors.Wrap(err, "等待图片编辑弹窗关闭超时")
+	}
+
+	slog.Info("图片标记添加完成", "index", index, "labels", labels)
+	return nil
+}
+
+// addLabel 点击图片画布添加一个标记，并选中第一个联想结果
+func addLabel(modal *rod.Element, text string) error {
+	canvas, err := modal.Element("canvas, img")
+	if err != nil {
+		return errors.Wrap(err, "未找到图片编辑画布")
+	}
+	canvas.MustClick()
+	time.Sleep(500 * time.Millisecond)
+
+	searchInput, err := modal.Timeout(5 * time.Second).Element("input[type='text'], input:not([type])")
+	if err != nil {
+		return errors.Wrap(err, "未找到标记搜索框")
+	}
+	searchInput.MustSelectAllText().MustInput(text)
+	time.Sleep(1 * time.Second)
+
+	item, err := modal.Timeout(5 * time.Second).Element(".item, li")
+	if err != nil {
+		return errors.Errorf("标记 %s 没有联想结果", text)
+	}
+	item.MustClick()
+	time.Sleep(500 * time.Millisecond)
+
+	return nil
+}
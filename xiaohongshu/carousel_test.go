@@ -0,0 +1,159 @@
+package xiaohongshu
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func solidImage(c color.Color) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 30))
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 40; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestImageFingerprint(t *testing.T) {
+	fp := imageFingerprint(solidImage(color.RGBA{R: 200, G: 100, B: 50, A: 255}))
+
+	require.Len(t, fp, fingerprintSize*fingerprintSize*3)
+	for i := 0; i < len(fp); i += 3 {
+		assert.InDelta(t, 200, fp[i], 0.01)
+		assert.InDelta(t, 100, fp[i+1], 0.01)
+		assert.InDelta(t, 50, fp[i+2], 0.01)
+	}
+}
+
+func TestFileFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	red := filepath.Join(dir, "red.png")
+	f, err := os.Create(red)
+	require.NoError(t, err)
+	require.NoError(t, png.Encode(f, solidImage(color.RGBA{R: 255, A: 255})))
+	require.NoError(t, f.Close())
+
+	// 文件头声明 50000x50000 的 PNG
+	huge, err := os.ReadFile(red)
+	require.NoError(t, err)
+	binary.BigEndian.PutUint32(huge[16:20], 50000)
+	binary.BigEndian.PutUint32(huge[20:24], 50000)
+	binary.BigEndian.PutUint32(huge[29:33], crc32.ChecksumIEEE(huge[12:29]))
+	hugePath := filepath.Join(dir, "huge.png")
+	require.NoError(t, os.WriteFile(hugePath, huge, 0644))
+
+	// 平台接受但无法解码的格式
+	bmpPath := filepath.Join(dir, "image.bmp")
+	require.NoError(t, os.WriteFile(bmpPath, append([]byte("BM"), make([]byte, 64)...), 0644))
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{name: "PNG", path: red},
+		{name: "文件不存在", path: filepath.Join(dir, "missing.png"), wantErr: true},
+		{name: "尺寸过大", path: hugePath, wantErr: true},
+		{name: "无法解码的格式", path: bmpPath, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fp, err := fileFingerprint(tt.path)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDelta(t, 255, fp[0], 0.01)
+		})
+	}
+}
+
+func TestMatchImageOrder(t *testing.T) {
+	red := imageFingerprint(solidImage(color.RGBA{R: 255, A: 255}))
+	green := imageFingerprint(solidImage(color.RGBA{G: 255, A: 255}))
+	blue := imageFingerprint(solidImage(color.RGBA{B: 255, A: 255}))
+
+	// 预览区图片带有轻微压缩误差
+	noisy := func(fp []float64, delta float64) []float64 {
+		out := make([]float64, len(fp))
+		for i, v := range fp {
+			out[i] = v + delta
+		}
+		return out
+	}
+
+	// 同一模板的文字卡片，只有少量文字像素不同
+	card1 := imageFingerprint(solidImage(color.RGBA{R: 250, G: 250, B: 250, A: 255}))
+	card2 := imageFingerprint(solidImage(color.RGBA{R: 248, G: 248, B: 248, A: 255}))
+
+	tests := []struct {
+		name     string
+		expected [][]float64
+		actual   [][]float64
+		want     []int
+		wantErr  bool
+	}{
+		{
+			name:     "顺序一致",
+			expected: [][]float64{red, green, blue},
+			actual:   [][]float64{noisy(red, 3), noisy(green, -2), noisy(blue, 1)},
+			want:     []int{0, 1, 2},
+		},
+		{
+			name:     "顺序打乱",
+			expected: [][]float64{red, green, blue},
+			actual:   [][]float64{noisy(blue, 2), red, noisy(green, 4)},
+			want:     []int{2, 0, 1},
+		},
+		{
+			name:     "预览图与所有图片都不相似",
+			expected: [][]float64{red, green},
+			actual:   [][]float64{red, blue},
+			wantErr:  true,
+		},
+		{
+			name:     "内容相近的图片无法区分",
+			expected: [][]float64{card1, card2, red},
+			actual:   [][]float64{card2, card1, red},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := matchImageOrder(tt.expected, tt.actual)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, errOrderUnverifiable)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, order)
+		})
+	}
+}
+
+func TestFirstMisplaced(t *testing.T) {
+	pos, target := firstMisplaced([]int{0, 1, 2})
+	assert.Equal(t, -1, pos)
+	assert.Equal(t, -1, target)
+
+	pos, target = firstMisplaced([]int{2, 0, 1})
+	assert.Equal(t, 1, pos)
+	assert.Equal(t, 0, target)
+
+	pos, target = firstMisplaced([]int{0, 2, 1})
+	assert.Equal(t, 2, pos)
+	assert.Equal(t, 1, target)
+}
@@ -0,0 +1,128 @@
+package xiaohongshu
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/input"
+	"github.com/pkg/errors"
+)
+
+// mentionLinkSelector 正文中已生成的 @用户链接
+const mentionLinkSelector = `a.mention, span.mention, [data-type="mention"]`
+
+// inputMentions 在正文末尾逐个 @用户，并校验是否生成了真实的 @ 链接
+func inputMentions(contentElem *rod.Element, mentions []string) error {
+	if len(mentions) == 0 {
+		return nil
+	}
+
+	time.Sleep(1 * time.Second)
+
+	// 光标移动到正文末尾
+	for i := 0; i < 20; i++ {
+		contentElem.MustKeyActions().
+			Type(input.ArrowDown).
+			MustDo()
+		time.Sleep(10 * time.Millisecond)
+	}
+	contentElem.MustKeyActions().Type(input.End).MustDo()
+
+	for _, mention := range mentions {
+		mention = strings.TrimSpace(strings.TrimLeft(mention, "@"))
+		if mention == "" {
+			continue
+		}
+
+		if err := inputMention(contentElem, mention); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func inputMention(contentElem *rod.Element, name string) error {
+	before := countMentionLinks(contentElem)
+
+	contentElem.MustInput(" @")
+	time.Sleep(200 * time.Millisecond)
+
+	for _, char := range name {
+		contentElem.MustInput(string(char))
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	time.Sleep(1500 * time.Millisecond)
+
+	item, err := findMentionItem(contentElem.Page(), name)
+	if err != nil {
+		return err
+	}
+	item.MustClick()
+	slog.Info("成功点击@用户联想选项", "mention", name)
+
+	time.Sleep(500 * time.Millisecond)
+
+	// 选中后编辑器会把 @name 替换为带链接的 mention 节点
+	if countMentionLinks(contentElem) <= before {
+		return errors.Errorf("@%s 未能生成有效的用户链接", name)
+	}
+
+	return nil
+}
+
+// findMentionItem 在 @ 联想下拉框中查找昵称或小红书号完全一致的用户。
+// 没有完全一致的选项时返回错误，避免在公开笔记中 @ 错人。
+func findMentionItem(page *rod.Page, name string) (*rod.Element, error) {
+	container, err := page.Timeout(5 * time.Second).Element("#creator-editor-mention-container")
+	if err != nil || container == nil {
+		return nil, errors.Errorf("未找到@用户联想下拉框: %s", name)
+	}
+
+	items, err := container.Elements(".item")
+	if err != nil || len(items) == 0 {
+		return nil, errors.Errorf("未找到@用户联想选项: %s", name)
+	}
+
+	var candidates []string
+	for _, item := range items {
+		text, err := item.Text()
+		if err != nil {
+			continue
+		}
+		if mentionTextMatches(text, name) {
+			return item, nil
+		}
+		if first, _, _ := strings.Cut(strings.TrimSpace(text), "\n"); first != "" {
+			candidates = append(candidates, first)
+		}
+	}
+
+	return nil, errors.Errorf("未找到与 @%s 完全一致的用户（联想结果: %s），请填写准确的昵称或小红书号",
+		name, strings.Join(candidates, "、"))
+}
+
+// mentionTextMatches 判断联想选项文本中是否包含完全一致的昵称或小红书号
+func mentionTextMatches(text, name string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimPrefix(line, "小红书号：")
+		line = strings.TrimPrefix(line, "小红书号:")
+		if strings.EqualFold(strings.TrimSpace(line), name) {
+			return true
+		}
+	}
+	return false
+}
+
+func countMentionLinks(contentElem *rod.Element) int {
+	res, err := contentElem.Eval(`(selector) => this.querySelectorAll(selector).length`, mentionLinkSelector)
+	if err != nil {
+		slog.Warn("统计@用户链接失败", "error", err)
+		return 0
+	}
+	return res.Value.Int()
+}
@@ -0,0 +1,26 @@
+package xiaohongshu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMentionTextMatches(t *testing.T) {
+	tests := []struct {
+		text     string
+		name     string
+		expected bool
+	}{
+		{"小红薯\n小红书号：123456", "小红薯", true},
+		{"小红薯\n小红书号：123456", "123456", true},
+		{"小红薯\n小红书号: 123456", "123456", true},
+		{"Hello World", "hello world", true},
+		{"小红薯本薯\n小红书号：123456", "小红薯", false},
+		{"", "小红薯", false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, mentionTextMatches(test.text, test.name), "text=%q name=%q", test.text, test.name)
+	}
+}
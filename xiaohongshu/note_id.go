@@ -0,0 +1,98 @@
+package xiaohongshu
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// noteIDTimeout 点击发布后等待发布接口响应的最长时间
+const noteIDTimeout = 15 * time.Second
+
+// watchNoteID 在点击发布前调用，监听发布接口的响应。
+// 返回的 wait 在接口响应或超时后返回笔记 ID，没有捕获到时返回空字符串
+func watchNoteID(page *rod.Page) (wait func() string) {
+	var (
+		requestID proto.NetworkRequestID
+		noteID    string
+	)
+
+	waitEvent := page.Timeout(noteIDTimeout).EachEvent(
+		func(e *proto.NetworkResponseReceived) {
+			if isPublishNoteURL(e.Response.URL) {
+				requestID = e.RequestID
+			}
+		},
+		func(e *proto.NetworkLoadingFinished) bool {
+			if requestID == "" || e.RequestID != requestID {
+				return false
+			}
+			// 等待结束后 Network 域会被关闭，需要在这里读取响应内容
+			body, err := responseBody(page, requestID)
+			if err != nil {
+				slog.Warn("读取发布接口响应失败", "error", err)
+				return true
+			}
+			noteID = parseNoteID(body)
+			return true
+		},
+	)
+
+	return func() string {
+		waitEvent()
+		if noteID == "" {
+			slog.Warn("未获取到发布后的笔记 ID", "captured_response", requestID != "")
+		}
+		return noteID
+	}
+}
+
+// isPublishNoteURL 是否为创作者中心的发布笔记接口
+func isPublishNoteURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return strings.HasSuffix(u.Path, "/web_api/sns/v2/note")
+}
+
+func responseBody(page *rod.Page, requestID proto.NetworkRequestID) ([]byte, error) {
+	res, err := proto.NetworkGetResponseBody{RequestID: requestID}.Call(page)
+	if err != nil {
+		return nil, err
+	}
+	if res.Base64Encoded {
+		return base64.StdEncoding.DecodeString(res.Body)
+	}
+	return []byte(res.Body), nil
+}
+
+// parseNoteID 从发布接口的响应中解析笔记 ID，兼容 data.id、data.note_id 等字段
+func parseNoteID(body []byte) string {
+	var resp struct {
+		Success *bool `json:"success"`
+		Data    struct {
+			ID          string `json:"id"`
+			NoteID      string `json:"note_id"`
+			NoteIDCamel string `json:"noteId"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return ""
+	}
+	if resp.Success != nil && !*resp.Success {
+		return ""
+	}
+	for _, id := range []string{resp.Data.ID, resp.Data.NoteID, resp.Data.NoteIDCamel} {
+		if id != "" {
+			return id
+		}
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+package xiaohongshu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNoteID(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"data.id", `{"success":true,"data":{"id":"64f1a2b3c4d5e6f7a8b9c0d1","score":10}}`, "64f1a2b3c4d5e6f7a8b9c0d1"},
+		{"data.note_id", `{"success":true,"data":{"note_id":"abc"}}`, "abc"},
+		{"data.noteId", `{"data":{"noteId":"abc"}}`, "abc"},
+		{"发布失败", `{"success":false,"msg":"内容违规","data":{"id":"abc"}}`, ""},
+		{"没有 ID", `{"success":true,"data":{}}`, ""},
+		{"不是 JSON", `<html></html>`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseNoteID([]byte(tt.body)))
+		})
+	}
+}
+
+func TestIsPublishNoteURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected bool
+	}{
+		{"https://edith.xiaohongshu.com/web_api/sns/v2/note", true},
+		{"https://edith.xiaohongshu.com/web_api/sns/v2/note?t=1", true},
+		{"https://edith.xiaohongshu.com/web_api/sns/v2/note/abc", false},
+		{"https://creator.xiaohongshu.com/api/galaxy/creator/note/user/posted", false},
+		{"://bad", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, isPublishNoteURL(tt.url), tt.url)
+	}
+}
@@ -0,0 +1,84 @@
+package xiaohongshu
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/pkg/errors"
+)
+
+// PublishPreview dry run 模式下从发布页表单读取的实际填写内容及整页截图
+type PublishPreview struct {
+	Title      string   `json:"title"`
+	Content    string   `json:"content"`
+	Tags       []string `json:"tags"`
+	Mentions   []string `json:"mentions"`
+	ImageCount int      `json:"image_count"`
+	HasVideo   bool     `json:"has_video"`
+
+	Screenshot []byte `json:"-"` // PNG 格式
+}
+
+// Preview 读取当前已填写的发布表单并截取整页截图，需在 DryRun 发布后调用
+func (p *PublishAction) Preview(ctx context.Context) (*PublishPreview, error) {
+	page := p.page.Context(ctx)
+
+	// 等待标签、@用户等渲染完成
+	time.Sleep(1 * time.Second)
+
+	preview := &PublishPreview{}
+
+	if titleElem, err := page.Element("div.d-input input"); err == nil {
+		if v, err := titleElem.Property("value"); err == nil {
+			preview.Title = v.String()
+		}
+	}
+
+	if contentElem, ok := getContentElement(page); ok {
+		if text, err := contentElem.Text(); err == nil {
+			preview.Content = strings.TrimSpace(text)
+		}
+		preview.Tags = elementTexts(contentElem, `a.topic, span.topic, [data-type="topic"], .tiptap-topic`)
+		preview.Mentions = elementTexts(contentElem, mentionLinkSelector)
+	}
+
+	if items, err := page.Elements(".img-preview-area .pr"); err == nil {
+		preview.ImageCount = len(items)
+	}
+	if has, _, err := page.Has("video"); err == nil {
+		preview.HasVideo = has
+	}
+
+	screenshot, err := page.Screenshot(true, &proto.PageCaptureScreenshot{
+		Format: proto.PageCaptureScreenshotFormatPng,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "截取发布页截图失败")
+	}
+	preview.Screenshot = screenshot
+
+	return preview, nil
+}
+
+// elementTexts 返回匹配元素的文本列表
+func elementTexts(parent *rod.Element, selector string) []string {
+	elems, err := parent.Elements(selector)
+	if err != nil {
+		return nil
+	}
+
+	texts := make([]string, 0, len(elems))
+	for _, elem := range elems {
+		text, err := elem.Text()
+		if err != nil {
+			continue
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return texts
+}
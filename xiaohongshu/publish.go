@@ -20,11 +20,20 @@ type PublishImageContent struct {
 	Title      string
 	Content    string
 	Tags       []string
+	Mentions   []string // @用户，昵称或小红书号
 	ImagePaths []string
+
+	// ImageLabels 单张图片的标记，可选
+	ImageLabels []ImageLabel
+
+	// DryRun 只填写表单，不点击发布
+	DryRun bool
 }
 
 type PublishAction struct {
 	page *rod.Page
+
+	noteID string
 }
 
 const (
@@ -54,6 +63,9 @@ func (p *PublishAction) Publish(ctx context.Context, content PublishImageContent
 	if len(content.ImagePaths) == 0 {
 		return errors.New("图片不能为空")
 	}
+	if len(content.ImagePaths) > MaxImages {
+		return errors.Errorf("图片数量不能超过 %d 张，当前 %d 张", MaxImages, len(content.ImagePaths))
+	}
 
 	page := p.page.Context(ctx)
 
@@ -61,21 +73,33 @@ func (p *PublishAction) Publish(ctx context.Context, content PublishImageContent
 		return errors.Wrap(err, "小红书上传图片失败")
 	}
 
+	if err := addImageLabels(page, len(content.ImagePaths), content.ImageLabels); err != nil {
+		return errors.Wrap(err, "小红书图片标记失败")
+	}
+
+	// 服务层发布前已检查标签数量，这里只防止超出平台限制
 	tags := content.Tags
-	if len(tags) >= 10 {
-		logrus.Warnf("标签数量超过10，截取前10个标签")
-		tags = tags[:10]
+	if len(tags) > MaxTags {
+		logrus.Warnf("标签数量超过%d，截取前%d个标签", MaxTags, MaxTags)
+		tags = tags[:MaxTags]
 	}
 
-	logrus.Infof("发布内容: title=%s, images=%v, tags=%v", content.Title, len(content.ImagePaths), tags)
+	logrus.Infof("发布内容: title=%s, images=%v, tags=%v, mentions=%v", content.Title, len(content.ImagePaths), tags, content.Mentions)
 
-	if err := submitPublish(page, content.Title, content.Content, tags); err != nil {
+	noteID, err := submitPublish(page, content.Title, content.Content, tags, content.Mentions, content.DryRun)
+	if err != nil {
 		return errors.Wrap(err, "小红书发布失败")
 	}
+	p.noteID = noteID
 
 	return nil
 }
 
+// NoteID 发布成功后的笔记 ID，从发布接口的响应中获取，没有获取到或 dry run 时为空
+func (p *PublishAction) NoteID() string {
+	return p.noteID
+}
+
 func removePopCover(page *rod.Page) {
 
 	// 先移除弹窗封面
@@ -184,28 +208,22 @@ func isElementBlocked(elem *rod.Element) (bool, error) {
 }
 
 func uploadImages(page *rod.Page, imagesPaths []string) error {
-	pp := page.Timeout(30 * time.Second)
-
-	// 验证文件路径有效性
-	validPaths := make([]string, 0, len(imagesPaths))
+	// 验证文件路径有效性，任意一张缺失都直接失败，避免少图发布
 	for _, path := range imagesPaths {
-		if _, err := os.Stat(path); os.IsNotExist(err) {
-			logrus.Warnf("图片文件不存在: %s", path)
-			continue
+		if _, err := os.Stat(path); err != nil {
+			return errors.Wrapf(err, "图片文件不存在或不可访问: %s", path)
 		}
-		validPaths = append(validPaths, path)
 
 		logrus.Infof("获取有效图片：%s", path)
 	}
 
-	// 等待上传输入框出现
-	uploadInput := pp.MustElement(".upload-input")
-
-	// 上传多个文件
-	uploadInput.MustSetFiles(validPaths...)
+	// 分批上传，保证上传顺序并避免一次提交过多文件
+	if err := uploadImagesInBatches(page, imagesPaths); err != nil {
+		return err
+	}
 
-	// 等待并验证上传完成
-	return waitForUploadComplete(pp, len(validPaths))
+	// 校验预览区顺序，站点打乱顺序时拖拽调整
+	return ensureImageOrder(page, imagesPaths)
 }
 
 // waitForUploadComplete 等待并验证上传完成
@@ -239,7 +257,7 @@ func waitForUploadComplete(page *rod.Page, expectedCount int) error {
 	return errors.New("上传超时，请检查网络连接和图片大小")
 }
 
-func submitPublish(page *rod.Page, title, content string, tags []string) error {
+func submitPublish(page *rod.Page, title, content string, tags, mentions []string, dryRun bool) (string, error) {
 
 	titleElem := page.MustElement("div.d-input input")
 	titleElem.MustInput(title)
@@ -249,20 +267,57 @@ func submitPublish(page *rod.Page, title, content string, tags []string) error {
 	if contentElem, ok := getContentElement(page); ok {
 		contentElem.MustInput(content)
 
+		if err := inputMentions(contentElem, mentions); err != nil {
+			return "", err
+		}
+
 		inputTags(contentElem, tags)
 
 	} else {
-		return errors.New("没有找到内容输入框")
+		return "", errors.New("没有找到内容输入框")
 	}
 
 	time.Sleep(1 * time.Second)
 
+	if dryRun {
+		slog.Info("dry run 模式，表单已填写，跳过点击发布")
+		return "", nil
+	}
+
 	submitButton := page.MustElement("div.submit div.d-button-content")
-	submitButton.MustClick()
+	return clickSubmit(page, submitButton)
+}
 
-	time.Sleep(3 * time.Second)
+// SubmittedError 点击发布按钮之后出现的错误，笔记可能已经发布，结果未知
+type SubmittedError struct {
+	Err error
+}
 
-	return nil
+func (e *SubmittedError) Error() string { return e.Err.Error() }
+
+func (e *SubmittedError) Unwrap() error { return e.Err }
+
+// IsSubmitted 错误是否发生在点击发布按钮之后
+func IsSubmitted(err error) bool {
+	var se *SubmittedError
+	return errors.As(err, &se)
+}
+
+// clickSubmit 点击发布按钮并等待提交完成，返回发布接口响应中的笔记 ID（没有获取到时为空）。
+// 点击事件可能已经送达页面，点击过程中的错误（包括 context 取消、超时）都标记为 SubmittedError
+func clickSubmit(page *rod.Page, btn *rod.Element) (string, error) {
+	waitNoteID := watchNoteID(page)
+	if err := btn.Click(proto.InputMouseButtonLeft, 1); err != nil {
+		go waitNoteID() // 结束监听
+		return "", &SubmittedError{Err: errors.Wrap(err, "点击发布按钮失败")}
+	}
+
+	noteID := waitNoteID()
+	if noteID == "" {
+		// 没有等到接口响应时保留原来的等待，确保提交完成
+		time.Sleep(3 * time.Second)
+	}
+	return noteID, nil
 }
 
 // 查找内容输入框 - 使用Race方法处理两种样式
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/xpzouying/xiaohongshu-mcp/browser"
 
 	"github.com/stretchr/testify/assert"
@@ -30,3 +31,21 @@ func TestPublish(t *testing.T) {
 	})
 	assert.NoError(t, err)
 }
+
+func TestIsSubmitted(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "点击发布前失败", err: errors.Wrap(errors.New("没有找到内容输入框"), "小红书发布失败"), want: false},
+		{name: "点击发布后出错", err: errors.Wrap(&SubmittedError{Err: context.DeadlineExceeded}, "小红书发布失败"), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsSubmitted(tt.err))
+		})
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"time"
 
 	"github.com/go-rod/rod"
-	"github.com/go-rod/rod/lib/proto"
 	"github.com/pkg/errors"
 )
 
@@ -17,7 +16,10 @@ type PublishVideoContent struct {
 	Title     string
 	Content   string
 	Tags      []string
+	Mentions  []string // @用户，昵称或小红书号
 	VideoPath string
+	Cover     *VideoCover // 可选，为空时使用平台自动选取的封面
+	DryRun    bool        // 只填写表单，不点击发布
 }
 
 // NewPublishVideoAction 进入发布页并切换到“上传视频”
@@ -48,9 +50,15 @@ func (p *PublishAction) PublishVideo(ctx context.Context, content PublishVideoCo
 		return errors.Wrap(err, "小红书上传视频失败")
 	}
 
-	if err := submitPublishVideo(page, content.Title, content.Content, content.Tags); err != nil {
+	if err := setVideoCover(page, content.Cover); err != nil {
+		return errors.Wrap(err, "小红书设置视频封面失败")
+	}
+
+	noteID, err := submitPublishVideo(page, content.Title, content.Content, content.Tags, content.Mentions, content.DryRun)
+	if err != nil {
 		return errors.Wrap(err, "小红书发布失败")
 	}
+	p.noteID = noteID
 	return nil
 }
 
@@ -115,19 +123,22 @@ func waitForPublishButtonClickable(page *rod.Page) (*rod.Element, error) {
 	return nil, errors.New("等待发布按钮可点击超时")
 }
 
-// submitPublishVideo 填写标题、正文、标签并点击发布（等待按钮可点击后再提交）
-func submitPublishVideo(page *rod.Page, title, content string, tags []string) error {
+// submitPublishVideo 填写标题、正文、标签并点击发布（等待按钮可点击后再提交），dryRun 时不点击
+func submitPublishVideo(page *rod.Page, title, content string, tags, mentions []string, dryRun bool) (string, error) {
 	// 标题
 	titleElem := page.MustElement("div.d-input input")
 	titleElem.MustInput(title)
 	time.Sleep(1 * time.Second)
 
-	// 正文 + 标签
+	// 正文 + @用户 + 标签
 	if contentElem, ok := getContentElement(page); ok {
 		contentElem.MustInput(content)
+		if err := inputMentions(contentElem, mentions); err != nil {
+			return "", err
+		}
 		inputTags(contentElem, tags)
 	} else {
-		return errors.New("没有找到内容输入框")
+		return "", errors.New("没有找到内容输入框")
 	}
 
 	time.Sleep(1 * time.Second)
@@ -135,14 +146,14 @@ func submitPublishVideo(page *rod.Page, title, content string, tags []string) er
 	// 等待发布按钮可点击
 	btn, err := waitForPublishButtonClickable(page)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	// 点击发布
-	if err := btn.Click(proto.InputMouseButtonLeft, 1); err != nil {
-		return errors.Wrap(err, "点击发布按钮失败")
+	if dryRun {
+		slog.Info("dry run 模式，视频表单已填写，跳过点击发布")
+		return "", nil
 	}
 
-	time.Sleep(3 * time.Second)
-	return nil
+	// 点击发布
+	return clickSubmit(page, btn)
 }
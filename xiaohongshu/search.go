@@ -66,6 +66,26 @@ var filterOptionsMap = map[int][]internalFilterOption{
 	},
 }
 
+// filterFields 筛选组索引对应的 FilterOption 字段（JSON 名称）
+var filterFields = map[int]string{
+	1: "sort_by",
+	2: "note_type",
+	3: "publish_time",
+	4: "search_scope",
+	5: "location",
+}
+
+// FilterValues 返回 FilterOption 各字段的可选值，key 为字段的 JSON 名称，如 sort_by
+func FilterValues() map[string][]string {
+	values := make(map[string][]string, len(filterFields))
+	for index, field := range filterFields {
+		for _, option := range filterOptionsMap[index] {
+			values[field] = append(values[field], option.Text)
+		}
+	}
+	return values
+}
+
 // convertToInternalFilters 将 FilterOption 转换为内部的 internalFilterOption 列表
 func convertToInternalFilters(filter FilterOption) ([]internalFilterOption, error) {
 	var internalFilters []internalFilterOption
@@ -103,3 +103,24 @@ func TestFilterValidation(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, internalFilters, 5)
 }
+
+func TestFilterValues(t *testing.T) {
+	values := FilterValues()
+	require.Equal(t, []string{"综合", "最新", "最多点赞", "最多评论", "最多收藏"}, values["sort_by"])
+	require.Len(t, values, 5)
+
+	// 每个可选值都能转换为内部筛选选项
+	for _, sortBy := range values["sort_by"] {
+		for _, location := range values["location"] {
+			filters, err := convertToInternalFilters(FilterOption{
+				SortBy:      sortBy,
+				NoteType:    values["note_type"][0],
+				PublishTime: values["publish_time"][0],
+				SearchScope: values["search_scope"][0],
+				Location:    location,
+			})
+			require.NoError(t, err)
+			require.Len(t, filters, 5)
+		}
+	}
+}
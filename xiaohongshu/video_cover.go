@@ -0,0 +1,163 @@
+package xiaohongshu
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/pkg/errors"
+)
+
+// coverFrameTolerance 编辑器选中的封面帧与指定时间点允许的误差，编辑器可能对齐到关键帧
+const coverFrameTolerance = 0.5
+
+// VideoCover 视频封面设置，ImagePath 与 Timestamp 二选一
+type VideoCover struct {
+	ImagePath string         // 自定义封面图片的本地路径
+	Timestamp *time.Duration // 从视频中截取指定时间点的帧作为封面，nil 表示未设置，0 为第一帧
+}
+
+// IsEmpty 是否未设置封面（使用平台自动选取的封面）
+func (c *VideoCover) IsEmpty() bool {
+	return c == nil || (c.ImagePath == "" && c.Timestamp == nil)
+}
+
+// setVideoCover 打开封面编辑器，上传自定义封面或选取指定时间点的帧
+func setVideoCover(page *rod.Page, cover *VideoCover) error {
+	if cover.IsEmpty() {
+		return nil
+	}
+
+	pp := page.Timeout(60 * time.Second)
+
+	editBtn, err := pp.ElementR("div, span, button", `^\s*(修改封面|设置封面|编辑封面)\s*$`)
+	if err != nil {
+		return errors.Wrap(err, "没有找到封面编辑按钮")
+	}
+	editBtn.MustClick()
+
+	modal, err := pp.Element("div.d-modal")
+	if err != nil {
+		return errors.Wrap(err, "封面编辑弹窗没有出现")
+	}
+	modal.MustWaitVisible()
+	time.Sleep(1 * time.Second)
+
+	if cover.ImagePath != "" {
+		err = uploadCoverImage(modal, cover.ImagePath)
+	} else {
+		err = pickCoverFrame(modal, *cover.Timestamp)
+	}
+	if err != nil {
+		return err
+	}
+
+	confirmBtn, err := modal.ElementR("button", `^\s*(确定|完成)\s*$`)
+	if err != nil {
+		return errors.Wrap(err, "没有找到封面确认按钮")
+	}
+	confirmBtn.MustClick()
+
+	// 等待弹窗关闭
+	if err := modal.Timeout(30 * time.Second).WaitInvisible(); err != nil {
+		return errors.Wrap(err, "等待封面弹窗关闭超时")
+	}
+
+	if cover.ImagePath != "" {
+		slog.Info("视频封面设置完成", "image", cover.ImagePath)
+	} else {
+		slog.Info("视频封面设置完成", "timestamp", *cover.Timestamp)
+	}
+	return nil
+}
+
+// uploadCoverImage 在封面弹窗中上传本地图片作为封面
+func uploadCoverImage(modal *rod.Element, imagePath string) error {
+	if _, err := os.Stat(imagePath); err != nil {
+		return errors.Wrapf(err, "封面图片不存在: %s", imagePath)
+	}
+
+	if tab, err := modal.ElementR("div, span", `^\s*上传封面\s*$`); err == nil {
+		tab.MustClick()
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	fileInput, err := modal.Element("input[type='file']")
+	if err != nil {
+		return errors.Wrap(err, "未找到封面上传输入框")
+	}
+	fileInput.MustSetFiles(imagePath)
+
+	// 等待封面预览加载完成
+	if _, err := modal.Timeout(60 * time.Second).Element("img[src^='blob:'], img[src^='http']"); err != nil {
+		return errors.Wrap(err, "等待封面图片上传超时")
+	}
+	time.Sleep(1 * time.Second)
+
+	return nil
+}
+
+// pickCoverFrame 在封面弹窗中将预览视频定位到指定时间点作为封面帧，
+// 等待视频元数据加载后再定位，并读回编辑器选中的时间点确认封面已切换
+func pickCoverFrame(modal *rod.Element, timestamp time.Duration) error {
+	if tab, err := modal.ElementR("div, span", `^\s*截取封面\s*$`); err == nil {
+		tab.MustClick()
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	video, err := modal.Element("video")
+	if err != nil {
+		return errors.Wrap(err, "未找到封面预览视频")
+	}
+
+	// 等待 loadedmetadata，duration 在此之前为 NaN
+	res, err := video.Timeout(30 * time.Second).Eval(`() => new Promise((resolve) => {
+		if (this.readyState >= 1) {
+			resolve(this.duration);
+			return;
+		}
+		this.addEventListener('loadedmetadata', () => resolve(this.duration), { once: true });
+	})`)
+	if err != nil {
+		return errors.Wrap(err, "等待封面预览视频加载超时")
+	}
+	duration := res.Value.Num()
+	if math.IsNaN(duration) || math.IsInf(duration, 0) || duration <= 0 {
+		return errors.New("无法获取封面预览视频的时长")
+	}
+
+	seconds := timestamp.Seconds()
+	if seconds > duration {
+		return fmt.Errorf("封面时间点 %s 超出视频时长 %.1fs", timestamp, duration)
+	}
+
+	if _, err := video.Timeout(30*time.Second).Eval(`(t) => new Promise((resolve) => {
+		this.addEventListener('seeked', () => resolve(), { once: true });
+		this.currentTime = t;
+	})`, seconds); err != nil {
+		return errors.Wrap(err, "定位封面帧失败")
+	}
+
+	// 触发事件，让编辑器同步进度条位置和选中的封面
+	_, _ = video.Eval(`() => ['timeupdate', 'input', 'change'].forEach((e) => this.dispatchEvent(new Event(e, { bubbles: true })))`)
+	time.Sleep(1 * time.Second)
+
+	// 编辑器可能在同步时重置播放位置，读回确认选中的是指定帧
+	res, err = video.Eval(`() => this.currentTime`)
+	if err != nil {
+		return errors.Wrap(err, "读取选中的封面帧失败")
+	}
+	if selected := res.Value.Num(); !coverFrameSelected(seconds, selected) {
+		return fmt.Errorf("封面编辑器没有选中指定的帧: 期望 %.1fs，实际 %.1fs", seconds, selected)
+	}
+
+	return nil
+}
+
+// coverFrameSelected 编辑器选中的时间点是否与指定时间点一致
+func coverFrameSelected(requested, selected float64) bool {
+	return math.Abs(requested-selected) <= coverFrameTolerance
+}
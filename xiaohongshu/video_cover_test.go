@@ -0,0 +1,44 @@
+package xiaohongshu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVideoCoverIsEmpty(t *testing.T) {
+	zero := time.Duration(0)
+
+	tests := []struct {
+		name     string
+		cover    *VideoCover
+		expected bool
+	}{
+		{"nil", nil, true},
+		{"unset", &VideoCover{}, true},
+		{"first frame", &VideoCover{Timestamp: &zero}, false},
+		{"image", &VideoCover{ImagePath: "/tmp/cover.jpg"}, false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, test.cover.IsEmpty(), test.name)
+	}
+}
+
+func TestCoverFrameSelected(t *testing.T) {
+	tests := []struct {
+		requested, selected float64
+		expected            bool
+	}{
+		{3.5, 3.5, true},
+		{3.5, 3.2, true},
+		{0, 0, true},
+		{3.5, 0, false},
+		{10, 8, false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, coverFrameSelected(test.requested, test.selected), "requested=%v selected=%v", test.requested, test.selected)
+	}
+}